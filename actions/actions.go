@@ -0,0 +1,237 @@
+// Package actions contains the typed representation of the return actions a seccomp filter can take,
+// and the parsing of the names used for them in policies and settings
+package actions
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twtiger/gosecco/constants"
+)
+
+// These are the return values the kernel understands for the different kinds of actions
+const (
+	RetKill   = uint32(0x00000000) /* kill the task immediately */
+	RetTrap   = uint32(0x00030000) /* disallow and force a SIGSYS */
+	RetErrno  = uint32(0x00050000) /* returns an errno */
+	RetNotify = uint32(0x7fc00000) /* notifies a user space supervisor */
+	RetTrace  = uint32(0x7ff00000) /* pass to a tracer or disallow */
+	RetAllow  = uint32(0x7fff0000) /* allow */
+)
+
+// These return values are never generated from a policy, but can appear in filters from other tools
+const (
+	RetKillProcess = uint32(0x80000000) /* kill the whole process immediately */
+	RetLog         = uint32(0x7ffc0000) /* allow after logging */
+)
+
+// Kind represents the different kinds of actions available
+type Kind int
+
+// The available kinds of actions
+const (
+	Kill Kind = iota
+	Trap
+	Errno
+	Notify
+	Trace
+	Allow
+)
+
+var kindNames = map[string]Kind{
+	"kill":   Kill,
+	"trap":   Trap,
+	"notify": Notify,
+	"trace":  Trace,
+	"allow":  Allow,
+}
+
+var kindValues = map[Kind]uint32{
+	Kill:   RetKill,
+	Trap:   RetTrap,
+	Errno:  RetErrno,
+	Notify: RetNotify,
+	Trace:  RetTrace,
+	Allow:  RetAllow,
+}
+
+// Action is a parsed return action. Errno is only used for actions of the Errno kind
+type Action struct {
+	Kind  Kind
+	Errno uint16
+}
+
+// K returns the value to return from the filter for this action
+func (a Action) K() uint32 {
+	if a.Kind == Errno {
+		return RetErrno | uint32(a.Errno)
+	}
+	return kindValues[a.Kind]
+}
+
+func (a Action) String() string {
+	if a.Kind == Errno {
+		return fmt.Sprintf("%d", a.Errno)
+	}
+	for name, k := range kindNames {
+		if k == a.Kind {
+			return name
+		}
+	}
+	return fmt.Sprintf("<unknown action kind %d>", a.Kind)
+}
+
+// Describe returns the name of the action for a value returned by a filter, such as "allow" or "EPERM".
+// Errnos without a name are described by their number, and values that aren't actions in hex
+func Describe(k uint32) string {
+	for name, kind := range kindNames {
+		if k == kindValues[kind] {
+			return name
+		}
+	}
+	switch k {
+	case RetKillProcess:
+		return "kill-process"
+	case RetLog:
+		return "log"
+	}
+	if k&0xFFFF0000 == RetErrno {
+		if name, ok := constants.AllErrorNumbers[int(k&0xFFFF)]; ok {
+			return name
+		}
+		return fmt.Sprintf("errno %d", k&0xFFFF)
+	}
+	return fmt.Sprintf("0x%08X", k)
+}
+
+// Parse turns the description of an action into an action. The description can be one of
+// the names "trap", "kill", "allow", "trace" or "notify" in any case, a number that will be used as an errno,
+// or the name of an errno such as EPERM. If the description is not valid, the error will suggest
+// the closest valid names, if any are close enough.
+func Parse(s string) (Action, error) {
+	if k, ok := kindNames[strings.ToLower(s)]; ok {
+		return Action{Kind: k}, nil
+	}
+
+	if res, err := strconv.ParseUint(s, 0, 16); err == nil {
+		return Action{Kind: Errno, Errno: uint16(res)}, nil
+	}
+
+	if res, ok := constants.GetError(s); ok {
+		return Action{Kind: Errno, Errno: uint16(res)}, nil
+	}
+
+	return Action{}, &ParseError{Text: s, Suggestions: Suggestions(s)}
+}
+
+// ParseError is returned when an action can't be parsed. It contains the closest valid names
+type ParseError struct {
+	Text        string
+	Suggestions []string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid action '%s'%s", e.Text, e.Hint())
+}
+
+// Hint returns the suggestions in a form that can be added to the end of an error message
+func (e *ParseError) Hint() string {
+	if len(e.Suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" - did you mean '%s'?", strings.Join(e.Suggestions, "' or '"))
+}
+
+// maxSuggestions is the largest number of names Suggestions will return
+const maxSuggestions = 3
+
+// Suggestions returns the valid action names closest to the given invalid name, with the closest first.
+// Names that are too far away to be a likely typo will not be included.
+func Suggestions(s string) []string {
+	upper := strings.ToUpper(s)
+	allowed := len(s) / 3
+	if allowed < 1 {
+		allowed = 1
+	}
+	if allowed > 2 {
+		allowed = 2
+	}
+
+	candidates := []candidate{}
+	consider := func(name string) {
+		if d := distance(upper, strings.ToUpper(name)); d <= allowed {
+			candidates = append(candidates, candidate{name, d})
+		}
+	}
+	for name := range kindNames {
+		consider(name)
+	}
+	for name := range constants.AllErrors {
+		consider(name)
+	}
+
+	sort.Sort(byDistance(candidates))
+
+	result := []string{}
+	for _, c := range candidates {
+		if len(result) == maxSuggestions {
+			break
+		}
+		result = append(result, c.name)
+	}
+	return result
+}
+
+type candidate struct {
+	name     string
+	distance int
+}
+
+type byDistance []candidate
+
+func (s byDistance) Len() int      { return len(s) }
+func (s byDistance) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDistance) Less(i, j int) bool {
+	if s[i].distance != s[j].distance {
+		return s[i].distance < s[j].distance
+	}
+	return s[i].name < s[j].name
+}
+
+// distance calculates the Levenshtein distance between two strings, where swapping two
+// neighbouring characters counts as one edit, since that is a very common typo
+func distance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minimum(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minimum(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minimum(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package actions
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ActionsSuite struct{}
+
+var _ = Suite(&ActionsSuite{})
+
+func (s *ActionsSuite) Test_parsesNamedActionsInAnyCase(c *C) {
+	a, err := Parse("AlloW")
+	c.Assert(err, IsNil)
+	c.Assert(a, Equals, Action{Kind: Allow})
+	c.Assert(a.K(), Equals, RetAllow)
+	c.Assert(a.String(), Equals, "allow")
+
+	a, err = Parse("notify")
+	c.Assert(err, IsNil)
+	c.Assert(a.K(), Equals, RetNotify)
+}
+
+func (s *ActionsSuite) Test_parsesErrnoActions(c *C) {
+	a, err := Parse("0x2a")
+	c.Assert(err, IsNil)
+	c.Assert(a, Equals, Action{Kind: Errno, Errno: 42})
+	c.Assert(a.K(), Equals, uint32(0x5002a))
+	c.Assert(a.String(), Equals, "42")
+
+	a, err = Parse("EPERM")
+	c.Assert(err, IsNil)
+	c.Assert(a, Equals, Action{Kind: Errno, Errno: 1})
+
+	_, err = Parse("70000")
+	c.Assert(err, ErrorMatches, "invalid action '70000'")
+}
+
+func (s *ActionsSuite) Test_suggestsCloseNames(c *C) {
+	_, err := Parse("allwo")
+	c.Assert(err, ErrorMatches, "invalid action 'allwo' - did you mean 'allow'\\?")
+
+	_, err = Parse("eperm")
+	c.Assert(err, ErrorMatches, "invalid action 'eperm' - did you mean 'EPERM'\\?")
+
+	_, err = Parse("EACCESS")
+	c.Assert(err, ErrorMatches, "invalid action 'EACCESS' - did you mean 'EACCES'.*")
+
+	c.Assert(Suggestions("completely-wrong"), HasLen, 0)
+}
+
+func (s *ActionsSuite) Test_describesReturnValues(c *C) {
+	c.Assert(Describe(RetAllow), Equals, "allow")
+	c.Assert(Describe(RetKill), Equals, "kill")
+	c.Assert(Describe(RetNotify), Equals, "notify")
+	c.Assert(Describe(RetKillProcess), Equals, "kill-process")
+	c.Assert(Describe(RetLog), Equals, "log")
+	c.Assert(Describe(RetErrno|13), Equals, "EACCES")
+	c.Assert(Describe(RetErrno|0xFFFF), Equals, "errno 65535")
+	c.Assert(Describe(0x12345678), Equals, "0x12345678")
+}
@@ -0,0 +1,193 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+
+	"golang.org/x/sys/unix"
+)
+
+const unknownOffset = -1
+
+// loadedOffsets finds which word of seccomp_data the accumulator holds before every instruction, or
+// unknownOffset if it holds something else, or different words depending on the path taken
+func loadedOffsets(ss []unix.SockFilter) []int {
+	result := make([]int, len(ss)+1)
+	reached := make([]bool, len(ss)+1)
+	reached[0] = true
+	result[0] = unknownOffset
+
+	flow := func(to int, offset int) {
+		if to < 0 || to >= len(result) {
+			return
+		}
+		if !reached[to] {
+			reached[to] = true
+			result[to] = offset
+		} else if result[to] != offset {
+			result[to] = unknownOffset
+		}
+	}
+
+	for i, s := range ss {
+		offset := result[i]
+		switch {
+		case s.Code == syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS:
+			offset = int(s.K)
+		case s.Code&0x07 == syscall.BPF_LD, s.Code&0x07 == syscall.BPF_ALU, s.Code == syscall.BPF_MISC|syscall.BPF_TXA:
+			offset = unknownOffset
+		}
+
+		switch {
+		case s.Code&0x07 == syscall.BPF_RET:
+		case isUnconditionalJump(s.Code):
+			flow(i+1+int(s.K), offset)
+		case isConditionalJump(s.Code):
+			flow(i+1+int(s.Jt), offset)
+			flow(i+1+int(s.Jf), offset)
+		default:
+			flow(i+1, offset)
+		}
+	}
+	return result
+}
+
+// fieldName returns the name of the seccomp_data field at the offset, as it is called in the kernel
+func fieldName(offset uint32) string {
+	switch {
+	case offset == 0x00:
+		return "nr"
+	case offset == 0x04:
+		return "arch"
+	case offset == 0x08:
+		return "instruction_pointer.lo"
+	case offset == 0x0C:
+		return "instruction_pointer.hi"
+	case offset >= 0x10 && offset < 0x40 && offset%4 == 0:
+		half := "lo"
+		if offset%8 == 4 {
+			half = "hi"
+		}
+		return fmt.Sprintf("args[%d].%s", (offset-0x10)/8, half)
+	}
+	return fmt.Sprintf("data[0x%X]", offset)
+}
+
+// describeValue returns the name of a value compared with a word of seccomp_data, if it has one
+func describeValue(offset int, k uint32) string {
+	switch offset {
+	case 0x00:
+		if name, ok := constants.SyscallNumbers[int(k)]; ok {
+			return name
+		}
+		if k == auditArchs["__X32_SYSCALL_BIT"] {
+			return "__X32_SYSCALL_BIT"
+		}
+	case 0x04:
+		for name, v := range auditArchs {
+			if v == k && strings.HasPrefix(name, "AUDIT_ARCH_") {
+				return name
+			}
+		}
+	}
+	return fmt.Sprintf("0x%X", k)
+}
+
+var aluOperators = map[uint16]string{
+	syscall.BPF_ADD: "+=",
+	syscall.BPF_SUB: "-=",
+	syscall.BPF_MUL: "*=",
+	syscall.BPF_DIV: "/=",
+	syscall.BPF_AND: "&=",
+	syscall.BPF_OR:  "|=",
+	BPF_XOR:         "^=",
+	syscall.BPF_LSH: "<<=",
+	syscall.BPF_RSH: ">>=",
+	BPF_MOD:         "%=",
+}
+
+var jumpOperators = map[uint16]string{
+	syscall.BPF_JEQ:  "==",
+	syscall.BPF_JGT:  ">",
+	syscall.BPF_JGE:  ">=",
+	syscall.BPF_JSET: "&",
+}
+
+// annotate describes what an instruction does, with absolute jump targets and names for the values
+func annotate(i int, s unix.SockFilter, offset int) string {
+	switch s.Code {
+	case syscall.BPF_RET | syscall.BPF_K:
+		return "return " + actions.Describe(s.K)
+	case syscall.BPF_RET | syscall.BPF_X:
+		return "return X"
+	case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS:
+		return "A = " + fieldName(s.K)
+	case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_IND:
+		return fmt.Sprintf("A = data[X + 0x%X]", s.K)
+	case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_LEN:
+		return "A = len"
+	case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_IMM:
+		return fmt.Sprintf("A = 0x%X", s.K)
+	case syscall.BPF_LD | syscall.BPF_W | syscall.BPF_MEM:
+		return fmt.Sprintf("A = M[%d]", s.K)
+	case syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_LEN:
+		return "X = len"
+	case syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_IMM:
+		return fmt.Sprintf("X = 0x%X", s.K)
+	case syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_MEM:
+		return fmt.Sprintf("X = M[%d]", s.K)
+	case syscall.BPF_ST:
+		return fmt.Sprintf("M[%d] = A", s.K)
+	case syscall.BPF_STX:
+		return fmt.Sprintf("M[%d] = X", s.K)
+	case syscall.BPF_ALU | syscall.BPF_NEG:
+		return "A = -A"
+	case syscall.BPF_MISC | syscall.BPF_TAX:
+		return "X = A"
+	case syscall.BPF_MISC | syscall.BPF_TXA:
+		return "A = X"
+	case syscall.BPF_JMP | syscall.BPF_JA:
+		return fmt.Sprintf("goto %04d", i+1+int(s.K))
+	}
+
+	operand := fmt.Sprintf("0x%X", s.K)
+	if s.Code&0x08 == syscall.BPF_X {
+		operand = "X"
+	}
+	switch s.Code & 0x07 {
+	case syscall.BPF_ALU:
+		if op, ok := aluOperators[s.Code&0xF0]; ok {
+			return fmt.Sprintf("A %s %s", op, operand)
+		}
+	case syscall.BPF_JMP:
+		if op, ok := jumpOperators[s.Code&0xF0]; ok {
+			if s.Code&0x08 == syscall.BPF_K {
+				operand = describeValue(offset, s.K)
+			}
+			return fmt.Sprintf("if A %s %s goto %04d else goto %04d", op, operand, i+1+int(s.Jt), i+1+int(s.Jf))
+		}
+	}
+	return fmt.Sprintf("unknown instruction code 0x%02X", s.Code)
+}
+
+// DumpAnnotated works like Dump, but starts every line with the index of the instruction, and ends it with a
+// description of what the instruction does - with the targets of jumps, the names of the fields of seccomp_data
+// that are loaded, the names of the system calls and architectures they are compared with, and the names of
+// the actions returned
+func DumpAnnotated(ss []unix.SockFilter) string {
+	offsets := loadedOffsets(ss)
+	result := []string{}
+	for i, s := range ss {
+		text, ok := dump(s)
+		if !ok {
+			text = "???"
+		}
+		text = strings.Replace(text, "\t", " ", -1)
+		result = append(result, fmt.Sprintf("%04d: %-24s # %s", i, text, annotate(i, s, offsets[i])))
+	}
+	return strings.Join(result, "\n") + "\n"
+}
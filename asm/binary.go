@@ -0,0 +1,42 @@
+package asm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// instructionSize is the size of a struct sock_filter
+const instructionSize = 8
+
+// DumpBinary writes the program as an array of struct sock_filter in little endian byte order, the format
+// seccomp_export_bpf from libseccomp writes
+func DumpBinary(ss []unix.SockFilter) []byte {
+	result := make([]byte, len(ss)*instructionSize)
+	for i, s := range ss {
+		b := result[i*instructionSize:]
+		binary.LittleEndian.PutUint16(b, s.Code)
+		b[2], b[3] = s.Jt, s.Jf
+		binary.LittleEndian.PutUint32(b[4:], s.K)
+	}
+	return result
+}
+
+// ParseBinary reads a program written as an array of struct sock_filter in little endian byte order
+func ParseBinary(b []byte) ([]unix.SockFilter, error) {
+	if len(b)%instructionSize != 0 {
+		return nil, fmt.Errorf("the length %d is not a multiple of the instruction size %d", len(b), instructionSize)
+	}
+	result := make([]unix.SockFilter, len(b)/instructionSize)
+	for i := range result {
+		s := b[i*instructionSize:]
+		result[i] = unix.SockFilter{
+			Code: binary.LittleEndian.Uint16(s),
+			Jt:   s[2],
+			Jf:   s[3],
+			K:    binary.LittleEndian.Uint32(s[4:]),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package asm
+
+import . "gopkg.in/check.v1"
+
+type BinarySuite struct{}
+
+var _ = Suite(&BinarySuite{})
+
+const roundTripProgram = "" +
+	"ld_abs\t4\n" +
+	"jeq_k\t00\t09\tC000003E\n" +
+	"ld_abs\t0\n" +
+	"jge_k\t07\t00\t40000000\n" +
+	"jeq_k\t05\t00\t1\n" +
+	"ld_abs\t20\n" +
+	"and_k\t40\n" +
+	"jset_x\t01\t00\n" +
+	"jmp\t1\n" +
+	"ret_k\t50001\n" +
+	"ret_k\t7FFF0000\n" +
+	"ret_k\t0\n"
+
+func (s *BinarySuite) Test_roundTrip(c *C) {
+	b := DumpBinary(MustParse(roundTripProgram))
+	c.Assert(b, HasLen, 12*8)
+	c.Assert(b[8:16], DeepEquals, []byte{0x15, 0x00, 0x00, 0x09, 0x3E, 0x00, 0x00, 0xC0})
+
+	res, err := ParseBinary(b)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, roundTripProgram)
+}
+
+func (s *BinarySuite) Test_rejectsPartialInstructions(c *C) {
+	_, err := ParseBinary(make([]byte, 12))
+	c.Assert(err, ErrorMatches, "the length 12 is not a multiple of the instruction size 8")
+}
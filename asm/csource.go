@@ -0,0 +1,140 @@
+package asm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	cInitializerRE = regexp.MustCompile(`sock_filter\s+\w+\s*\[\s*\w*\s*\]\s*=\s*\{`)
+	cOffsetofRE    = regexp.MustCompile(`offsetof\s*\(\s*struct\s+seccomp_data\s*,\s*(\w+)\s*(?:\[\s*(\d+)\s*\])?\s*\)`)
+	cSuffixRE      = regexp.MustCompile(`\b(0[xX][0-9a-fA-F]+|[0-9]+)[uUlL]+\b`)
+)
+
+var cFieldOffsets = map[string]uint32{
+	"nr":                  0x00,
+	"arch":                0x04,
+	"instruction_pointer": 0x08,
+	"args":                0x10,
+}
+
+// preprocessC replaces the parts of C source that aren't valid Go expressions - the offsets of the fields
+// of struct seccomp_data and the suffixes of integer literals
+func preprocessC(source string) string {
+	source = cOffsetofRE.ReplaceAllStringFunc(source, func(s string) string {
+		m := cOffsetofRE.FindStringSubmatch(s)
+		offset, ok := cFieldOffsets[m[1]]
+		if !ok {
+			return s
+		}
+		if m[2] != "" {
+			index, _ := strconv.Atoi(m[2])
+			offset += uint32(index * 8)
+		}
+		return strconv.Itoa(int(offset))
+	})
+	return cSuffixRE.ReplaceAllString(source, "$1")
+}
+
+// codePart is one part of an instruction code, such as the size or the mode of a load
+type codePart struct {
+	mask  uint16
+	names map[uint16]string
+}
+
+var cSizes = codePart{0x18, map[uint16]string{syscall.BPF_W: "BPF_W", syscall.BPF_H: "BPF_H", syscall.BPF_B: "BPF_B"}}
+
+var cModes = codePart{0xE0, map[uint16]string{
+	syscall.BPF_IMM: "BPF_IMM", syscall.BPF_ABS: "BPF_ABS", syscall.BPF_IND: "BPF_IND",
+	syscall.BPF_MEM: "BPF_MEM", syscall.BPF_LEN: "BPF_LEN", syscall.BPF_MSH: "BPF_MSH",
+}}
+
+var cOperations = codePart{0xF0, map[uint16]string{
+	syscall.BPF_ADD: "BPF_ADD", syscall.BPF_SUB: "BPF_SUB", syscall.BPF_MUL: "BPF_MUL", syscall.BPF_DIV: "BPF_DIV",
+	syscall.BPF_OR: "BPF_OR", syscall.BPF_AND: "BPF_AND", syscall.BPF_LSH: "BPF_LSH", syscall.BPF_RSH: "BPF_RSH",
+	syscall.BPF_NEG: "BPF_NEG", BPF_MOD: "BPF_MOD", BPF_XOR: "BPF_XOR",
+}}
+
+var cJumps = codePart{0xF0, map[uint16]string{
+	syscall.BPF_JA: "BPF_JA", syscall.BPF_JEQ: "BPF_JEQ", syscall.BPF_JGT: "BPF_JGT",
+	syscall.BPF_JGE: "BPF_JGE", syscall.BPF_JSET: "BPF_JSET",
+}}
+
+var cSources = codePart{0x08, map[uint16]string{syscall.BPF_K: "BPF_K", syscall.BPF_X: "BPF_X"}}
+
+var cReturnValues = codePart{0x18, map[uint16]string{syscall.BPF_K: "BPF_K", syscall.BPF_X: "BPF_X", syscall.BPF_A: "BPF_A"}}
+
+var cMisc = codePart{0xF8, map[uint16]string{syscall.BPF_TAX: "BPF_TAX", syscall.BPF_TXA: "BPF_TXA"}}
+
+var cClasses = map[uint16]string{
+	syscall.BPF_LD: "BPF_LD", syscall.BPF_LDX: "BPF_LDX", syscall.BPF_ST: "BPF_ST", syscall.BPF_STX: "BPF_STX",
+	syscall.BPF_ALU: "BPF_ALU", syscall.BPF_JMP: "BPF_JMP", syscall.BPF_RET: "BPF_RET", syscall.BPF_MISC: "BPF_MISC",
+}
+
+// cCodeParts are the parts of instruction codes after the class, by the class
+var cCodeParts = map[uint16][]codePart{
+	syscall.BPF_LD:   {cSizes, cModes},
+	syscall.BPF_LDX:  {cSizes, cModes},
+	syscall.BPF_ALU:  {cOperations, cSources},
+	syscall.BPF_JMP:  {cJumps, cSources},
+	syscall.BPF_RET:  {cReturnValues},
+	syscall.BPF_MISC: {cMisc},
+}
+
+// cCode writes an instruction code with the names of its parts, such as BPF_LD | BPF_W | BPF_ABS. Codes
+// with bits that don't belong to any of the parts are written as numbers
+func cCode(code uint16) string {
+	class := code & 0x07
+	parts := []string{cClasses[class]}
+	used := uint16(0x07)
+	// Negation and unconditional jumps don't use a source, so it is left out when it has the default value
+	noSource := code == syscall.BPF_ALU|syscall.BPF_NEG || code == syscall.BPF_JMP|syscall.BPF_JA
+	for _, p := range cCodeParts[class] {
+		used |= p.mask
+		if noSource && p.mask == cSources.mask {
+			continue
+		}
+		name, ok := p.names[code&p.mask]
+		if !ok {
+			return fmt.Sprintf("0x%02x", code)
+		}
+		parts = append(parts, name)
+	}
+	if code&^used != 0 {
+		return fmt.Sprintf("0x%02x", code)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// DumpC writes the program as a C array of struct sock_filter with the given name, using the BPF_STMT and
+// BPF_JUMP macros from linux/filter.h
+func DumpC(ss []unix.SockFilter, name string) string {
+	result := []string{fmt.Sprintf("struct sock_filter %s[] = {", name)}
+	for _, s := range ss {
+		if isConditionalJump(s.Code) {
+			result = append(result, fmt.Sprintf("\tBPF_JUMP(%s, 0x%x, %d, %d),", cCode(s.Code), s.K, s.Jt, s.Jf))
+		} else {
+			result = append(result, fmt.Sprintf("\tBPF_STMT(%s, 0x%x),", cCode(s.Code), s.K))
+		}
+	}
+	result = append(result, "};")
+	return strings.Join(result, "\n") + "\n"
+}
+
+// ParseC reads a program from C source. The source should contain an array of struct sock_filter - if it
+// doesn't, the whole source is read as the contents of such an array. The instructions can be written with
+// the BPF_STMT and BPF_JUMP macros, or as { code, jt, jf, k }, and the values can use the names of the parts
+// of instruction codes, SECCOMP_RET_* actions, __NR_* system calls, errnos, AUDIT_ARCH_* architectures and
+// offsetof(struct seccomp_data, ...), combined with |, &, +, -, << and >>
+func ParseC(source string) ([]unix.SockFilter, error) {
+	in, err := findInitializer(preprocessC(source), cInitializerRE)
+	if err != nil {
+		return nil, err
+	}
+	return in.instructions()
+}
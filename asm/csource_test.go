@@ -0,0 +1,91 @@
+package asm
+
+import . "gopkg.in/check.v1"
+
+type CSourceSuite struct{}
+
+var _ = Suite(&CSourceSuite{})
+
+func (s *CSourceSuite) Test_roundTrip(c *C) {
+	source := DumpC(MustParse(roundTripProgram+"neg\ntax\nret_x\n"), "filter")
+	c.Assert(source, Equals, ""+
+		"struct sock_filter filter[] = {\n"+
+		"\tBPF_STMT(BPF_LD | BPF_W | BPF_ABS, 0x4),\n"+
+		"\tBPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, 0xc000003e, 0, 9),\n"+
+		"\tBPF_STMT(BPF_LD | BPF_W | BPF_ABS, 0x0),\n"+
+		"\tBPF_JUMP(BPF_JMP | BPF_JGE | BPF_K, 0x40000000, 7, 0),\n"+
+		"\tBPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, 0x1, 5, 0),\n"+
+		"\tBPF_STMT(BPF_LD | BPF_W | BPF_ABS, 0x20),\n"+
+		"\tBPF_STMT(BPF_ALU | BPF_AND | BPF_K, 0x40),\n"+
+		"\tBPF_JUMP(BPF_JMP | BPF_JSET | BPF_X, 0x0, 1, 0),\n"+
+		"\tBPF_STMT(BPF_JMP | BPF_JA, 0x1),\n"+
+		"\tBPF_STMT(BPF_RET | BPF_K, 0x50001),\n"+
+		"\tBPF_STMT(BPF_RET | BPF_K, 0x7fff0000),\n"+
+		"\tBPF_STMT(BPF_RET | BPF_K, 0x0),\n"+
+		"\tBPF_STMT(BPF_ALU | BPF_NEG, 0x0),\n"+
+		"\tBPF_STMT(BPF_MISC | BPF_TAX, 0x0),\n"+
+		"\tBPF_STMT(BPF_RET | BPF_X, 0x0),\n"+
+		"};\n")
+
+	res, err := ParseC(source)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, roundTripProgram+"neg\ntax\nret_x\n")
+}
+
+func (s *CSourceSuite) Test_readsHandWrittenFilters(c *C) {
+	res, err := ParseC(`
+#include <linux/filter.h>
+
+static struct sock_filter filter[] = {
+	/* check the architecture */
+	BPF_STMT(BPF_LD | BPF_W | BPF_ABS, offsetof(struct seccomp_data, arch)),
+	BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, AUDIT_ARCH_X86_64, 1, 0),
+	BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_KILL),
+	BPF_STMT(BPF_LD | BPF_W | BPF_ABS, offsetof(struct seccomp_data, args[1]) + 4),
+	BPF_STMT(BPF_LD | BPF_W | BPF_ABS, offsetof(struct seccomp_data, nr)),
+	BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, __NR_write, 0, 1),
+	BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ERRNO | (EPERM & SECCOMP_RET_DATA)),
+	{ 0x06, 0, 0, 0x7fff0000U }, // allow
+};
+`)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t01\t00\tC000003E\n"+
+		"ret_k\t0\n"+
+		"ld_abs\t1C\n"+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"ret_k\t50001\n"+
+		"ret_k\t7FFF0000\n")
+}
+
+func (s *CSourceSuite) Test_readsTheContentsOfAnArray(c *C) {
+	res, err := ParseC("BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW),\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "ret_k\t7FFF0000\n")
+}
+
+func (s *CSourceSuite) Test_readsTheNamesOfNewerActions(c *C) {
+	res, err := ParseC("BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_KILL_PROCESS),\n" +
+		"BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_LOG),\n" +
+		"BPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW & SECCOMP_RET_ACTION_FULL),\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "ret_k\t80000000\n"+
+		"ret_k\t7FFC0000\n"+
+		"ret_k\t7FFF0000\n")
+}
+
+func (s *CSourceSuite) Test_reportsWhereErrorsAre(c *C) {
+	_, err := ParseC("struct sock_filter f[] = {\n\tBPF_STMT(BPF_RET | BPF_K, 0),\n\tBPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_NOPE),\n};\n")
+	c.Assert(err, ErrorMatches, "3:2: unknown name SECCOMP_RET_NOPE")
+
+	_, err = ParseC("BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, 0, 1),\n")
+	c.Assert(err, ErrorMatches, "1:1: expected BPF_STMT with 2 arguments or BPF_JUMP with 4 arguments")
+
+	_, err = ParseC("BPF_JUMP(BPF_JMP | BPF_JEQ | BPF_K, 0, 256, 0),\n")
+	c.Assert(err, ErrorMatches, "1:1: the code or a jump is out of range")
+
+	_, err = ParseC("struct sock_filter f[] = {\n\tBPF_STMT(BPF_RET | BPF_K, 0),\n")
+	c.Assert(err, ErrorMatches, "the list of instructions is not closed")
+}
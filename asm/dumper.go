@@ -2,7 +2,6 @@ package asm
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"golang.org/x/sys/unix"
@@ -11,7 +10,6 @@ import (
 func dump(filter unix.SockFilter) (string, bool) {
 	inst, ok := instructionsByCode[filter.Code]
 	if !ok {
-		log.Printf("Unknown instruction %x", filter.Code)
 		return "", false
 	}
 
@@ -96,3 +96,82 @@ ret_k	7FFF0000
 ret_k	0
 `)
 }
+
+const annotatedProgram = `
+	ld_abs	arch
+	jeq_k	00	ko	AUDIT_ARCH_X86_64
+	ld_abs	nr
+	jge_k	ko	00	__X32_SYSCALL_BIT
+	jeq_k	ok	00	write
+	jeq_k	00	ko	openat
+	ld_abs	argL2
+	jset_k	eperm	ok	O_CREAT
+eperm:	ret_k	EPERM
+ok:	ret_k	allow
+ko:	ret_k	kill
+`
+
+func (s *DumperSuite) Test_annotatedDump(c *C) {
+	c.Assert(DumpAnnotated(MustParse(annotatedProgram)), Equals, ""+
+		"0000: ld_abs 4                 # A = arch\n"+
+		"0001: jeq_k 00 08 C000003E     # if A == AUDIT_ARCH_X86_64 goto 0002 else goto 0010\n"+
+		"0002: ld_abs 0                 # A = nr\n"+
+		"0003: jge_k 06 00 40000000     # if A >= __X32_SYSCALL_BIT goto 0010 else goto 0004\n"+
+		"0004: jeq_k 04 00 1            # if A == write goto 0009 else goto 0005\n"+
+		"0005: jeq_k 00 04 101          # if A == openat goto 0006 else goto 0010\n"+
+		"0006: ld_abs 20                # A = args[2].lo\n"+
+		"0007: jset_k 00 01 40          # if A & 0x40 goto 0008 else goto 0009\n"+
+		"0008: ret_k 50001              # return EPERM\n"+
+		"0009: ret_k 7FFF0000           # return allow\n"+
+		"0010: ret_k 0                  # return kill\n")
+}
+
+func (s *DumperSuite) Test_annotatedReturnsCanBeAssembledAgain(c *C) {
+	program := MustParse("ret_k kill-process\nret_k log\nret_k trace\nret_k EPERM\nret_k allow\n")
+	c.Assert(DumpAnnotated(program), Equals, ""+
+		"0000: ret_k 80000000           # return kill-process\n"+
+		"0001: ret_k 7FFC0000           # return log\n"+
+		"0002: ret_k 7FF00000           # return trace\n"+
+		"0003: ret_k 50001              # return EPERM\n"+
+		"0004: ret_k 7FFF0000           # return allow\n")
+}
+
+func (s *DumperSuite) Test_annotatedDumpOnlyNamesValuesWhenTheLoadedFieldIsKnown(c *C) {
+	c.Assert(DumpAnnotated(MustParse(""+
+		"ld_abs nr\n"+
+		"jeq_k 00 01 0\n"+
+		"ld_abs argH0\n"+
+		"jeq_k 00 01 0\n"+
+		"ret_k 0\n"+
+		"ld_mem 1\n"+
+		"add_k 3\n"+
+		"ret_k 5000D\n")), Equals, ""+
+		"0000: ld_abs 0                 # A = nr\n"+
+		"0001: jeq_k 00 01 0            # if A == read goto 0002 else goto 0003\n"+
+		"0002: ld_abs 14                # A = args[0].hi\n"+
+		"0003: jeq_k 00 01 0            # if A == 0x0 goto 0004 else goto 0005\n"+
+		"0004: ret_k 0                  # return kill\n"+
+		"0005: ld_mem 1                 # A = M[1]\n"+
+		"0006: add_k 3                  # A += 0x3\n"+
+		"0007: ret_k 5000D              # return EACCES\n")
+}
+
+func (s *DumperSuite) Test_tcpdumpDump(c *C) {
+	c.Assert(DumpTcpdump(MustParse(annotatedProgram+"ld_imm 10\ntax\nadd_x\njmp 0\nret_x\n")), Equals, ""+
+		"(000) ld       [4]\n"+
+		"(001) jeq      #0xc000003e      jt 2\tjf 10\n"+
+		"(002) ld       [0]\n"+
+		"(003) jge      #0x40000000      jt 10\tjf 4\n"+
+		"(004) jeq      #0x1             jt 9\tjf 5\n"+
+		"(005) jeq      #0x101           jt 6\tjf 10\n"+
+		"(006) ld       [32]\n"+
+		"(007) jset     #0x40            jt 8\tjf 9\n"+
+		"(008) ret      #327681\n"+
+		"(009) ret      #2147418112\n"+
+		"(010) ret      #0\n"+
+		"(011) ld       #0x10\n"+
+		"(012) tax      \n"+
+		"(013) add      x\n"+
+		"(014) ja       15\n"+
+		"(015) ret      x\n")
+}
@@ -0,0 +1,246 @@
+package asm
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpfNames contains the names of the parts of instruction codes, as they are called in C and in Go
+var bpfNames = map[string]uint32{
+	"BPF_LD": syscall.BPF_LD, "BPF_LDX": syscall.BPF_LDX, "BPF_ST": syscall.BPF_ST, "BPF_STX": syscall.BPF_STX,
+	"BPF_ALU": syscall.BPF_ALU, "BPF_JMP": syscall.BPF_JMP, "BPF_RET": syscall.BPF_RET, "BPF_MISC": syscall.BPF_MISC,
+
+	"BPF_W": syscall.BPF_W, "BPF_H": syscall.BPF_H, "BPF_B": syscall.BPF_B,
+	"BPF_IMM": syscall.BPF_IMM, "BPF_ABS": syscall.BPF_ABS, "BPF_IND": syscall.BPF_IND,
+	"BPF_MEM": syscall.BPF_MEM, "BPF_LEN": syscall.BPF_LEN, "BPF_MSH": syscall.BPF_MSH,
+
+	"BPF_ADD": syscall.BPF_ADD, "BPF_SUB": syscall.BPF_SUB, "BPF_MUL": syscall.BPF_MUL, "BPF_DIV": syscall.BPF_DIV,
+	"BPF_OR": syscall.BPF_OR, "BPF_AND": syscall.BPF_AND, "BPF_LSH": syscall.BPF_LSH, "BPF_RSH": syscall.BPF_RSH,
+	"BPF_NEG": syscall.BPF_NEG, "BPF_MOD": BPF_MOD, "BPF_XOR": BPF_XOR,
+
+	"BPF_JA": syscall.BPF_JA, "BPF_JEQ": syscall.BPF_JEQ, "BPF_JGT": syscall.BPF_JGT,
+	"BPF_JGE": syscall.BPF_JGE, "BPF_JSET": syscall.BPF_JSET,
+
+	"BPF_K": syscall.BPF_K, "BPF_X": syscall.BPF_X, "BPF_A": syscall.BPF_A,
+	"BPF_TAX": syscall.BPF_TAX, "BPF_TXA": syscall.BPF_TXA,
+
+	"SECCOMP_RET_KILL": actions.RetKill, "SECCOMP_RET_KILL_THREAD": actions.RetKill, "SECCOMP_RET_KILL_PROCESS": actions.RetKillProcess,
+	"SECCOMP_RET_TRAP": actions.RetTrap, "SECCOMP_RET_ERRNO": actions.RetErrno,
+	"SECCOMP_RET_USER_NOTIF": actions.RetNotify, "SECCOMP_RET_TRACE": actions.RetTrace, "SECCOMP_RET_LOG": actions.RetLog,
+	"SECCOMP_RET_ALLOW": actions.RetAllow, "SECCOMP_RET_ACTION_FULL": 0xFFFF0000, "SECCOMP_RET_ACTION": 0x7FFF0000, "SECCOMP_RET_DATA": 0x0000FFFF,
+}
+
+// valueOfName returns the value of a name used in C or Go source for a filter. Besides the names in bpfNames,
+// system calls can be given as __NR_read or SYS_READ, and errnos, architectures and constants by their names
+func valueOfName(name string) (uint32, bool) {
+	if v, ok := bpfNames[name]; ok {
+		return v, true
+	}
+	if strings.HasPrefix(name, "__NR_") {
+		return constants.GetSyscall(strings.TrimPrefix(name, "__NR_"))
+	}
+	if strings.HasPrefix(name, "SYS_") {
+		return constants.GetSyscall(strings.ToLower(strings.TrimPrefix(name, "SYS_")))
+	}
+	if v, ok := auditArchs[name]; ok {
+		return v, true
+	}
+	if v, ok := constants.GetError(name); ok {
+		return v, true
+	}
+	return constants.GetConstant(name)
+}
+
+// evaluate returns the value of a constant expression in C or Go source for a filter
+func evaluate(e ast.Expr) (uint32, error) {
+	switch v := e.(type) {
+	case *ast.BasicLit:
+		if v.Kind == token.INT {
+			if result, err := strconv.ParseUint(v.Value, 0, 32); err == nil {
+				return uint32(result), nil
+			}
+		}
+		return 0, fmt.Errorf("invalid value %s", v.Value)
+	case *ast.Ident:
+		if result, ok := valueOfName(v.Name); ok {
+			return result, nil
+		}
+		return 0, fmt.Errorf("unknown name %s", v.Name)
+	case *ast.SelectorExpr:
+		return evaluate(v.Sel)
+	case *ast.ParenExpr:
+		return evaluate(v.X)
+	case *ast.BinaryExpr:
+		left, err := evaluate(v.X)
+		if err != nil {
+			return 0, err
+		}
+		right, err := evaluate(v.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch v.Op {
+		case token.OR:
+			return left | right, nil
+		case token.AND:
+			return left & right, nil
+		case token.ADD:
+			return left + right, nil
+		case token.SUB:
+			return left - right, nil
+		case token.SHL:
+			return left << right, nil
+		case token.SHR:
+			return left >> right, nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", v.Op)
+	}
+	return 0, fmt.Errorf("unsupported expression")
+}
+
+// initializer is the list of instructions found in C or Go source
+type initializer struct {
+	source string
+	// start is where the list starts in the source, used to find the lines and columns of errors
+	start    int
+	elements []ast.Expr
+	fset     *token.FileSet
+}
+
+func (in *initializer) errorAt(e ast.Expr, err error) error {
+	offset := in.start + in.fset.Position(e.Pos()).Offset - len("[]T")
+	before := in.source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return &ParseError{Line: line, Column: column, Err: err.Error()}
+}
+
+// findInitializer finds the list of instructions in source, starting at the first match of the regular
+// expression, which has to end with the opening brace of the list. If there is no match, the whole source is
+// used as the contents of the list
+func findInitializer(source string, start *regexp.Regexp) (*initializer, error) {
+	body, offset := source, 0
+	if loc := start.FindStringIndex(source); loc != nil {
+		offset = loc[1] - 1
+		end, err := matchingBrace(source[offset:])
+		if err != nil {
+			return nil, err
+		}
+		body = source[offset : offset+end+1]
+	} else {
+		body = "{" + source + "\n}"
+		offset = -1
+	}
+
+	fset := token.NewFileSet()
+	e, err := parser.ParseExprFrom(fset, "", "[]T"+body, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instructions: %s", err)
+	}
+	list, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("invalid instructions")
+	}
+	return &initializer{source: source, start: offset, elements: list.Elts, fset: fset}, nil
+}
+
+// matchingBrace returns the index of the brace that closes the one the source starts with
+func matchingBrace(source string) (int, error) {
+	fset := token.NewFileSet()
+	s := scanner.Scanner{}
+	s.Init(fset.AddFile("", fset.Base(), len(source)), []byte(source), nil, scanner.ScanComments)
+	depth := 0
+	for {
+		pos, tok, _ := s.Scan()
+		switch tok {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+			if depth == 0 {
+				return fset.Position(pos).Offset, nil
+			}
+		case token.EOF:
+			return 0, fmt.Errorf("the list of instructions is not closed")
+		}
+	}
+}
+
+// instructionFrom evaluates one element of the list of instructions. It can be a call to BPF_STMT or BPF_JUMP,
+// or a struct literal with the code, jt, jf and k in order, or with the fields of unix.SockFilter as keys
+func instructionFrom(e ast.Expr) (unix.SockFilter, error) {
+	values := [4]uint32{}
+	fieldIndexes := map[string]int{"Code": 0, "Jt": 1, "Jf": 2, "K": 3}
+	switch v := e.(type) {
+	case *ast.CallExpr:
+		name, _ := v.Fun.(*ast.Ident)
+		order := []int{}
+		switch {
+		case name != nil && name.Name == "BPF_STMT" && len(v.Args) == 2:
+			order = []int{0, 3}
+		case name != nil && name.Name == "BPF_JUMP" && len(v.Args) == 4:
+			order = []int{0, 3, 1, 2}
+		default:
+			return unix.SockFilter{}, fmt.Errorf("expected BPF_STMT with 2 arguments or BPF_JUMP with 4 arguments")
+		}
+		for i, a := range v.Args {
+			val, err := evaluate(a)
+			if err != nil {
+				return unix.SockFilter{}, err
+			}
+			values[order[i]] = val
+		}
+	case *ast.CompositeLit:
+		for i, el := range v.Elts {
+			index := i
+			if kv, ok := el.(*ast.KeyValueExpr); ok {
+				key, _ := kv.Key.(*ast.Ident)
+				if key == nil {
+					return unix.SockFilter{}, fmt.Errorf("invalid field")
+				}
+				if index, ok = fieldIndexes[key.Name]; !ok {
+					return unix.SockFilter{}, fmt.Errorf("unknown field %s", key.Name)
+				}
+				el = kv.Value
+			} else if len(v.Elts) != 4 {
+				return unix.SockFilter{}, fmt.Errorf("expected 4 values, but got %d", len(v.Elts))
+			}
+			val, err := evaluate(el)
+			if err != nil {
+				return unix.SockFilter{}, err
+			}
+			values[index] = val
+		}
+	default:
+		return unix.SockFilter{}, fmt.Errorf("expected an instruction")
+	}
+
+	if values[0] > 0xFFFF || values[1] > 0xFF || values[2] > 0xFF {
+		return unix.SockFilter{}, fmt.Errorf("the code or a jump is out of range")
+	}
+	return unix.SockFilter{Code: uint16(values[0]), Jt: uint8(values[1]), Jf: uint8(values[2]), K: values[3]}, nil
+}
+
+func (in *initializer) instructions() ([]unix.SockFilter, error) {
+	result := []unix.SockFilter{}
+	for _, e := range in.elements {
+		s, err := instructionFrom(e)
+		if err != nil {
+			return nil, in.errorAt(e, err)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
@@ -0,0 +1,37 @@
+package asm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var goInitializerRE = regexp.MustCompile(`\[\]\s*(?:\w+\.)?SockFilter\s*\{`)
+
+// DumpGo writes the program as a Go variable with the given name, of the type []unix.SockFilter. Every
+// instruction is followed by a comment with its assembler form
+func DumpGo(ss []unix.SockFilter, name string) string {
+	result := []string{fmt.Sprintf("var %s = []unix.SockFilter{", name)}
+	for _, s := range ss {
+		comment := ""
+		if text, ok := dump(s); ok {
+			comment = " // " + strings.Replace(text, "\t", " ", -1)
+		}
+		result = append(result, fmt.Sprintf("\t{Code: 0x%02x, Jt: 0x%02x, Jf: 0x%02x, K: 0x%08x},%s", s.Code, s.Jt, s.Jf, s.K, comment))
+	}
+	result = append(result, "}")
+	return strings.Join(result, "\n") + "\n"
+}
+
+// ParseGo reads a program from Go source. The source should contain a []unix.SockFilter literal - if it
+// doesn't, the whole source is read as the contents of such a literal. The instructions can have the fields
+// as keys or in order, and the values can use the same names as in ParseC, with or without a package
+func ParseGo(source string) ([]unix.SockFilter, error) {
+	in, err := findInitializer(source, goInitializerRE)
+	if err != nil {
+		return nil, err
+	}
+	return in.instructions()
+}
@@ -0,0 +1,45 @@
+package asm
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type GoSourceSuite struct{}
+
+var _ = Suite(&GoSourceSuite{})
+
+func (s *GoSourceSuite) Test_roundTrip(c *C) {
+	source := DumpGo(MustParse(roundTripProgram), "filter")
+	c.Assert(strings.SplitN(source, "\n", 4)[:3], DeepEquals, []string{
+		"var filter = []unix.SockFilter{",
+		"\t{Code: 0x20, Jt: 0x00, Jf: 0x00, K: 0x00000004}, // ld_abs 4",
+		"\t{Code: 0x15, Jt: 0x00, Jf: 0x09, K: 0xc000003e}, // jeq_k 00 09 C000003E",
+	})
+
+	res, err := ParseGo("package filters\n\nimport \"golang.org/x/sys/unix\"\n\n" + source)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, roundTripProgram)
+}
+
+func (s *GoSourceSuite) Test_readsHandWrittenFilters(c *C) {
+	res, err := ParseGo(`
+	filter := []syscall.SockFilter{
+		syscall.SockFilter{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: 0},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 0, 1, syscall.SYS_WRITE},
+		{Code: syscall.BPF_RET | syscall.BPF_K, K: SECCOMP_RET_ALLOW},
+		{Code: syscall.BPF_RET | syscall.BPF_K},
+	}
+`)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "ld_abs\t0\njeq_k\t00\t01\t1\nret_k\t7FFF0000\nret_k\t0\n")
+}
+
+func (s *GoSourceSuite) Test_reportsWhereErrorsAre(c *C) {
+	_, err := ParseGo("x := []unix.SockFilter{\n\t{Code: 6, K: 0},\n\t{Code: 6, Op: 1},\n}\n")
+	c.Assert(err, ErrorMatches, "3:2: unknown field Op")
+
+	_, err = ParseGo("x := []unix.SockFilter{\n\t{6, 0, 0},\n}\n")
+	c.Assert(err, ErrorMatches, "2:2: expected 4 values, but got 3")
+}
@@ -0,0 +1,123 @@
+package asm
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxJumpSize is the longest jump a conditional jump can make
+const maxJumpSize = 255
+
+// node is an instruction with the jumps pointing to other instructions, so that instructions can be inserted
+// without changing where the jumps go. A nil target means the jump is kept as the offset that was given
+type node struct {
+	filter        unix.SockFilter
+	jt, jf, jumpK *node
+}
+
+func isConditionalJump(code uint16) bool {
+	return code&0x07 == syscall.BPF_JMP && code&0xF0 != syscall.BPF_JA
+}
+
+func isUnconditionalJump(code uint16) bool {
+	return code == syscall.BPF_JMP|syscall.BPF_JA
+}
+
+// assemble resolves the labels and offsets of the instructions into targets, inserts unconditional jumps
+// for conditional jumps that are too long, and calculates the final offsets
+func assemble(insts []instruction, positions map[string]int) ([]unix.SockFilter, error) {
+	// The last node stands for the end of the program, so that labels at the end can be jumped to
+	nodes := make([]*node, len(insts)+1)
+	for i := range nodes {
+		nodes[i] = &node{}
+	}
+
+	var err error
+	target := func(from int, l field, offset uint32) *node {
+		to := from + 1 + int(offset)
+		if l.text != "" {
+			to = positions[l.text]
+			if to <= from && err == nil {
+				err = l.errorf("jump to %s goes backwards", l.text)
+			}
+		}
+		if to <= from || to >= len(nodes) {
+			return nil
+		}
+		return nodes[to]
+	}
+
+	for i, inst := range insts {
+		n := nodes[i]
+		n.filter = inst.filter
+		switch {
+		case isConditionalJump(inst.filter.Code):
+			n.jt = target(i, inst.jt, uint32(inst.filter.Jt))
+			n.jf = target(i, inst.jf, uint32(inst.filter.Jf))
+		case isUnconditionalJump(inst.filter.Code):
+			n.jumpK = target(i, inst.k, inst.filter.K)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for insertTrampolines(&nodes) {
+	}
+
+	return layout(nodes[:len(nodes)-1], indexes(nodes)), nil
+}
+
+func indexes(nodes []*node) map[*node]int {
+	result := make(map[*node]int, len(nodes))
+	for i, n := range nodes {
+		result[n] = i
+	}
+	return result
+}
+
+// insertTrampolines finds the first conditional jump that is too long, and makes it jump to an unconditional
+// jump inserted after it instead. It returns false if there were no jumps that were too long
+func insertTrampolines(nodes *[]*node) bool {
+	at := indexes(*nodes)
+	for i, n := range *nodes {
+		if !isConditionalJump(n.filter.Code) {
+			continue
+		}
+		inserted := []*node{}
+		if n.jt != nil && at[n.jt]-i-1 > maxJumpSize {
+			t := &node{filter: unix.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JA}, jumpK: n.jt}
+			n.jt = t
+			inserted = append(inserted, t)
+		}
+		if n.jf != nil && at[n.jf]-i-1 > maxJumpSize {
+			t := &node{filter: unix.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JA}, jumpK: n.jf}
+			n.jf = t
+			inserted = append(inserted, t)
+		}
+		if len(inserted) > 0 {
+			rest := append(inserted, (*nodes)[i+1:]...)
+			*nodes = append((*nodes)[:i+1], rest...)
+			return true
+		}
+	}
+	return false
+}
+
+func layout(nodes []*node, at map[*node]int) []unix.SockFilter {
+	result := make([]unix.SockFilter, len(nodes))
+	for i, n := range nodes {
+		result[i] = n.filter
+		if n.jt != nil {
+			result[i].Jt = uint8(at[n.jt] - i - 1)
+		}
+		if n.jf != nil {
+			result[i].Jf = uint8(at[n.jf] - i - 1)
+		}
+		if n.jumpK != nil {
+			result[i].K = uint32(at[n.jumpK] - i - 1)
+		}
+	}
+	return result
+}
@@ -1,88 +1,258 @@
 package asm
 
 import (
-	"log"
+	"fmt"
 	"strconv"
 	"strings"
+	"syscall"
+	"unicode"
 
 	"golang.org/x/sys/unix"
 )
 
-// The ASM format for BPF will on purpose be extremely simple - space separated values, all values in hex, no 0x
-// prefixes. We will use mnemonics for the instructions.
+// The ASM format for BPF will on purpose be extremely simple - values separated by spaces or commas, all values
+// in hex, with an optional 0x prefix. We will use mnemonics for the instructions.
 // The first iteration will only read jump arguments if it's a jump instruction. It will also only
 // read K if the instruction takes a K. The map below details the instructions:
+//
+// Everything after a # on a line is a comment. A line can start with one or more labels, such as "ok:", and
+// jumps can use labels instead of offsets - "jeq_k ok deny 1". The offsets are calculated when the program is
+// assembled, and jumps that are too long for a conditional jump go through an inserted unconditional jump.
+// K can also be a name - loads take the names of words in seccomp_data, such as nr, arch or argL0, returns
+// take actions, such as allow or EPERM, and other instructions take system calls, architectures such as
+// AUDIT_ARCH_X86_64 and constants. Names are preferred over hex values, and so are labels in jumps. A value that
+// could be read as either a name or hex, such as EBADE, is ambiguous in strict mode and should be written with
+// a 0x prefix.
 
-// Add support for reading kill, trace etc?
+// ParseError is returned when a program can't be assembled. Lines and columns count from 1
+type ParseError struct {
+	Line, Column int
+	Err          string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Err)
+}
+
+// field is one space separated piece of a line, together with where it is
+type field struct {
+	text         string
+	line, column int
+}
+
+func (f field) errorf(format string, args ...interface{}) error {
+	return &ParseError{Line: f.line, Column: f.column, Err: fmt.Sprintf(format, args...)}
+}
+
+// instruction is a parsed instruction, where the jumps can still refer to labels
+type instruction struct {
+	filter unix.SockFilter
+	// jt, jf and k are the labels the instruction jumps to, if the jumps were given as labels
+	jt, jf, k field
+}
+
+// loader keeps the state of parsing one program. In strict mode extra values and values that are too large are
+// errors - otherwise the extra values are ignored and the values truncated, the same way as earlier versions of
+// the loader did
+type loader struct {
+	strict bool
+	labels map[string]field
+}
+
+func isLabel(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || r == '.' || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// splitLabels returns the labels at the start of the pieces of a line, and the pieces after them
+func splitLabels(pieces []field) ([]field, []field) {
+	labels := []field{}
+	for len(pieces) > 0 && strings.HasSuffix(pieces[0].text, ":") && isLabel(strings.TrimSuffix(pieces[0].text, ":")) {
+		l := pieces[0]
+		l.text = strings.TrimSuffix(l.text, ":")
+		labels = append(labels, l)
+		pieces = pieces[1:]
+	}
+	return labels, pieces
+}
+
+// fields splits a line into its pieces, leaving out comments
+func fields(s string, line int) []field {
+	if i := strings.Index(s, "#"); i != -1 {
+		s = s[:i]
+	}
+	result := []field{}
+	start := -1
+	for i, r := range s + " " {
+		if unicode.IsSpace(r) || r == ',' {
+			if start != -1 {
+				result = append(result, field{text: s[start:i], line: line, column: start + 1})
+				start = -1
+			}
+		} else if start == -1 {
+			start = i
+		}
+	}
+	return result
+}
+
+// hasHexPrefix returns true if the value starts with 0x or 0X
+func hasHexPrefix(s string) bool {
+	return strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")
+}
 
-func parseLine(s string) (unix.SockFilter, bool) {
-	pieces := strings.Split(strings.Replace(strings.TrimSpace(s), "\t", " ", -1), " ")
-	if len(pieces) == 0 || len(pieces) == 1 && pieces[0] == "" {
-		return unix.SockFilter{}, false
+// parseValue reads a hex value of the given size, with or without a 0x prefix. Outside of strict mode, larger
+// values are truncated
+func (l *loader) parseValue(f field, bits int, what string) (uint32, error) {
+	base := 16
+	if hasHexPrefix(f.text) {
+		base = 0
+	}
+	tmp, err := strconv.ParseUint(f.text, base, 64)
+	if err != nil {
+		return 0, f.errorf("invalid %s '%s'", what, f.text)
 	}
+	if l.strict && tmp>>uint(bits) != 0 {
+		return 0, f.errorf("%s %s is out of range", what, f.text)
+	}
+	return uint32(tmp), nil
+}
+
+// parseJump reads a jump that is either a label or a hex offset of the given size
+func (l *loader) parseJump(f field, bits int) (uint32, field, error) {
+	if _, ok := l.labels[f.text]; ok {
+		return 0, f, nil
+	}
+	v, err := l.parseValue(f, bits, "jump")
+	return v, field{}, err
+}
 
-	inst, ok := instructionsByName[pieces[0]]
+// parseLine parses the pieces of one line. It returns false if the line has no instruction
+func (l *loader) parseLine(pieces []field) (instruction, bool, error) {
+	if len(pieces) == 0 {
+		return instruction{}, false, nil
+	}
+
+	name := pieces[0]
+	inst, ok := instructionsByName[name.text]
 	if !ok {
-		log.Printf("No instruction with name: %s known", pieces[0])
-		return unix.SockFilter{}, false
+		return instruction{}, false, name.errorf("unknown instruction '%s'", name.text)
 	}
 
 	index := 1
-	filter := unix.SockFilter{}
-	filter.Code = inst.realInstruction
+	result := instruction{}
+	result.filter.Code = inst.realInstruction
 	if inst.takesJumps {
 		if len(pieces) < index+2 {
-			log.Printf("Instruction %s requires jumps, but not enough given", pieces[0])
-			return unix.SockFilter{}, false
+			return instruction{}, false, name.errorf("%s requires two jumps", name.text)
 		}
-		tmp, err := strconv.ParseUint(pieces[index], 16, 8)
+		tmp, label, err := l.parseJump(pieces[index], 8)
 		if err != nil {
-			log.Printf("Instruction %s has invalid jump: %s - %s", pieces[index], err.Error())
-			return unix.SockFilter{}, false
+			return instruction{}, false, err
 		}
-		filter.Jt = uint8(tmp)
+		result.filter.Jt, result.jt = uint8(tmp), label
 		index++
-		tmp, err = strconv.ParseUint(pieces[index], 16, 8)
+		tmp, label, err = l.parseJump(pieces[index], 8)
 		if err != nil {
-			log.Printf("Instruction %s has invalid jump: %s - %s", pieces[index], err.Error())
-			return unix.SockFilter{}, false
+			return instruction{}, false, err
 		}
-		filter.Jf = uint8(tmp)
+		result.filter.Jf, result.jf = uint8(tmp), label
 		index++
 	}
 	if inst.takesK {
 		if len(pieces) < index+1 {
-			log.Printf("Instruction %s takes K, but none given", pieces[0])
-			return unix.SockFilter{}, false
+			return instruction{}, false, name.errorf("%s requires K", name.text)
 		}
 
-		tmp, err := strconv.ParseUint(pieces[index], 16, 32)
-		if err != nil {
-			log.Printf("Instruction %s has invalid K: %s - %s", pieces[index], err.Error())
-			return unix.SockFilter{}, false
+		k := pieces[index]
+		if inst.realInstruction == syscall.BPF_JMP|syscall.BPF_JA {
+			tmp, label, err := l.parseJump(k, 32)
+			if err != nil {
+				return instruction{}, false, err
+			}
+			result.filter.K, result.k = tmp, label
+		} else if v, ok := namedValue(inst.realInstruction, k.text); ok {
+			if l.strict && isHex(k.text) {
+				return instruction{}, false, k.errorf("ambiguous K '%s' - it is both a name and a hex value, use 0x%s for the value", k.text, k.text)
+			}
+			result.filter.K = v
+		} else {
+			tmp, err := l.parseValue(k, 32, "K")
+			if err != nil {
+				return instruction{}, false, err
+			}
+			result.filter.K = tmp
 		}
-		filter.K = uint32(tmp)
 		index++
 	}
-	if len(pieces) > index {
-		log.Printf("Instruction %s has extra values given: %v", pieces[index:])
-		return unix.SockFilter{}, false
+	if len(pieces) > index && l.strict {
+		return instruction{}, false, pieces[index].errorf("%s has extra values", name.text)
 	}
 
-	return filter, true
+	return result, true, nil
 }
 
-func parseLines(s []string) []unix.SockFilter {
-	result := []unix.SockFilter{}
-	for _, ss := range s {
-		if r, ok := parseLine(ss); ok {
+func isHex(s string) bool {
+	_, err := strconv.ParseUint(s, 16, 64)
+	return err == nil
+}
+
+func (l *loader) parseLines(s []string) ([]unix.SockFilter, error) {
+	lines := make([][]field, len(s))
+	for i, ss := range s {
+		lines[i] = fields(ss, i+1)
+		labels, _ := splitLabels(lines[i])
+		for _, label := range labels {
+			if previous, ok := l.labels[label.text]; ok {
+				return nil, label.errorf("label %s is already defined on line %d", label.text, previous.line)
+			}
+			l.labels[label.text] = label
+		}
+	}
+
+	result := []instruction{}
+	positions := map[string]int{}
+	for _, pieces := range lines {
+		labels, pieces := splitLabels(pieces)
+		for _, label := range labels {
+			positions[label.text] = len(result)
+		}
+		r, ok, err := l.parseLine(pieces)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
 			result = append(result, r)
 		}
 	}
-	return result
+	return assemble(result, positions)
 }
 
-// Parse takes a string that contains a sock filter assembly program and returns the parsed representation
-func Parse(s string) []unix.SockFilter {
-	return parseLines(strings.Split(s, "\n"))
+// Parse takes a string that contains a sock filter assembly program and returns the parsed representation.
+// Extra values are ignored and values that are too large are truncated - all other problems, such as unknown
+// instructions, are returned as a *ParseError
+func Parse(s string) ([]unix.SockFilter, error) {
+	l := &loader{labels: make(map[string]field)}
+	return l.parseLines(strings.Split(s, "\n"))
+}
+
+// ParseStrict works like Parse, but also returns errors for extra values, values that are too large and
+// names that could also be read as hex values
+func ParseStrict(s string) ([]unix.SockFilter, error) {
+	l := &loader{strict: true, labels: make(map[string]field)}
+	return l.parseLines(strings.Split(s, "\n"))
+}
+
+// MustParse works like Parse, but panics if the program can't be parsed. It is meant for programs
+// in tests and other places where they are known to be correct
+func MustParse(s string) []unix.SockFilter {
+	result, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return result
 }
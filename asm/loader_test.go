@@ -77,7 +77,7 @@ func (s *LoaderSuite) Test_simpleLoad(c *C) {
 		},
 	}
 
-	res := Parse("" +
+	res, err := Parse("" +
 		`ld_abs	0
 jeq_k	00	08	1
 ld_imm	C
@@ -90,5 +90,158 @@ jeq_x	00	01
 ret_k	7FFF0000
 ret_k	0
 `)
+	c.Assert(err, IsNil)
 	c.Assert(res, DeepEquals, expected)
 }
+
+func (s *LoaderSuite) Test_labelsAndComments(c *C) {
+	res, err := Parse(`# only allow write
+	ld_abs	0
+	jeq_k	ok	deny	1   # write
+ok:	ret_k	7FFF0000
+deny:
+	ret_k	0
+`)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, ""+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")
+}
+
+func (s *LoaderSuite) Test_labelsArePreferredOverHexValues(c *C) {
+	res, err := Parse("jeq_k\t00\tad\t1\nret_k\t1\nad: ret_k\t0\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "jeq_k\t00\t01\t1\nret_k\t1\nret_k\t0\n")
+}
+
+func (s *LoaderSuite) Test_unconditionalJumpsToLabels(c *C) {
+	res, err := Parse("jmp end\nret_k 1\nend: ret_k 0\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "jmp\t1\nret_k\t1\nret_k\t0\n")
+}
+
+func (s *LoaderSuite) Test_namedValues(c *C) {
+	res, err := Parse(`
+	ld_abs	arch
+	jeq_k	00	ko	AUDIT_ARCH_X86_64
+	ld_abs	nr
+	jeq_k	00	ko	write
+	ld_abs	argL2
+	jset_k	bad	00	O_CREAT
+	ret_k	allow
+bad:	ret_k	EPERM
+ko:	ret_k	kill
+`)
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t06\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t04\t1\n"+
+		"ld_abs\t20\n"+
+		"jset_k\t01\t00\t40\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t50001\n"+
+		"ret_k\t0\n")
+}
+
+func (s *LoaderSuite) Test_namesDependOnTheInstruction(c *C) {
+	c.Assert(MustParse("ld_abs\targH5\n"), DeepEquals, MustParse("ld_abs\t3C\n"))
+	c.Assert(MustParse("ld_imm\tkill\n"), DeepEquals, MustParse("ld_imm\t3E\n"))
+	c.Assert(MustParse("ret_k\tkill\n"), DeepEquals, MustParse("ret_k\t0\n"))
+
+	_, err := Parse("ld_abs\tkill\n")
+	c.Assert(err, ErrorMatches, "1:8: invalid K 'kill'")
+	_, err = Parse("ret_k\tnr\n")
+	c.Assert(err, ErrorMatches, "1:7: invalid K 'nr'")
+}
+
+func (s *LoaderSuite) Test_commasAndHexPrefixes(c *C) {
+	res, err := Parse("ld_abs 0x0\njeq_k ok, deny, 0x1\nok: ret_k 0x7FFF0000\ndeny: ret_k 0\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, ""+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")
+
+	c.Assert(MustParse("jeq_k 00, 01, 0X10\n"), DeepEquals, MustParse("jeq_k 00 01 10\n"))
+}
+
+func (s *LoaderSuite) Test_namesArePreferredOverHexValues(c *C) {
+	c.Assert(MustParse("ret_k\tEBADE\n"), DeepEquals, MustParse("ret_k\t50034\n"))
+	c.Assert(MustParse("ret_k\t0xEBADE\n")[0].K, Equals, uint32(0xEBADE))
+}
+
+func (s *LoaderSuite) Test_namesThatAreAlsoHexAreAmbiguousInStrictMode(c *C) {
+	_, err := ParseStrict("ret_k\tEBADE\n")
+	c.Assert(err, ErrorMatches, "1:7: ambiguous K 'EBADE' - it is both a name and a hex value, use 0xEBADE for the value")
+	res, err := ParseStrict("ret_k\t0xEBADE\n")
+	c.Assert(err, IsNil)
+	c.Assert(res[0].K, Equals, uint32(0xEBADE))
+}
+
+func (s *LoaderSuite) Test_backwardJumpsAreRejected(c *C) {
+	_, err := Parse("back: ld_abs 0\njeq_k back 00 1\nret_k 0\n")
+	c.Assert(err, ErrorMatches, "2:7: jump to back goes backwards")
+	_, err = Parse("ld_abs 0\njeq_k nowhere 00 1\nret_k 0\n")
+	c.Assert(err, ErrorMatches, "2:7: invalid jump 'nowhere'")
+}
+
+func (s *LoaderSuite) Test_longJumpsGoThroughUnconditionalJumps(c *C) {
+	program := "ld_abs nr\njeq_k 00 far 1\njeq_k 00 02 2\n"
+	for i := 0; i < 300; i++ {
+		program += "ld_abs arch\n"
+	}
+	program += "ret_k allow\nfar: ret_k kill\n"
+
+	res, err := Parse(program)
+	c.Assert(err, IsNil)
+	c.Assert(res, HasLen, 306)
+	c.Assert(Dump(res[:4]), Equals, ""+
+		"ld_abs\t0\n"+
+		"jeq_k\t01\t00\t1\n"+
+		"jmp\t12E\n"+
+		"jeq_k\t00\t02\t2\n")
+	c.Assert(res[2+1+0x12E], DeepEquals, unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: 0})
+	c.Assert(res[3+1+2], DeepEquals, unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: 4})
+}
+
+func (s *LoaderSuite) Test_reportsWhereErrorsAre(c *C) {
+	_, err := Parse("ld_abs 0\n  jeq_k 00\n")
+	c.Assert(err, ErrorMatches, "2:3: jeq_k requires two jumps")
+	_, err = Parse("ret_k\n")
+	c.Assert(err, ErrorMatches, "1:1: ret_k requires K")
+	_, err = Parse("ret_k 0xG\n")
+	c.Assert(err, ErrorMatches, "1:7: invalid K '0xG'")
+	_, err = Parse("ld_abs 0\nld_ab 4\n")
+	c.Assert(err, ErrorMatches, "2:1: unknown instruction 'ld_ab'")
+	_, err = ParseStrict("ld_abs 0\nld_ab 4\n")
+	c.Assert(err, ErrorMatches, "2:1: unknown instruction 'ld_ab'")
+	_, err = Parse("a: ret_k 0\na: ret_k 1\n")
+	c.Assert(err, ErrorMatches, "2:1: label a is already defined on line 1")
+
+	perr, ok := err.(*ParseError)
+	c.Assert(ok, Equals, true)
+	c.Assert(perr.Line, Equals, 2)
+	c.Assert(perr.Column, Equals, 1)
+}
+
+func (s *LoaderSuite) Test_strictModeRejectsWhatIsOtherwiseAccepted(c *C) {
+	res, err := Parse("ld_abs 0\nret_k 0 1\njeq_k 100 00 1FFFFFFFF\nret_k 0\n")
+	c.Assert(err, IsNil)
+	c.Assert(Dump(res), Equals, "ld_abs\t0\nret_k\t0\njeq_k\t00\t00\tFFFFFFFF\nret_k\t0\n")
+
+	_, err = ParseStrict("ret_k 0 1\n")
+	c.Assert(err, ErrorMatches, "1:9: ret_k has extra values")
+	_, err = ParseStrict("jeq_k 100 00 1\nret_k 0\n")
+	c.Assert(err, ErrorMatches, "1:7: jump 100 is out of range")
+	_, err = ParseStrict("ret_k 1FFFFFFFF\n")
+	c.Assert(err, ErrorMatches, "1:7: K 1FFFFFFFF is out of range")
+}
+
+func (s *LoaderSuite) Test_mustParsePanicsOnErrors(c *C) {
+	c.Assert(func() { MustParse("ret_k\n") }, PanicMatches, "1:1: ret_k requires K")
+}
@@ -0,0 +1,74 @@
+package asm
+
+import (
+	"fmt"
+	"syscall"
+	"unicode"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+)
+
+// offsets contains the names of the words in seccomp_data, for loads. Arguments and the instruction
+// pointer are split into their low and high halves, the same way as in policies
+var offsets = map[string]uint32{
+	"nr":   0x00,
+	"arch": 0x04,
+	"ipL":  0x08,
+	"ipH":  0x0C,
+}
+
+// auditArchs contains the architecture values the arch field of seccomp_data can be compared with
+var auditArchs = map[string]uint32{
+	"AUDIT_ARCH_X86_64":  0xC000003E,
+	"AUDIT_ARCH_I386":    0x40000003,
+	"AUDIT_ARCH_AARCH64": 0xC00000B7,
+	"AUDIT_ARCH_ARM":     0x40000028,
+	"__X32_SYSCALL_BIT":  0x40000000,
+}
+
+// otherActions contains the names of return values that policies can't use, but that filters from other tools can
+// return, so that they can be written the same way DumpAnnotated describes them
+var otherActions = map[string]uint32{
+	"kill-process": actions.RetKillProcess,
+	"log":          actions.RetLog,
+}
+
+func init() {
+	for i := 0; i < 6; i++ {
+		offsets[fmt.Sprintf("argL%d", i)] = 0x10 + uint32(i*8)
+		offsets[fmt.Sprintf("argH%d", i)] = 0x14 + uint32(i*8)
+	}
+}
+
+// namedValue returns the value of a name used as the K of an instruction. Which names are allowed depends on
+// the instruction - loads take the names of words in seccomp_data, returns take actions such as allow or EPERM,
+// and comparisons and arithmetic take system call names, architectures and constants
+func namedValue(code uint16, name string) (uint32, bool) {
+	if name == "" || !unicode.IsLetter(rune(name[0])) && name[0] != '_' {
+		return 0, false
+	}
+
+	switch {
+	case code == syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS:
+		v, ok := offsets[name]
+		return v, ok
+	case code&0x07 == syscall.BPF_RET:
+		if v, ok := otherActions[name]; ok {
+			return v, true
+		}
+		a, err := actions.Parse(name)
+		return a.K(), err == nil
+	case code&0x07 == syscall.BPF_JMP && code&0xF0 != syscall.BPF_JA,
+		code&0x07 == syscall.BPF_ALU,
+		(code&0x07 == syscall.BPF_LD || code&0x07 == syscall.BPF_LDX) && code&0xE0 == syscall.BPF_IMM:
+		if v, ok := constants.GetSyscall(name); ok {
+			return v, true
+		}
+		if v, ok := auditArchs[name]; ok {
+			return v, true
+		}
+		return constants.GetConstant(name)
+	}
+	return 0, false
+}
@@ -0,0 +1,98 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpdumpFormat is the name and operand format libpcap uses for an instruction
+type tcpdumpFormat struct {
+	name, operand string
+}
+
+var tcpdumpFormats = map[uint16]tcpdumpFormat{
+	syscall.BPF_RET | syscall.BPF_K: {"ret", "#%d"},
+	syscall.BPF_RET | syscall.BPF_X: {"ret", "x"},
+
+	syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS:  {"ld", "[%d]"},
+	syscall.BPF_LD | syscall.BPF_W | syscall.BPF_IND:  {"ld", "[x + %d]"},
+	syscall.BPF_LD | syscall.BPF_W | syscall.BPF_LEN:  {"ld", "#pktlen"},
+	syscall.BPF_LD | syscall.BPF_W | syscall.BPF_IMM:  {"ld", "#0x%x"},
+	syscall.BPF_LD | syscall.BPF_W | syscall.BPF_MEM:  {"ld", "M[%d]"},
+	syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_LEN: {"ldx", "#pktlen"},
+	syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_IMM: {"ldx", "#0x%x"},
+	syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_MEM: {"ldx", "M[%d]"},
+	syscall.BPF_ST:  {"st", "M[%d]"},
+	syscall.BPF_STX: {"stx", "M[%d]"},
+
+	syscall.BPF_ALU | syscall.BPF_ADD | syscall.BPF_K: {"add", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_SUB | syscall.BPF_K: {"sub", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_MUL | syscall.BPF_K: {"mul", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_DIV | syscall.BPF_K: {"div", "#%d"},
+	syscall.BPF_ALU | BPF_MOD | syscall.BPF_K:         {"mod", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_AND | syscall.BPF_K: {"and", "#0x%x"},
+	syscall.BPF_ALU | syscall.BPF_OR | syscall.BPF_K:  {"or", "#0x%x"},
+	syscall.BPF_ALU | BPF_XOR | syscall.BPF_K:         {"xor", "#0x%x"},
+	syscall.BPF_ALU | syscall.BPF_LSH | syscall.BPF_K: {"lsh", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_RSH | syscall.BPF_K: {"rsh", "#%d"},
+	syscall.BPF_ALU | syscall.BPF_ADD | syscall.BPF_X: {"add", "x"},
+	syscall.BPF_ALU | syscall.BPF_SUB | syscall.BPF_X: {"sub", "x"},
+	syscall.BPF_ALU | syscall.BPF_MUL | syscall.BPF_X: {"mul", "x"},
+	syscall.BPF_ALU | syscall.BPF_DIV | syscall.BPF_X: {"div", "x"},
+	syscall.BPF_ALU | BPF_MOD | syscall.BPF_X:         {"mod", "x"},
+	syscall.BPF_ALU | syscall.BPF_AND | syscall.BPF_X: {"and", "x"},
+	syscall.BPF_ALU | syscall.BPF_OR | syscall.BPF_X:  {"or", "x"},
+	syscall.BPF_ALU | BPF_XOR | syscall.BPF_X:         {"xor", "x"},
+	syscall.BPF_ALU | syscall.BPF_LSH | syscall.BPF_X: {"lsh", "x"},
+	syscall.BPF_ALU | syscall.BPF_RSH | syscall.BPF_X: {"rsh", "x"},
+	syscall.BPF_ALU | syscall.BPF_NEG:                 {"neg", ""},
+
+	syscall.BPF_MISC | syscall.BPF_TAX: {"tax", ""},
+	syscall.BPF_MISC | syscall.BPF_TXA: {"txa", ""},
+
+	syscall.BPF_JMP | syscall.BPF_JA:                   {"ja", "%d"},
+	syscall.BPF_JMP | syscall.BPF_JGT | syscall.BPF_K:  {"jgt", "#0x%x"},
+	syscall.BPF_JMP | syscall.BPF_JGE | syscall.BPF_K:  {"jge", "#0x%x"},
+	syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K:  {"jeq", "#0x%x"},
+	syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_K: {"jset", "#0x%x"},
+	syscall.BPF_JMP | syscall.BPF_JGT | syscall.BPF_X:  {"jgt", "x"},
+	syscall.BPF_JMP | syscall.BPF_JGE | syscall.BPF_X:  {"jge", "x"},
+	syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_X:  {"jeq", "x"},
+	syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_X: {"jset", "x"},
+}
+
+func dumpTcpdump(i int, s unix.SockFilter) string {
+	f, ok := tcpdumpFormats[s.Code]
+	if !ok {
+		f = tcpdumpFormat{"unimp", "0x%x"}
+	}
+
+	operand := f.operand
+	if strings.Contains(operand, "%") {
+		v := s.K
+		if isUnconditionalJump(s.Code) {
+			v = uint32(i + 1 + int(s.K))
+		} else if !ok {
+			v = uint32(s.Code)
+		}
+		operand = fmt.Sprintf(operand, v)
+	}
+
+	if isConditionalJump(s.Code) {
+		return fmt.Sprintf("(%03d) %-8s %-16s jt %d\tjf %d", i, f.name, operand, i+1+int(s.Jt), i+1+int(s.Jf))
+	}
+	return fmt.Sprintf("(%03d) %-8s %s", i, f.name, operand)
+}
+
+// DumpTcpdump returns the program in the format "tcpdump -d" and bpf_dbg use for classic BPF, with absolute jump
+// targets, so that it can be compared with the output of those tools
+func DumpTcpdump(ss []unix.SockFilter) string {
+	result := []string{}
+	for i, s := range ss {
+		result = append(result, dumpTcpdump(i, s))
+	}
+	return strings.Join(result, "\n") + "\n"
+}
@@ -71,9 +71,11 @@ func (ar *argumentRestrictions) AcceptComparison(v tree.Comparison) {
 
 // AcceptInclusion implements Visitor
 func (ar *argumentRestrictions) AcceptInclusion(v tree.Inclusion) {
-	ar.register(checkRestrictedArgumentUsage(v.Left))
-	for _, r := range v.Rights {
-		ar.register(checkRestrictedArgumentUsage(r))
+	// An inclusion is a series of comparisons, so arguments used directly are fine here as well
+	for _, x := range append([]tree.Numeric{v.Left}, v.Rights...) {
+		if _, isArg := x.(tree.Argument); !isArg {
+			ar.register(checkRestrictedArgumentUsage(x))
+		}
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/twtiger/gosecco/actions"
 	"github.com/twtiger/gosecco/constants"
 	"github.com/twtiger/gosecco/tree"
 )
@@ -16,7 +17,7 @@ import (
 // in the phases before).
 // Except for checking type validity, the checker will also make sure we don't have
 // more than one rule for the same syscall. This is also the place where we make sure
-// all the syscalls with rules are defined.
+// all the syscalls with rules are defined, and that all actions are valid.
 // Further, we will also ensure that the usage of Arguments matches the behavior we are interested in
 // Specifically, full Arguments can only appear directly on the side of comparisons, never inside
 // arithmetic expressions.
@@ -25,7 +26,7 @@ import (
 // If everything is valid, the return will be empty
 func EnsureValid(p tree.Policy) []error {
 	v := &validityChecker{rules: p.Rules, seen: make(map[string]*tree.Rule)}
-	return v.check()
+	return append(checkPolicyActions(p), v.check()...)
 }
 
 type validityChecker struct {
@@ -33,13 +34,57 @@ type validityChecker struct {
 	seen  map[string]*tree.Rule
 }
 
-type ruleError struct {
-	syscallName string
-	err         error
+// ActionError represents an invalid action in one of the settings for the whole policy,
+// such as DEFAULT_POSITIVE or ActionOnX32
+type ActionError struct {
+	Setting  string
+	Position tree.Position
+	Err      error
 }
 
-func (e *ruleError) Error() string {
-	return fmt.Sprintf("[%s] %s", e.syscallName, e.err)
+func (e *ActionError) Error() string {
+	if e.Position.IsKnown() {
+		return fmt.Sprintf("%s: [%s] %s", e.Position, e.Setting, e.Err)
+	}
+	return fmt.Sprintf("[%s] %s", e.Setting, e.Err)
+}
+
+// checkAction makes sure the given action can be compiled. Empty actions are allowed, since
+// they mean that the action hasn't been specified at this level
+func checkAction(kind, action string) error {
+	if action == "" {
+		return nil
+	}
+	if _, err := actions.Parse(action); err != nil {
+		if pe, ok := err.(*actions.ParseError); ok {
+			return fmt.Errorf("invalid %s '%s'%s", kind, pe.Text, pe.Hint())
+		}
+		return err
+	}
+	return nil
+}
+
+func checkRuleActions(r *tree.Rule) error {
+	return either(
+		checkAction("positive action", r.PositiveAction),
+		checkAction("negative action", r.NegativeAction))
+}
+
+func checkPolicyActions(p tree.Policy) []error {
+	result := []error{}
+	settings := []struct{ name, action string }{
+		{"DEFAULT_POSITIVE", p.DefaultPositiveAction},
+		{"DEFAULT_NEGATIVE", p.DefaultNegativeAction},
+		{"DEFAULT_POLICY", p.DefaultPolicyAction},
+		{"ActionOnX32", p.ActionOnX32},
+		{"ActionOnAuditFailure", p.ActionOnAuditFailure},
+	}
+	for _, s := range settings {
+		if err := checkAction("action", s.action); err != nil {
+			result = append(result, &ActionError{Setting: s.name, Position: p.DefaultPositions[s.name], Err: err})
+		}
+	}
+	return result
 }
 
 func checkValidSyscall(r *tree.Rule) error {
@@ -64,11 +109,14 @@ func (v *validityChecker) check() []error {
 		if res == nil {
 			res = checkValidSyscall(r)
 		}
+		if res == nil {
+			res = checkRuleActions(r)
+		}
 		if res == nil {
 			res = v.checkRule(r)
 		}
 		if res != nil {
-			result = append(result, &ruleError{syscallName: r.Name, err: res})
+			result = append(result, &tree.RuleError{SyscallName: r.Name, Position: r.Position, Err: res})
 		}
 	}
 
@@ -395,6 +395,15 @@ func (s *CheckerSuite) Test_argument_inExpression_fails(c *C) {
 	c.Assert(val[0], ErrorMatches, "\\[read\\] full argument cannot be used in arithmetic expressions - use the 32bit accessors instead: arg1")
 }
 
+func (s *CheckerSuite) Test_argument_inInclusion_succeeds(c *C) {
+	toCheck := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.Inclusion{Positive: true, Left: tree.Argument{Type: tree.Full, Index: 0}, Rights: []tree.Numeric{tree.NumericLiteral{42}, tree.Argument{Type: tree.Full, Index: 1}}}}}}
+
+	val := EnsureValid(toCheck)
+
+	c.Assert(len(val), Equals, 0)
+}
+
 func (s *CheckerSuite) Test_hiargument_inExpression_succeeds(c *C) {
 	toCheck := tree.Policy{Rules: []*tree.Rule{
 		&tree.Rule{Name: "read", Body: tree.Comparison{Op: tree.EQL, Right: tree.Arithmetic{Op: tree.PLUS, Left: tree.Argument{Type: tree.Hi, Index: 1}, Right: tree.NumericLiteral{1}}, Left: tree.NumericLiteral{1}}}}}
@@ -403,3 +412,37 @@ func (s *CheckerSuite) Test_hiargument_inExpression_succeeds(c *C) {
 
 	c.Assert(len(val), Equals, 0)
 }
+
+func (s *CheckerSuite) Test_checksRuleActionsWithSuggestions(c *C) {
+	toCheck := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}, PositiveAction: "allwo", Position: tree.Position{File: "a.policy", Line: 2}},
+		&tree.Rule{Name: "write", Body: tree.BooleanLiteral{true}, NegativeAction: "EPRM"},
+		&tree.Rule{Name: "close", Body: tree.BooleanLiteral{true}, PositiveAction: "something", NegativeAction: "EPERM"},
+		&tree.Rule{Name: "open", Body: tree.BooleanLiteral{true}, PositiveAction: "Trace", NegativeAction: "42"},
+	}}
+
+	val := EnsureValid(toCheck)
+
+	c.Assert(len(val), Equals, 3)
+	c.Assert(val[0], ErrorMatches, "a.policy:2: \\[read\\] invalid positive action 'allwo' - did you mean 'allow'\\?")
+	c.Assert(val[1], ErrorMatches, "\\[write\\] invalid negative action 'EPRM' - did you mean 'EPERM'\\?")
+	c.Assert(val[2], ErrorMatches, "\\[close\\] invalid positive action 'something'")
+	c.Assert(val[0].(*tree.RuleError).Position, Equals, tree.Position{File: "a.policy", Line: 2})
+}
+
+func (s *CheckerSuite) Test_checksPolicyActions(c *C) {
+	toCheck := tree.Policy{
+		DefaultPositiveAction: "allow",
+		DefaultNegativeAction: "EPRM",
+		DefaultPolicyAction:   "kil",
+		ActionOnX32:           "trapp",
+		DefaultPositions:      map[string]tree.Position{"DEFAULT_NEGATIVE": tree.Position{File: "a.policy", Line: 0}},
+	}
+
+	val := EnsureValid(toCheck)
+
+	c.Assert(len(val), Equals, 3)
+	c.Assert(val[0], ErrorMatches, "a.policy:0: \\[DEFAULT_NEGATIVE\\] invalid action 'EPRM' - did you mean 'EPERM'\\?")
+	c.Assert(val[1], ErrorMatches, "\\[DEFAULT_POLICY\\] invalid action 'kil' - did you mean 'kill'\\?")
+	c.Assert(val[2], ErrorMatches, "\\[ActionOnX32\\] invalid action 'trapp' - did you mean 'trap'\\?")
+}
@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/tree"
+)
+
+// interval keeps track of the values an argument can have, given the comparisons
+// seen so far in a conjunction
+type interval struct {
+	low, high uint64
+	excluded  map[uint64]bool
+}
+
+func fullInterval(a tree.Argument) *interval {
+	high := uint64(0xFFFFFFFFFFFFFFFF)
+	if a.Type != tree.Full {
+		high = 0xFFFFFFFF
+	}
+	return &interval{low: 0, high: high, excluded: make(map[uint64]bool)}
+}
+
+func (i *interval) isEmpty() bool {
+	return i.low > i.high || (i.low == i.high && i.excluded[i.low])
+}
+
+// restrict narrows the interval to the values where "arg op value" holds
+func (i *interval) restrict(op tree.ComparisonType, value uint64) {
+	switch op {
+	case tree.EQL:
+		i.restrictTo(value, value)
+	case tree.NEQL:
+		i.excluded[value] = true
+	case tree.GT:
+		if value == 0xFFFFFFFFFFFFFFFF {
+			i.low, i.high = 1, 0
+		} else {
+			i.restrictTo(value+1, i.high)
+		}
+	case tree.GTE:
+		i.restrictTo(value, i.high)
+	case tree.LT:
+		if value == 0 {
+			i.low, i.high = 1, 0
+		} else {
+			i.restrictTo(i.low, value-1)
+		}
+	case tree.LTE:
+		i.restrictTo(i.low, value)
+	}
+}
+
+func (i *interval) restrictTo(low, high uint64) {
+	if low > i.low {
+		i.low = low
+	}
+	if high < i.high {
+		i.high = high
+	}
+}
+
+// flippedComparison gives the operation to use when the argument is on the right side of the comparison
+var flippedComparison = map[tree.ComparisonType]tree.ComparisonType{
+	tree.EQL:  tree.EQL,
+	tree.NEQL: tree.NEQL,
+	tree.GT:   tree.LT,
+	tree.GTE:  tree.LTE,
+	tree.LT:   tree.GT,
+	tree.LTE:  tree.GTE,
+}
+
+// argumentComparison returns the argument, operation and constant value of a comparison
+// between an argument and something that can be determined statically
+func argumentComparison(c tree.Comparison) (tree.Argument, tree.ComparisonType, uint64, bool) {
+	if a, ok := c.Left.(tree.Argument); ok {
+		if v, ok := simplifier.Simplify(c.Right).(tree.NumericLiteral); ok && fitsIn(a, v.Value) {
+			_, known := flippedComparison[c.Op]
+			return a, c.Op, v.Value, known
+		}
+	}
+	if a, ok := c.Right.(tree.Argument); ok {
+		if v, ok := simplifier.Simplify(c.Left).(tree.NumericLiteral); ok && fitsIn(a, v.Value) {
+			op, known := flippedComparison[c.Op]
+			return a, op, v.Value, known
+		}
+	}
+	return tree.Argument{}, 0, 0, false
+}
+
+// fitsIn returns false for constants that are too wide for the argument - those are reported as a separate warning
+func fitsIn(a tree.Argument, value uint64) bool {
+	return a.Type == tree.Full || value <= max32
+}
+
+func conjuncts(x tree.Expression) []tree.Expression {
+	if a, ok := x.(tree.And); ok {
+		return append(conjuncts(a.Left), conjuncts(a.Right)...)
+	}
+	return []tree.Expression{x}
+}
+
+// findContradiction looks through all conjunctions in the expression, and returns the first argument
+// that is compared in such a way that the conjunction can never be true. Disjunctions are checked
+// branch by branch, since a branch that can never be true is just as suspicious
+func findContradiction(x tree.Expression) (tree.Argument, bool) {
+	intervals := make(map[tree.Argument]*interval)
+	order := []tree.Argument{}
+
+	for _, c := range conjuncts(x) {
+		switch v := c.(type) {
+		case tree.Or:
+			if a, ok := findContradiction(v.Left); ok {
+				return a, true
+			}
+			if a, ok := findContradiction(v.Right); ok {
+				return a, true
+			}
+		case tree.Comparison:
+			if a, op, value, ok := argumentComparison(v); ok {
+				i, seen := intervals[a]
+				if !seen {
+					i = fullInterval(a)
+					intervals[a] = i
+					order = append(order, a)
+				}
+				i.restrict(op, value)
+			}
+		}
+	}
+
+	for _, a := range order {
+		if intervals[a].isEmpty() {
+			return a, true
+		}
+	}
+	return tree.Argument{}, false
+}
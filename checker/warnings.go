@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/tree"
+)
+
+// The warning codes are stable, so that they can be referred to in suppression comments and in
+// the configuration of tools. A new kind of warning always gets a new code
+const (
+	// WarningUnreachableDuplicate is reported for a rule that is identical to an earlier rule for the same syscall
+	WarningUnreachableDuplicate = "W001"
+	// WarningAlwaysTrue is reported for a rule body that simplifies to true without being written as true
+	WarningAlwaysTrue = "W002"
+	// WarningAlwaysFalse is reported for a rule body that simplifies to false
+	WarningAlwaysFalse = "W003"
+	// WarningContradictoryConditions is reported when the comparisons on an argument can never be true at the same time
+	WarningContradictoryConditions = "W004"
+	// WarningSameAsDefault is reported for a rule that always results in the default policy action
+	WarningSameAsDefault = "W005"
+	// WarningWideConstant is reported when a constant wider than 32 bits is used together with a 32 bit runtime value
+	WarningWideConstant = "W006"
+	// WarningArithmeticDivergence is reported when a constant calculated at compile time would have had a different value
+	// if it had been calculated with 32 bit arithmetic, and it is used together with a 32 bit runtime value
+	WarningArithmeticDivergence = "W007"
+)
+
+// Warning represents something in a policy that is valid, but probably not what the author intended
+type Warning struct {
+	Code        string
+	SyscallName string
+	Position    tree.Position
+	Message     string
+}
+
+func (w Warning) String() string {
+	result := fmt.Sprintf("[%s] %s (%s)", w.SyscallName, w.Message, w.Code)
+	if w.Position.IsKnown() {
+		return fmt.Sprintf("%s: %s", w.Position, result)
+	}
+	return result
+}
+
+// Warnings takes a unified policy that has passed EnsureValid and returns all the warnings for the rules in it,
+// except for the ones suppressed in the rules themselves. The policy will not be changed
+func Warnings(p tree.Policy) []Warning {
+	result := []Warning{}
+	seen := make(map[string]*tree.Rule)
+
+	for _, r := range p.Rules {
+		warn := func(code, format string, args ...interface{}) {
+			if !IsSuppressed(r, code) {
+				result = append(result, Warning{Code: code, SyscallName: r.Name, Position: r.Position, Message: fmt.Sprintf(format, args...)})
+			}
+		}
+
+		if oldR, ok := seen[r.Name]; ok {
+			if oldR.Position.IsKnown() {
+				warn(WarningUnreachableDuplicate, "rule is unreachable, since it duplicates the rule at %s", oldR.Position)
+			} else {
+				warn(WarningUnreachableDuplicate, "rule is unreachable, since it duplicates an earlier rule")
+			}
+			continue
+		}
+		seen[r.Name] = r
+
+		simplified := simplifier.Simplify(r.Body)
+		value, isConstant := simplified.(tree.BooleanLiteral)
+		_, writtenAsLiteral := r.Body.(tree.BooleanLiteral)
+		switch {
+		case isConstant && value.Value && !writtenAsLiteral:
+			warn(WarningAlwaysTrue, "rule body is always true: %s", tree.SourceString(r.Body))
+		case isConstant && !value.Value && !writtenAsLiteral:
+			warn(WarningAlwaysFalse, "rule body is always false: %s", tree.SourceString(r.Body))
+		case !isConstant:
+			if arg, ok := findContradiction(r.Body); ok {
+				warn(WarningContradictoryConditions, "the conditions on %s can never be true at the same time", tree.SourceString(arg))
+			}
+		}
+
+		for _, wp := range checkWidths(r.Body) {
+			warn(wp.code, "%s", wp.message)
+		}
+
+		if action, ok := sameAsDefault(p, r, simplified); ok {
+			warn(WarningSameAsDefault, "rule always results in the default policy action '%s'", action)
+		}
+	}
+
+	return result
+}
+
+// IsSuppressed returns true if the rule has a "# gosecco:ignore" comment for the given code
+func IsSuppressed(r *tree.Rule, code string) bool {
+	for _, s := range strings.Fields(r.Suppressions) {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// OrDefault returns the action of a rule, or the default action if the rule doesn't specify one
+func OrDefault(action, def string) string {
+	if action == "" {
+		return def
+	}
+	return action
+}
+
+// sameAsDefault returns true if every possible outcome of the rule is the same as the default policy action,
+// which means the rule could be removed without changing the behavior of the policy
+func sameAsDefault(p tree.Policy, r *tree.Rule, simplified tree.Expression) (string, bool) {
+	if p.DefaultPolicyAction == "" {
+		return "", false
+	}
+	outcomes := []string{
+		OrDefault(r.PositiveAction, p.DefaultPositiveAction),
+		OrDefault(r.NegativeAction, p.DefaultNegativeAction),
+	}
+	if v, ok := simplified.(tree.BooleanLiteral); ok {
+		if v.Value {
+			outcomes = outcomes[:1]
+		} else {
+			outcomes = outcomes[1:]
+		}
+	}
+	for _, o := range outcomes {
+		if o != p.DefaultPolicyAction {
+			return "", false
+		}
+	}
+	return p.DefaultPolicyAction, true
+}
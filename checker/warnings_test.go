@@ -0,0 +1,134 @@
+package checker
+
+import (
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+type WarningsSuite struct{}
+
+var _ = Suite(&WarningsSuite{})
+
+func arg(ix int) tree.Argument {
+	return tree.Argument{Type: tree.Full, Index: ix}
+}
+
+func cmp(op tree.ComparisonType, l, r tree.Numeric) tree.Comparison {
+	return tree.Comparison{Op: op, Left: l, Right: r}
+}
+
+func num(v uint64) tree.NumericLiteral {
+	return tree.NumericLiteral{Value: v}
+}
+
+func codes(ws []Warning) []string {
+	result := []string{}
+	for _, w := range ws {
+		result = append(result, w.Code)
+	}
+	return result
+}
+
+func (s *WarningsSuite) Test_reportsNothingForReasonableRules(c *C) {
+	p := tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}},
+		&tree.Rule{Name: "write", Body: tree.And{Left: cmp(tree.GT, arg(0), num(3)), Right: cmp(tree.LT, arg(0), num(5))}},
+		&tree.Rule{Name: "close", Body: tree.BooleanLiteral{false}, NegativeAction: "EPERM"},
+	}}
+
+	c.Assert(Warnings(p), HasLen, 0)
+}
+
+func (s *WarningsSuite) Test_reportsUnreachableDuplicates(c *C) {
+	p := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}, Position: tree.Position{File: "a.policy", Line: 1}},
+		&tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}, Position: tree.Position{File: "a.policy", Line: 4}},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(ws, HasLen, 1)
+	c.Assert(ws[0].String(), Equals, "a.policy:4: [read] rule is unreachable, since it duplicates the rule at a.policy:1 (W001)")
+}
+
+func (s *WarningsSuite) Test_reportsBodiesThatAreConstant(c *C) {
+	p := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: cmp(tree.EQL, num(1), tree.Arithmetic{Op: tree.MINUS, Left: num(2), Right: num(1)})},
+		&tree.Rule{Name: "write", Body: tree.And{Left: cmp(tree.EQL, arg(0), num(1)), Right: tree.BooleanLiteral{false}}},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(codes(ws), DeepEquals, []string{"W002", "W003"})
+	c.Assert(ws[0].String(), Equals, "[read] rule body is always true: 1 == 2 - 1 (W002)")
+	c.Assert(ws[1].String(), Equals, "[write] rule body is always false: arg0 == 1 && false (W003)")
+}
+
+func (s *WarningsSuite) Test_reportsContradictoryConditions(c *C) {
+	p := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.And{Left: cmp(tree.GT, arg(0), num(5)), Right: cmp(tree.LT, arg(0), num(3))}},
+		&tree.Rule{Name: "write", Body: tree.Or{
+			Left:  cmp(tree.EQL, arg(1), num(1)),
+			Right: tree.And{Left: cmp(tree.EQL, arg(2), num(3)), Right: cmp(tree.NEQL, num(3), arg(2))},
+		}},
+		&tree.Rule{Name: "close", Body: tree.And{Left: cmp(tree.GT, tree.Argument{Type: tree.Low, Index: 0}, num(0xFFFFFFFF)), Right: cmp(tree.EQL, arg(1), num(1))}},
+		&tree.Rule{Name: "open", Body: tree.And{Left: cmp(tree.GTE, num(10), arg(0)), Right: cmp(tree.GTE, arg(0), num(10))}},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(codes(ws), DeepEquals, []string{"W004", "W004", "W004"})
+	c.Assert(ws[0].Message, Equals, "the conditions on arg0 can never be true at the same time")
+	c.Assert(ws[1].Message, Equals, "the conditions on arg2 can never be true at the same time")
+	c.Assert(ws[2].Message, Equals, "the conditions on argL0 can never be true at the same time")
+}
+
+func (s *WarningsSuite) Test_reportsRulesWithTheSameEffectAsTheDefaultPolicy(c *C) {
+	p := tree.Policy{DefaultPositiveAction: "kill", DefaultNegativeAction: "allow", DefaultPolicyAction: "allow", Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: cmp(tree.EQL, arg(0), num(1)), PositiveAction: "allow"},
+		&tree.Rule{Name: "write", Body: tree.BooleanLiteral{false}},
+		&tree.Rule{Name: "close", Body: cmp(tree.EQL, arg(0), num(1))},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(codes(ws), DeepEquals, []string{"W005", "W005"})
+	c.Assert(ws[0].String(), Equals, "[read] rule always results in the default policy action 'allow' (W005)")
+	c.Assert(ws[1].SyscallName, Equals, "write")
+}
+
+func (s *WarningsSuite) Test_suppressedWarningsAreNotReported(c *C) {
+	p := tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: tree.Negation{Operand: tree.BooleanLiteral{true}}, Suppressions: "W003 W005"},
+		&tree.Rule{Name: "write", Body: tree.Negation{Operand: tree.BooleanLiteral{true}}, Suppressions: "W003"},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(len(ws), Equals, 1)
+	c.Assert(ws[0].SyscallName, Equals, "write")
+	c.Assert(ws[0].Code, Equals, "W005")
+}
+
+func (s *WarningsSuite) Test_reportsConstantsWiderThanHalfArguments(c *C) {
+	argL0 := tree.Argument{Type: tree.Low, Index: 0}
+	p := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: cmp(tree.EQL, argL0, tree.Arithmetic{Op: tree.LSH, Left: num(1), Right: num(40)})},
+		&tree.Rule{Name: "write", Body: cmp(tree.EQL, tree.Arithmetic{Op: tree.BINAND, Left: argL0, Right: tree.BinaryNegation{Operand: num(0)}}, num(1))},
+		&tree.Rule{Name: "close", Body: cmp(tree.EQL, arg(0), tree.Arithmetic{Op: tree.LSH, Left: num(1), Right: num(40)})},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(codes(ws), DeepEquals, []string{"W006", "W006"})
+	c.Assert(ws[0].Message, Equals, "the constant 0x10000000000 is wider than 32 bits, but is used with a 32 bit value: argL0 == 1 << 40")
+	c.Assert(ws[1].Message, Equals, "the constant 0xFFFFFFFFFFFFFFFF is wider than 32 bits, but is used with a 32 bit value: argL0 & ~0")
+}
+
+func (s *WarningsSuite) Test_reportsFoldingThatDiffersFrom32BitArithmetic(c *C) {
+	argH1 := tree.Argument{Type: tree.Hi, Index: 1}
+	overflow := tree.Arithmetic{Op: tree.RSH, Left: tree.Arithmetic{Op: tree.PLUS, Left: num(0xFFFFFFFF), Right: num(1)}, Right: num(1)}
+	p := tree.Policy{Rules: []*tree.Rule{
+		&tree.Rule{Name: "read", Body: cmp(tree.GT, overflow, argH1)},
+		&tree.Rule{Name: "write", Body: cmp(tree.GT, overflow, arg(1))},
+	}}
+
+	ws := Warnings(p)
+	c.Assert(codes(ws), DeepEquals, []string{"W007"})
+	c.Assert(ws[0].Message, Equals, "0xFFFFFFFF + 1 >> 1 is 0x80000000 when calculated at compile time, but would be 0 with 32 bit arithmetic: 0xFFFFFFFF + 1 >> 1 > argH1")
+}
@@ -0,0 +1,146 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/twtiger/gosecco/tree"
+)
+
+// The simplifier folds constant expressions using 64bit arithmetic, but everything that happens at runtime
+// uses the 32bit ALU of BPF. These checks find the places where that difference changes the meaning of a rule.
+
+const max32 = 0xFFFFFFFF
+
+// width describes what is known about a numeric expression: either it is a constant, in which case
+// we know both the value the simplifier will calculate and the value a 32bit machine would have
+// calculated, or it is a runtime value. Only half arguments are 32bit runtime values, since full
+// arguments are split up into comparisons of both halves
+type width struct {
+	constant  bool
+	value64   uint64
+	value32   uint64
+	runtime32 bool
+}
+
+type widthProblem struct {
+	code    string
+	message string
+}
+
+type widthChecker struct {
+	problems []widthProblem
+}
+
+func checkWidths(x tree.Expression) []widthProblem {
+	wc := &widthChecker{}
+	wc.checkBoolean(x)
+	return wc.problems
+}
+
+func (wc *widthChecker) report(code, format string, args ...interface{}) {
+	wc.problems = append(wc.problems, widthProblem{code, fmt.Sprintf(format, args...)})
+}
+
+func hex(v uint64) string {
+	return tree.SourceString(tree.NumericLiteral{Value: v})
+}
+
+// checkMixed reports problems with a constant used together with a 32bit runtime value in the given context
+func (wc *widthChecker) checkMixed(context tree.Expression, constant tree.Expression, w width) {
+	if w.value64 > max32 {
+		wc.report(WarningWideConstant, "the constant %s is wider than 32 bits, but is used with a 32 bit value: %s", hex(w.value64), tree.SourceString(context))
+	} else if w.value64 != w.value32 {
+		wc.report(WarningArithmeticDivergence, "%s is %s when calculated at compile time, but would be %s with 32 bit arithmetic: %s",
+			tree.SourceString(constant), hex(w.value64), hex(w.value32), tree.SourceString(context))
+	}
+}
+
+func (wc *widthChecker) checkPair(context, left, right tree.Expression, lw, rw width) {
+	if lw.runtime32 && rw.constant {
+		wc.checkMixed(context, right, rw)
+	}
+	if rw.runtime32 && lw.constant {
+		wc.checkMixed(context, left, lw)
+	}
+}
+
+func (wc *widthChecker) checkBoolean(x tree.Expression) {
+	switch v := x.(type) {
+	case tree.And:
+		wc.checkBoolean(v.Left)
+		wc.checkBoolean(v.Right)
+	case tree.Or:
+		wc.checkBoolean(v.Left)
+		wc.checkBoolean(v.Right)
+	case tree.Negation:
+		wc.checkBoolean(v.Operand)
+	case tree.Comparison:
+		wc.checkPair(v, v.Left, v.Right, wc.numeric(v.Left), wc.numeric(v.Right))
+	case tree.Inclusion:
+		lw := wc.numeric(v.Left)
+		for _, r := range v.Rights {
+			wc.checkPair(v, v.Left, r, lw, wc.numeric(r))
+		}
+	}
+}
+
+func (wc *widthChecker) numeric(x tree.Expression) width {
+	switch v := x.(type) {
+	case tree.NumericLiteral:
+		return width{constant: true, value64: v.Value, value32: v.Value & max32}
+	case tree.Argument:
+		return width{runtime32: v.Type != tree.Full}
+	case tree.BinaryNegation:
+		w := wc.numeric(v.Operand)
+		if w.constant {
+			return width{constant: true, value64: ^w.value64, value32: ^w.value32 & max32}
+		}
+		return w
+	case tree.Arithmetic:
+		lw, rw := wc.numeric(v.Left), wc.numeric(v.Right)
+		if lw.constant && rw.constant {
+			if r64, ok := calculate(v.Op, lw.value64, rw.value64); ok {
+				r32, _ := calculate(v.Op, lw.value32, rw.value32)
+				return width{constant: true, value64: r64, value32: r32 & max32}
+			}
+			return width{}
+		}
+		wc.checkPair(v, v.Left, v.Right, lw, rw)
+		return width{runtime32: lw.runtime32 || rw.runtime32}
+	}
+	return width{}
+}
+
+// calculate does the same calculation as the arithmetic simplifier. It returns false
+// if the result can't be calculated at all
+func calculate(op tree.ArithmeticType, l, r uint64) (uint64, bool) {
+	switch op {
+	case tree.PLUS:
+		return l + r, true
+	case tree.MINUS:
+		return l - r, true
+	case tree.MULT:
+		return l * r, true
+	case tree.DIV:
+		if r == 0 {
+			return 0, false
+		}
+		return l / r, true
+	case tree.MOD:
+		if r == 0 {
+			return 0, false
+		}
+		return l % r, true
+	case tree.BINAND:
+		return l & r, true
+	case tree.BINOR:
+		return l | r, true
+	case tree.BINXOR:
+		return l ^ r, true
+	case tree.LSH:
+		return l << r, true
+	case tree.RSH:
+		return l >> r, true
+	}
+	return 0, false
+}
@@ -0,0 +1,21 @@
+// gosecco-lsp is a language server for seccomp policy files. It communicates
+// using the Language Server Protocol over stdin and stdout, and gives diagnostics, hover information,
+// go-to-definition for macros and completion for syscalls, constants and macros.
+//
+// Files with extra definitions can be given in the initialization options of the client:
+//
+//	{"extraDefinitions": ["profiles/shared.seccomp"]}
+//
+// Relative paths will be resolved against the root of the workspace.
+package main
+
+import (
+	"log"
+	"os"
+)
+
+func main() {
+	if err := newServer(os.Stdin, os.Stdout).serve(); err != nil {
+		log.Fatal(err)
+	}
+}
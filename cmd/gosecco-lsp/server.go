@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+type server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	workspace *workspace
+}
+
+func newServer(in io.Reader, out io.Writer) *server {
+	return &server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		workspace: newWorkspace(),
+	}
+}
+
+// serve reads and handles messages until the client asks the server to exit or closes the connection
+func (s *server) serve() error {
+	for {
+		m, err := s.in.Peek(1)
+		if err == io.EOF || len(m) == 0 {
+			return nil
+		}
+		msg, err := readMessage(s.in)
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.dispatch(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) dispatch(msg *message) (err error) {
+	var result interface{}
+	var rerr *responseError
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				rerr = &responseError{Code: errorInternal, Message: fmt.Sprintf("%v", r)}
+			}
+		}()
+		result, rerr = s.handle(msg)
+	}()
+
+	if msg.ID == nil {
+		return nil
+	}
+	if rerr != nil {
+		return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rerr})
+	}
+	return writeMessage(s.out, successResponse{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *server) handle(msg *message) (interface{}, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		params := initializeParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params), nil
+	case "initialized", "shutdown", "$/cancelRequest":
+		return nil, nil
+	case "textDocument/didOpen":
+		params := didOpenParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.workspace.update(uriToPath(params.TextDocument.URI), params.TextDocument.Text)
+		return nil, s.publishAll()
+	case "textDocument/didChange":
+		params := didChangeParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if l := len(params.ContentChanges); l > 0 {
+			s.workspace.update(uriToPath(params.TextDocument.URI), params.ContentChanges[l-1].Text)
+		}
+		return nil, s.publishAll()
+	case "textDocument/didClose":
+		params := didCloseParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.workspace.close(uriToPath(params.TextDocument.URI))
+		if err := s.publish(params.TextDocument.URI, []diagnostic{}); err != nil {
+			return nil, err
+		}
+		return nil, s.publishAll()
+	case "textDocument/hover":
+		params := textDocumentPositionParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.workspace.hover(uriToPath(params.TextDocument.URI), params.Position), nil
+	case "textDocument/definition":
+		params := textDocumentPositionParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.workspace.definition(uriToPath(params.TextDocument.URI), params.Position), nil
+	case "textDocument/completion":
+		params := textDocumentPositionParams{}
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.workspace.completion(uriToPath(params.TextDocument.URI), params.Position), nil
+	}
+
+	if msg.ID == nil {
+		// Unknown notifications can safely be ignored
+		return nil, nil
+	}
+	return nil, &responseError{Code: errorMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+}
+
+func unmarshalParams(msg *message, v interface{}) *responseError {
+	if len(msg.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &responseError{Code: errorInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *server) initialize(params initializeParams) interface{} {
+	root := uriToPath(params.RootURI)
+	for _, ed := range params.InitializationOptions.ExtraDefinitions {
+		if !filepath.IsAbs(ed) && root != "" {
+			ed = filepath.Join(root, ed)
+		}
+		s.workspace.extraDefinitions = append(s.workspace.extraDefinitions, ed)
+	}
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1,
+			"hoverProvider":      true,
+			"definitionProvider": true,
+			"completionProvider": map[string]interface{}{},
+		},
+		"serverInfo": map[string]string{"name": "gosecco-lsp"},
+	}
+}
+
+func (s *server) publish(uri string, diags []diagnostic) *responseError {
+	err := writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+	if err != nil {
+		return &responseError{Code: errorInternal, Message: err.Error()}
+	}
+	return nil
+}
+
+// publishAll sends the diagnostics for all open documents, since a change
+// in one document can influence the others through the extra definitions
+func (s *server) publishAll() *responseError {
+	paths := []string{}
+	for p := range s.workspace.documents {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := s.publish(pathToURI(p), s.workspace.diagnose(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
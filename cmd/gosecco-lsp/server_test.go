@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ServerSuite struct{}
+
+var _ = Suite(&ServerSuite{})
+
+const testDocument = "# test policy\n" +
+	"VAL = 40 + 2\n" +
+	"f(x) = x == VAL\n" +
+	"read: f(arg0)\n" +
+	"write: arg0 ==\n" +
+	"close: arg0 == UNKNOWN\n" +
+	"foobar: 1\n" +
+	"open: argL0 == 0x100000000\n" +
+	"fstat: arg1 == O_RDONLY\n"
+
+func workspaceWith(path, text string) *workspace {
+	w := newWorkspace()
+	w.update(path, text)
+	return w
+}
+
+func messages(ds []diagnostic) []string {
+	result := []string{}
+	for _, d := range ds {
+		result = append(result, fmt.Sprintf("%d: %s", d.Range.Start.Line, d.Message))
+	}
+	return result
+}
+
+func (s *ServerSuite) Test_diagnose_reportsProblemsFromAllPhasesOnTheirLines(c *C) {
+	w := workspaceWith("/a.seccomp", testDocument)
+
+	c.Assert(messages(w.diagnose("/a.seccomp")), DeepEquals, []string{
+		"4: unexpected end of line",
+		"5: Variable 'UNKNOWN' is not defined",
+		"6: [foobar] invalid syscall",
+		"7: [open] the constant 0x100000000 is wider than 32 bits, but is used with a 32 bit value: argL0 == 0x100000000",
+		"7: [open] no literals larger than 0xFFFFFFFF allowed - this is probably a programmer error: 0x100000000",
+	})
+}
+
+func (s *ServerSuite) Test_diagnose_reportsNothingForValidDocument(c *C) {
+	w := workspaceWith("/a.seccomp", "read: 1\nwrite: arg0 == 1\n")
+	c.Assert(w.diagnose("/a.seccomp"), HasLen, 0)
+}
+
+func (s *ServerSuite) Test_diagnose_reportsEveryBrokenLine(c *C) {
+	w := workspaceWith("/a.seccomp", "read: arg0 ==\nwrite: arg0 == A\nfoo\nclose: arg0 == B\nfstat: 1\n")
+
+	c.Assert(messages(w.diagnose("/a.seccomp")), DeepEquals, []string{
+		"0: unexpected end of line",
+		"2: Couldn't parse line: 'foo' - it doesn't match any kind of valid syntax",
+		"1: Variable 'A' is not defined",
+		"3: Variable 'B' is not defined",
+	})
+}
+
+func (s *ServerSuite) Test_diagnose_endsTheRangeAtTheEndOfTheLineInUTF16(c *C) {
+	w := workspaceWith("/a.seccomp", "read: \u00e9\U0001F600")
+
+	ds := w.diagnose("/a.seccomp")
+	c.Assert(ds, HasLen, 1)
+	c.Assert(ds[0].Range.End, Equals, position{Line: 0, Character: 9})
+}
+
+func (s *ServerSuite) Test_diagnose_reportsCheckerWarningsWithTheirCodes(c *C) {
+	w := workspaceWith("/a.seccomp", "read: 1\nwrite: arg0 > 5 && arg0 < 3\n# gosecco:ignore W002\nclose: 1 == 1\n")
+
+	ds := w.diagnose("/a.seccomp")
+	c.Assert(messages(ds), DeepEquals, []string{"1: [write] the conditions on arg0 can never be true at the same time"})
+	c.Assert(ds[0].Severity, Equals, severityWarning)
+	c.Assert(ds[0].Code, Equals, "W004")
+}
+
+func (s *ServerSuite) Test_diagnose_reportsInvalidActionsWithSuggestions(c *C) {
+	w := workspaceWith("/a.seccomp", "DEFAULT_NEGATIVE = EPRM\nread[+allwo]: arg0 == 1\n")
+
+	c.Assert(messages(w.diagnose("/a.seccomp")), DeepEquals, []string{
+		"0: [DEFAULT_NEGATIVE] invalid action 'EPRM' - did you mean 'EPERM'?",
+		"1: [read] invalid positive action 'allwo' - did you mean 'allow'?",
+	})
+}
+
+func (s *ServerSuite) Test_hover_showsConstantsSyscallsAndMacros(c *C) {
+	w := workspaceWith("/a.seccomp", testDocument)
+
+	c.Assert(w.hover("/a.seccomp", position{Line: 8, Character: 18}).Contents.Value, Equals, "Constant `O_RDONLY` = 0 (0x0)")
+	c.Assert(w.hover("/a.seccomp", position{Line: 3, Character: 1}).Contents.Value, Equals, "System call `read` = 0")
+	c.Assert(w.hover("/a.seccomp", position{Line: 2, Character: 13}).Contents.Value, Equals,
+		"```\nVAL = 40 + 2\n```\nDefined at /a.seccomp:1\n\nValue: 42 (0x2A)")
+	c.Assert(w.hover("/a.seccomp", position{Line: 3, Character: 6}).Contents.Value, Equals,
+		"```\nf(x) = x == VAL\n```\nDefined at /a.seccomp:2")
+	c.Assert(w.hover("/a.seccomp", position{Line: 3, Character: 5}), IsNil)
+}
+
+func (s *ServerSuite) Test_definition_findsMacrosInExtraDefinitions(c *C) {
+	dir := c.MkDir()
+	shared := filepath.Join(dir, "shared.seccomp")
+	ioutil.WriteFile(shared, []byte("# shared\n\nSHARED = 1\n"), 0644)
+
+	w := workspaceWith("/a.seccomp", "LOCAL = 2\nread: arg0 == SHARED || arg0 == LOCAL\n")
+	w.extraDefinitions = []string{shared}
+
+	c.Assert(w.definition("/a.seccomp", position{Line: 1, Character: 16}), DeepEquals, []location{
+		{URI: "file://" + shared, Range: textRange{Start: position{Line: 2}, End: position{Line: 2}}},
+	})
+	c.Assert(w.definition("/a.seccomp", position{Line: 1, Character: 33}), DeepEquals, []location{
+		{URI: "file:///a.seccomp", Range: textRange{}},
+	})
+	c.Assert(w.definition("/a.seccomp", position{Line: 1, Character: 1}), HasLen, 0)
+	c.Assert(messages(w.diagnose("/a.seccomp")), HasLen, 0)
+}
+
+func (s *ServerSuite) Test_hover_countsCharactersInUTF16(c *C) {
+	w := workspaceWith("/a.seccomp", "# \u00e9\U0001F600\n\u00e9\U0001F600 read")
+
+	c.Assert(w.hover("/a.seccomp", position{Line: 1, Character: 5}).Contents.Value, Equals, "System call `read` = 0")
+	c.Assert(w.hover("/a.seccomp", position{Line: 1, Character: 2}), IsNil)
+}
+
+func (s *ServerSuite) Test_hover_seesChangesInOpenExtraDefinitions(c *C) {
+	w := workspaceWith("/a.seccomp", "read: arg0 == SHARED\n")
+	w.extraDefinitions = []string{"/shared.seccomp"}
+	w.update("/shared.seccomp", "SHARED = 1\n")
+	c.Assert(w.hover("/a.seccomp", position{Line: 0, Character: 15}).Contents.Value, Matches, "(?s).*Value: 1 .*")
+
+	w.update("/shared.seccomp", "SHARED = 2\n")
+	c.Assert(w.hover("/a.seccomp", position{Line: 0, Character: 15}).Contents.Value, Matches, "(?s).*Value: 2 .*")
+}
+
+func labels(items []completionItem) []string {
+	result := []string{}
+	for _, i := range items {
+		result = append(result, i.Label)
+	}
+	return result
+}
+
+func (s *ServerSuite) Test_completion_givesSyscallsInRuleHeads(c *C) {
+	w := workspaceWith("/a.seccomp", "readl")
+	c.Assert(labels(w.completion("/a.seccomp", position{Line: 0, Character: 5})), DeepEquals, []string{"readlink", "readlinkat"})
+}
+
+func (s *ServerSuite) Test_completion_givesMacrosAndConstantsInExpressions(c *C) {
+	w := workspaceWith("/a.seccomp", "O_RDWR_MASK = 3\nread: arg0 == O_RDW")
+	c.Assert(labels(w.completion("/a.seccomp", position{Line: 1, Character: 19})), DeepEquals, []string{"O_RDWR", "O_RDWR_MASK"})
+}
+
+func (s *ServerSuite) Test_completion_countsCharactersInUTF16(c *C) {
+	w := workspaceWith("/a.seccomp", "read: \u00e9 == O_RDW")
+	c.Assert(labels(w.completion("/a.seccomp", position{Line: 0, Character: 17})), DeepEquals, []string{"O_RDWR"})
+}
+
+func frame(method string, id int, params interface{}) string {
+	m := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != 0 {
+		m["id"] = id
+	}
+	body, _ := json.Marshal(m)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *ServerSuite) Test_serve_handlesAFullSession(c *C) {
+	in := frame("initialize", 1, map[string]interface{}{"rootUri": "file:///tmp"}) +
+		frame("initialized", 0, map[string]interface{}{}) +
+		frame("textDocument/didOpen", 0, map[string]interface{}{"textDocument": map[string]interface{}{"uri": "file:///tmp/a.seccomp", "text": "read: arg0 =="}}) +
+		frame("textDocument/didChange", 0, map[string]interface{}{"textDocument": map[string]interface{}{"uri": "file:///tmp/a.seccomp"}, "contentChanges": []interface{}{map[string]interface{}{"text": "read: 1"}}}) +
+		frame("textDocument/hover", 2, map[string]interface{}{"textDocument": map[string]interface{}{"uri": "file:///tmp/a.seccomp"}, "position": map[string]interface{}{"line": 0, "character": 1}}) +
+		frame("unknown/method", 3, nil) +
+		frame("shutdown", 4, nil) +
+		frame("exit", 0, nil)
+	out := &bytes.Buffer{}
+
+	c.Assert(newServer(strings.NewReader(in), out).serve(), IsNil)
+
+	r := bufio.NewReader(out)
+	results := []string{}
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		body, _ := json.Marshal(m)
+		results = append(results, string(body))
+	}
+
+	c.Assert(results, HasLen, 6)
+	c.Assert(results[0], Matches, `.*"id":1.*`)
+	c.Assert(results[1], Matches, `.*"method":"textDocument/publishDiagnostics".*unexpected end of line.*`)
+	c.Assert(results[2], Matches, `.*"method":"textDocument/publishDiagnostics".*"diagnostics":\[\].*`)
+	c.Assert(results[3], Matches, `.*"id":2.*`)
+	c.Assert(results[4], Matches, `.*"id":3.*`)
+	c.Assert(results[5], Matches, `.*"id":4.*`)
+}
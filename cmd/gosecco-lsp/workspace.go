@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/twtiger/gosecco/checker"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/precompilation"
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/tree"
+	"github.com/twtiger/gosecco/unifier"
+)
+
+// The defaults used when checking a document. They only need to be valid, since
+// the actual defaults will be decided when the policy is compiled
+const (
+	checkPositiveAction = "allow"
+	checkNegativeAction = "kill"
+	checkPolicyAction   = "kill"
+)
+
+// workspace keeps track of the open documents and the files with extra definitions.
+// The extra definitions will be made available to every document, in the same way
+// as the ExtraDefinitions in the SeccompSettings
+type workspace struct {
+	documents        map[string]string
+	extraDefinitions []string
+	// macroCache keeps the result of extraMacros until the next time a document changes
+	macroCache map[string][]map[string]tree.Macro
+}
+
+func newWorkspace() *workspace {
+	return &workspace{documents: make(map[string]string), macroCache: make(map[string][]map[string]tree.Macro)}
+}
+
+// update sets the text of an open document
+func (w *workspace) update(path, text string) {
+	w.documents[path] = text
+	w.macroCache = make(map[string][]map[string]tree.Macro)
+}
+
+// close forgets an open document, so that it will be read from the file again
+func (w *workspace) close(path string) {
+	delete(w.documents, path)
+	w.macroCache = make(map[string][]map[string]tree.Macro)
+}
+
+func (w *workspace) source(path string) parser.Source {
+	if text, ok := w.documents[path]; ok {
+		return &parser.StringSource{Name: path, Content: text}
+	}
+	return &parser.FileSource{Filename: path}
+}
+
+func (w *workspace) lines(path string) []string {
+	return strings.Split(w.documents[path], "\n")
+}
+
+// extraMacros returns the macros defined in all extra definition files except for the given one.
+// Files that can't be parsed or unified are ignored, since their problems will be reported when they are opened
+func (w *workspace) extraMacros(except string) []map[string]tree.Macro {
+	if result, ok := w.macroCache[except]; ok {
+		return result
+	}
+	result := []map[string]tree.Macro{}
+	for _, ed := range w.extraDefinitions {
+		if ed == except {
+			continue
+		}
+		rp, err := parser.Parse(w.source(ed))
+		if err != nil {
+			continue
+		}
+		p, err := unifier.Unify(rp, nil, "", "", "")
+		if err != nil {
+			continue
+		}
+		result = append(result, p.Macros)
+	}
+	w.macroCache[except] = result
+	return result
+}
+
+func lineDiagnostic(lines []string, line int, severity int, msg string) diagnostic {
+	end := 0
+	if line >= 0 && line < len(lines) {
+		end = characterOffset(lines[line], len(lines[line]))
+	}
+	return diagnostic{
+		Range:    textRange{Start: position{Line: line}, End: position{Line: line, Character: end}},
+		Severity: severity,
+		Source:   "gosecco",
+		Message:  msg,
+	}
+}
+
+// diagnose runs the document through all the phases before compilation, and reports
+// every problem found on the line it belongs to
+func (w *workspace) diagnose(path string) []diagnostic {
+	lines := w.lines(path)
+	result := []diagnostic{}
+
+	rp, parseErrors := parser.ParseLenient(&parser.StringSource{Name: path, Content: w.documents[path]})
+	for _, pe := range parseErrors {
+		msg := strings.TrimPrefix(pe.Error(), pe.Position().String()+": ")
+		result = append(result, lineDiagnostic(lines, pe.Position().Line, severityError, msg))
+	}
+
+	pol, unifyErrors := unifier.UnifyLenient(rp, w.extraMacros(path), checkPositiveAction, checkNegativeAction, checkPolicyAction)
+	for _, re := range unifyErrors {
+		result = append(result, lineDiagnostic(lines, re.Position.Line, severityError, re.Err.Error()))
+	}
+
+	invalid := make(map[tree.Position]bool)
+	for _, e := range checker.EnsureValid(pol) {
+		if re, ok := e.(*tree.RuleError); ok {
+			invalid[re.Position] = true
+			result = append(result, lineDiagnostic(lines, re.Position.Line, severityError, fmt.Sprintf("[%s] %s", re.SyscallName, re.Err)))
+		} else if ae, ok := e.(*checker.ActionError); ok && ae.Position.File == path {
+			result = append(result, lineDiagnostic(lines, ae.Position.Line, severityError, fmt.Sprintf("[%s] %s", ae.Setting, ae.Err)))
+		} else {
+			result = append(result, lineDiagnostic(lines, 0, severityError, e.Error()))
+		}
+	}
+
+	valid := pol
+	valid.Rules = nil
+	for _, r := range pol.Rules {
+		if !invalid[r.Position] {
+			valid.Rules = append(valid.Rules, r)
+		}
+	}
+	for _, wr := range checker.Warnings(valid) {
+		d := lineDiagnostic(lines, wr.Position.Line, severityWarning, fmt.Sprintf("[%s] %s", wr.SyscallName, wr.Message))
+		d.Code = wr.Code
+		result = append(result, d)
+	}
+
+	for _, r := range valid.Rules {
+		single := tree.Policy{Rules: []*tree.Rule{r}}
+		simplifier.SimplifyPolicy(&single)
+		for _, e := range precompilation.EnsureValid(single) {
+			result = append(result, lineDiagnostic(lines, r.Position.Line, severityError, e.Error()))
+		}
+	}
+
+	return result
+}
+
+// macrosVisibleAt returns all macros that can be used at the given line in the document.
+// Definitions in the document override the ones from extra definition files
+func (w *workspace) macrosVisibleAt(path string, line int) map[string]tree.Macro {
+	result := make(map[string]tree.Macro)
+	for _, m := range w.extraMacros(path) {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+
+	rp, _ := parser.ParseLenient(&parser.StringSource{Name: path, Content: w.documents[path]})
+	for _, rm := range rp.RuleOrMacros {
+		if m, ok := rm.(tree.Macro); ok && m.Position.Line <= line {
+			result[m.Name] = m
+		}
+	}
+	return result
+}
+
+func isWordCharacter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// wordAt returns the word around the given character, and the part of it before the character
+func wordAt(line string, character int) (string, string) {
+	if character > len(line) {
+		character = len(line)
+	}
+	start, end := character, character
+	for start > 0 && isWordCharacter(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordCharacter(line[end]) {
+		end++
+	}
+	return line[start:end], line[start:character]
+}
+
+func (w *workspace) wordAt(path string, pos position) (string, string) {
+	lines := w.lines(path)
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", ""
+	}
+	return wordAt(lines[pos.Line], byteOffset(lines[pos.Line], pos.Character))
+}
+
+// byteOffset converts a character in a position, which is counted in UTF-16 code units,
+// to the offset in bytes in the line. Characters past the end of the line give the length of the line
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// characterOffset converts an offset in bytes in the line to a character in a position,
+// which is counted in UTF-16 code units
+func characterOffset(line string, offset int) int {
+	return len(utf16.Encode([]rune(line[:offset])))
+}
+
+func macroSignature(m tree.Macro) string {
+	if len(m.ArgumentNames) == 0 {
+		return fmt.Sprintf("%s = %s", m.Name, tree.SourceString(m.Body))
+	}
+	return fmt.Sprintf("%s(%s) = %s", m.Name, strings.Join(m.ArgumentNames, ", "), tree.SourceString(m.Body))
+}
+
+func describeValue(v uint64) string {
+	return fmt.Sprintf("%d (0x%X)", v, v)
+}
+
+func (w *workspace) hover(path string, pos position) *hover {
+	word, _ := w.wordAt(path, pos)
+	if word == "" {
+		return nil
+	}
+
+	var text string
+	macros := w.macrosVisibleAt(path, pos.Line)
+	if m, ok := macros[word]; ok {
+		text = fmt.Sprintf("```\n%s\n```\nDefined at %s", macroSignature(m), m.Position)
+		if len(m.ArgumentNames) == 0 {
+			if x, err := unifier.Expand(m.Body, macros); err == nil {
+				x = simplifier.Simplify(x)
+				if lit, ok := x.(tree.NumericLiteral); ok {
+					text += "\n\nValue: " + describeValue(lit.Value)
+				} else {
+					text += "\n\nExpands to: `" + tree.SourceString(x) + "`"
+				}
+			}
+		}
+	} else if v, ok := constants.AllConstants[word]; ok {
+		kind := "Constant"
+		if _, isErr := constants.AllErrors[word]; isErr {
+			kind = "Error number"
+		}
+		text = fmt.Sprintf("%s `%s` = %s", kind, word, describeValue(uint64(v)))
+	} else if nr, ok := constants.Syscalls[word]; ok {
+		text = fmt.Sprintf("System call `%s` = %d", word, nr)
+	} else {
+		return nil
+	}
+
+	return &hover{Contents: markupContent{Kind: "markdown", Value: text}}
+}
+
+func (w *workspace) definition(path string, pos position) []location {
+	word, _ := w.wordAt(path, pos)
+	m, ok := w.macrosVisibleAt(path, pos.Line)[word]
+	if word == "" || !ok || !m.Position.IsKnown() {
+		return []location{}
+	}
+	p := position{Line: m.Position.Line}
+	return []location{{URI: pathToURI(m.Position.File), Range: textRange{Start: p, End: p}}}
+}
+
+// isRuleHead returns true if the text is the start of a line before the rule separator
+func isRuleHead(text string) bool {
+	return !strings.ContainsAny(text, ":=") && !strings.HasPrefix(strings.TrimSpace(text), "#")
+}
+
+func (w *workspace) completion(path string, pos position) []completionItem {
+	lines := w.lines(path)
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return []completionItem{}
+	}
+	line := lines[pos.Line]
+	cursor := byteOffset(line, pos.Character)
+	_, prefix := wordAt(line, cursor)
+	matches := func(s string) bool {
+		return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+	}
+
+	result := []completionItem{}
+	if isRuleHead(line[:cursor]) {
+		for name, nr := range constants.Syscalls {
+			if matches(name) {
+				result = append(result, completionItem{Label: name, Kind: completionKindFunction, Detail: fmt.Sprintf("syscall %d", nr)})
+			}
+		}
+	} else {
+		for name, m := range w.macrosVisibleAt(path, pos.Line-1) {
+			if matches(name) {
+				result = append(result, completionItem{Label: name, Kind: completionKindVariable, Detail: macroSignature(m)})
+			}
+		}
+		for name, v := range constants.AllConstants {
+			if matches(name) {
+				result = append(result, completionItem{Label: name, Kind: completionKindConstant, Detail: describeValue(uint64(v))})
+			}
+		}
+	}
+
+	sort.Sort(byLabel(result))
+	return result
+}
+
+type byLabel []completionItem
+
+func (s byLabel) Len() int           { return len(s) }
+func (s byLabel) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byLabel) Less(i, j int) bool { return s[i].Label < s[j].Label }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/cost"
+	"github.com/twtiger/gosecco/parser"
+)
+
+func runCost(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cost", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settings := settingsFlags(fs)
+	maxPath := fs.Int("max-path", 0, "fail if any path executes more than this many instructions")
+	maxLength := fs.Int("max-length", cost.MaxInstructions, "fail if the program is longer than this many instructions")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco cost [-max-path <n>] [-max-length <n>] [-positive <action>] [-negative <action>] [-default <action>] <policy>\n")
+		fmt.Fprintf(stderr, "Reports how many instructions the compiled policy executes for every system call, and exits with 1 if a limit is exceeded.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	program, origins, err := gosecco.PrepareSourceWithSourceMap(&parser.FileSource{fs.Arg(0)}, *settings)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco cost: %s\n", err)
+		return 2
+	}
+	r, err := cost.Analyse(program, origins)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco cost: %s\n", err)
+		return 2
+	}
+	r.WriteText(stdout)
+
+	result := 0
+	if r.Length > *maxLength {
+		fmt.Fprintf(stderr, "gosecco cost: the program has %d instructions, more than the limit of %d\n", r.Length, *maxLength)
+		result = 1
+	}
+	if *maxPath > 0 && len(r.Worst.Instructions) > *maxPath {
+		fmt.Fprintf(stderr, "gosecco cost: %s executes %d instructions, more than the limit of %d\n", r.Worst.Syscall, len(r.Worst.Instructions), *maxPath)
+		result = 1
+	}
+	return result
+}
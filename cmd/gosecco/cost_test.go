@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CostSuite struct{}
+
+var _ = Suite(&CostSuite{})
+
+func (s *CostSuite) Test_cost_reportsTheCostOfAPolicy(c *C) {
+	dir := c.MkDir()
+	policy := filepath.Join(dir, "x.policy")
+	ioutil.WriteFile(policy, []byte("read: arg0 == 0 || arg0 == 1\nwrite: true\nclose: true\n"), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"cost", policy}, strings.NewReader(""), stdout, stderr)
+	c.Assert(code, Equals, 0)
+	c.Assert(strings.SplitN(stdout.String(), "\n", 3)[:2], DeepEquals, []string{
+		"program length: 16 of 4096 instructions",
+		"worst case: 11 instructions for read, returning kill",
+	})
+	c.Assert(stderr.String(), Equals, "")
+}
+
+func (s *CostSuite) Test_cost_failsWhenALimitIsExceeded(c *C) {
+	dir := c.MkDir()
+	policy := filepath.Join(dir, "x.policy")
+	ioutil.WriteFile(policy, []byte("read: arg0 == 0 || arg0 == 1\nwrite: true\nclose: true\n"), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"cost", "-max-path", "10", "-max-length", "15", policy}, strings.NewReader(""), stdout, stderr)
+	c.Assert(code, Equals, 1)
+	c.Assert(stderr.String(), Equals, ""+
+		"gosecco cost: the program has 16 instructions, more than the limit of 15\n"+
+		"gosecco cost: read executes 11 instructions, more than the limit of 10\n")
+}
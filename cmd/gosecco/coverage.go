@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/coverage"
+	"github.com/twtiger/gosecco/parser"
+)
+
+func runCoverage(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settings := settingsFlags(fs)
+	lcov := fs.String("lcov", "", "also write the coverage in the lcov format to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco coverage [-lcov <file>] [-positive <action>] [-negative <action>] [-default <action>] <policy> [inputs]\n")
+		fmt.Fprintf(stderr, "The inputs have one working memory per line, such as \"nr=read arg0=1\", and are read from stdin if no file is given.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := reportCoverage(fs.Arg(0), fs.Arg(1), *lcov, *settings, stdin, stdout); err != nil {
+		fmt.Fprintf(stderr, "gosecco coverage: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func reportCoverage(policy, inputs, lcov string, s gosecco.SeccompSettings, stdin io.Reader, stdout io.Writer) error {
+	program, origins, err := gosecco.PrepareSourceWithSourceMap(&parser.FileSource{policy}, s)
+	if err != nil {
+		return err
+	}
+
+	in := stdin
+	if inputs != "" {
+		f, err := os.Open(inputs)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	ds, err := coverage.ParseInputs(in)
+	if err != nil {
+		return err
+	}
+
+	r := coverage.NewReport(program, origins)
+	for _, d := range ds {
+		r.Add(d)
+	}
+	r.WriteText(stdout)
+
+	if lcov != "" {
+		f, err := os.Create(lcov)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r.WriteLcov(f)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type CoverageSuite struct{}
+
+var _ = Suite(&CoverageSuite{})
+
+func (s *CoverageSuite) Test_coverage_reportsAsTextAndLcov(c *C) {
+	dir := c.MkDir()
+	policy, lcov := filepath.Join(dir, "x.policy"), filepath.Join(dir, "x.lcov")
+	ioutil.WriteFile(policy, []byte("read: argL0 == 0\n"), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"coverage", "-lcov", lcov, policy}, strings.NewReader("nr=read\nnr=write\n"), stdout, stderr)
+
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout.String(), Equals, ""+
+		policy+":0: read: matched 1 of 2\n"+
+		"\targL0 == 0: true 1, false 0 - partially covered\n"+
+		"returned: allow 1, kill 1\n"+
+		"instructions: 9 of 9 executed\n")
+	written, _ := ioutil.ReadFile(lcov)
+	c.Assert(string(written), Matches, "TN:\nSF:.*x.policy\nBRDA:1,0,0,1\nBRDA:1,0,1,0\nDA:1,1\n(.|\n)*")
+}
+
+func (s *CoverageSuite) Test_coverage_reportsInvalidInputs(c *C) {
+	dir := c.MkDir()
+	policy := filepath.Join(dir, "x.policy")
+	ioutil.WriteFile(policy, []byte("read: argL0 == 0\n"), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"coverage", policy}, strings.NewReader("nr=foo\n"), stdout, stderr)
+
+	c.Assert(code, Equals, 1)
+	c.Assert(stderr.String(), Equals, "gosecco coverage: line 1: invalid value for nr: 'foo'\n")
+}
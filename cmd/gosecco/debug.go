@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+
+	"golang.org/x/sys/unix"
+)
+
+const debugHelp = `Commands:
+  load program <file>     load a filter program in the asm format
+  load memory <memory>    set the working memory, such as "nr=read arch=0xC000003E arg0=1"
+  memory                  show the working memory
+  step [n]                execute the next n instructions, default 1
+  run                     execute until a breakpoint is hit or the program returns
+  trace                   restart and execute the whole program, showing every instruction
+  break <pc>              set a breakpoint at the given instruction
+  break                   list the breakpoints
+  break reset             remove all breakpoints
+  registers               show the registers
+  list                    show the program, marking the next instruction and breakpoints
+  reset                   restart the execution from the first instruction
+  quit                    leave the debugger
+`
+
+const debugPrompt = "(gosecco) "
+
+// debugger keeps the state of an interactive debugging session. Every change to the
+// program or the working memory restarts the execution
+type debugger struct {
+	out         io.Writer
+	program     []unix.SockFilter
+	memory      data.SeccompWorkingMemory
+	execution   *emulator.Execution
+	breakpoints map[uint32]bool
+}
+
+func newDebugger(out io.Writer) *debugger {
+	d := &debugger{out: out, breakpoints: make(map[uint32]bool)}
+	d.reset()
+	return d
+}
+
+func runDebug(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	memory := fs.String("memory", "", "the working memory to run the program against, such as \"nr=read arg0=1\"")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco debug [-memory <memory>] [program.asm]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	d := newDebugger(stdout)
+	if fs.NArg() > 0 {
+		if err := d.loadProgram(fs.Arg(0)); err != nil {
+			fmt.Fprintf(stderr, "gosecco debug: %s\n", err)
+			return 1
+		}
+	}
+	if *memory != "" {
+		if err := d.loadMemory(*memory); err != nil {
+			fmt.Fprintf(stderr, "gosecco debug: %s\n", err)
+			return 1
+		}
+	}
+
+	d.repl(stdin)
+	return 0
+}
+
+func (d *debugger) printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.out, format, args...)
+}
+
+func (d *debugger) reset() {
+	d.execution = emulator.NewExecution(d.memory, d.program)
+}
+
+func (d *debugger) loadProgram(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	program, err := asm.ParseStrict(string(content))
+	if err != nil {
+		return fmt.Errorf("%s:%s", path, err)
+	}
+	d.program = program
+	d.reset()
+	return nil
+}
+
+func (d *debugger) loadMemory(desc string) error {
+	m, err := data.ParseWorkingMemory(desc)
+	if err != nil {
+		return err
+	}
+	d.memory = m
+	d.reset()
+	return nil
+}
+
+func (d *debugger) repl(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	d.printf(debugPrompt)
+	for scanner.Scan() {
+		if !d.execute(strings.Fields(scanner.Text())) {
+			return
+		}
+		d.printf(debugPrompt)
+	}
+	d.printf("\n")
+}
+
+// execute runs one command, and returns false if the session should end
+func (d *debugger) execute(words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+
+	var err error
+	switch words[0] {
+	case "load":
+		err = d.load(words[1:])
+	case "memory":
+		d.printMemory()
+	case "step":
+		err = d.step(words[1:])
+	case "run":
+		d.run()
+	case "trace":
+		d.reset()
+		for d.stepOne() {
+		}
+	case "break":
+		err = d.breakpoint(words[1:])
+	case "registers":
+		d.printRegisters()
+	case "list":
+		d.list()
+	case "reset":
+		d.reset()
+	case "help":
+		d.printf(debugHelp)
+	case "quit", "exit":
+		return false
+	default:
+		err = fmt.Errorf("unknown command '%s' - try help", words[0])
+	}
+
+	if err != nil {
+		d.printf("error: %s\n", err)
+	}
+	return true
+}
+
+func (d *debugger) load(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("expected 'load program <file>' or 'load memory <memory>'")
+	}
+	switch args[0] {
+	case "program":
+		if err := d.loadProgram(args[1]); err != nil {
+			return err
+		}
+		d.printf("loaded %d instructions\n", len(d.program))
+	case "memory":
+		if err := d.loadMemory(strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+		d.printMemory()
+	default:
+		return fmt.Errorf("can only load program or memory, not '%s'", args[0])
+	}
+	return nil
+}
+
+func (d *debugger) step(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 1 {
+			return fmt.Errorf("invalid number of steps: '%s'", args[0])
+		}
+		n = v
+	}
+	for i := 0; i < n && d.stepOne(); i++ {
+	}
+	return nil
+}
+
+// run executes until the program finishes or the next instruction has a breakpoint. The instruction the
+// execution is standing on will always be executed, so that run can be used to continue from a breakpoint
+func (d *debugger) run() {
+	for d.stepOne() {
+		if d.breakpoints[d.execution.PC()] {
+			d.printf("breakpoint at %d\n", d.execution.PC())
+			return
+		}
+	}
+}
+
+// stepOne executes one instruction and prints it. It returns false if there is nothing left to execute
+func (d *debugger) stepOne() bool {
+	s, ok := d.execution.Step()
+	if !ok {
+		d.printResult()
+		return false
+	}
+	d.printf("%s\n", formatStep(s))
+	if _, finished := d.execution.Result(); finished {
+		d.printResult()
+		return false
+	}
+	return true
+}
+
+func (d *debugger) printResult() {
+	if err := d.execution.Err(); err != nil {
+		d.printf("error: %s\n", err)
+		return
+	}
+	result, _ := d.execution.Result()
+	d.printf("returned %08X (%s)\n", result, actions.Describe(result))
+}
+
+func (d *debugger) breakpoint(args []string) error {
+	if len(args) == 0 {
+		pcs := []int{}
+		for pc := range d.breakpoints {
+			pcs = append(pcs, int(pc))
+		}
+		sort.Ints(pcs)
+		if len(pcs) == 0 {
+			d.printf("no breakpoints\n")
+		}
+		for _, pc := range pcs {
+			d.printf("breakpoint at %d\n", pc)
+		}
+		return nil
+	}
+	if args[0] == "reset" {
+		d.breakpoints = make(map[uint32]bool)
+		return nil
+	}
+	pc, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil || pc >= uint64(len(d.program)) {
+		return fmt.Errorf("invalid instruction index: '%s'", args[0])
+	}
+	d.breakpoints[uint32(pc)] = true
+	return nil
+}
+
+func (d *debugger) printMemory() {
+	m := d.memory
+	d.printf("nr=%d arch=0x%X ip=0x%X", m.NR, m.Arch, m.InstructionPointer)
+	for i, a := range m.Args {
+		d.printf(" arg%d=0x%X", i, a)
+	}
+	d.printf("\n")
+}
+
+func (d *debugger) printRegisters() {
+	r := d.execution.Registers()
+	d.printf("pc: %d\nA:  %08X\nX:  %08X\n", d.execution.PC(), r.A, r.X)
+	for i, m := range r.M {
+		d.printf("M[%d]: %08X\n", i, m)
+	}
+}
+
+func (d *debugger) list() {
+	for i, f := range d.program {
+		marker := " "
+		if uint32(i) == d.execution.PC() {
+			marker = ">"
+		}
+		if d.breakpoints[uint32(i)] {
+			marker += "*"
+		} else {
+			marker += " "
+		}
+		d.printf("%s %3d: %s\n", marker, i, instructionString(f))
+	}
+}
+
+func instructionString(f unix.SockFilter) string {
+	return strings.TrimSpace(asm.Dump([]unix.SockFilter{f}))
+}
+
+func formatStep(s emulator.Step) string {
+	result := fmt.Sprintf("%3d: %-24s A: %08X -> %08X  X: %08X -> %08X",
+		s.PC, strings.Replace(instructionString(s.Instruction), "\t", " ", -1), s.Before.A, s.After.A, s.Before.X, s.After.X)
+	for i := range s.Before.M {
+		if s.Before.M[i] != s.After.M[i] {
+			result += fmt.Sprintf("  M[%d]: %08X -> %08X", i, s.Before.M[i], s.After.M[i])
+		}
+	}
+	return result
+}
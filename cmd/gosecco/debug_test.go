@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type DebugSuite struct{}
+
+var _ = Suite(&DebugSuite{})
+
+const debugProgram = "" +
+	"ld_abs\t10\n" +
+	"st\t2\n" +
+	"jeq_k\t00\t01\t2A\n" +
+	"ret_k\t7FFF0000\n" +
+	"ret_k\t50001\n"
+
+func session(c *C, args []string, input string) (string, string, int) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "program.asm")
+	ioutil.WriteFile(path, []byte(debugProgram), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(append(append([]string{"debug"}, args...), path), strings.NewReader(input), stdout, stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func (s *DebugSuite) Test_debug_stepsThroughAProgram(c *C) {
+	out, _, code := session(c, []string{"-memory", "nr=read arg0=42"}, "step 2\nregisters\nstep\nstep 5\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, ""+
+		"(gosecco)   0: ld_abs 10                A: 00000000 -> 0000002A  X: 00000000 -> 00000000\n"+
+		"  1: st 2                     A: 0000002A -> 0000002A  X: 00000000 -> 00000000  M[2]: 00000000 -> 0000002A\n"+
+		"(gosecco) pc: 2\nA:  0000002A\nX:  00000000\n"+
+		"M[0]: 00000000\nM[1]: 00000000\nM[2]: 0000002A\nM[3]: 00000000\n"+
+		"M[4]: 00000000\nM[5]: 00000000\nM[6]: 00000000\nM[7]: 00000000\n"+
+		"M[8]: 00000000\nM[9]: 00000000\nM[10]: 00000000\nM[11]: 00000000\n"+
+		"M[12]: 00000000\nM[13]: 00000000\nM[14]: 00000000\nM[15]: 00000000\n"+
+		"(gosecco)   2: jeq_k 00 01 2A           A: 0000002A -> 0000002A  X: 00000000 -> 00000000\n"+
+		"(gosecco)   3: ret_k 7FFF0000           A: 0000002A -> 0000002A  X: 00000000 -> 00000000\n"+
+		"returned 7FFF0000 (allow)\n"+
+		"(gosecco) \n")
+}
+
+func (s *DebugSuite) Test_debug_stopsAtBreakpointsAndCanReloadMemory(c *C) {
+	out, _, _ := session(c, nil, "break 2\nbreak\nrun\nlist\nload memory arg0=1\nrun\nrun\nquit\nstep\n")
+
+	c.Assert(out, Equals, ""+
+		"(gosecco) (gosecco) breakpoint at 2\n"+
+		"(gosecco)   0: ld_abs 10                A: 00000000 -> 00000000  X: 00000000 -> 00000000\n"+
+		"  1: st 2                     A: 00000000 -> 00000000  X: 00000000 -> 00000000\n"+
+		"breakpoint at 2\n"+
+		"(gosecco)      0: ld_abs\t10\n"+
+		"     1: st\t2\n"+
+		">*   2: jeq_k\t00\t01\t2A\n"+
+		"     3: ret_k\t7FFF0000\n"+
+		"     4: ret_k\t50001\n"+
+		"(gosecco) nr=0 arch=0x0 ip=0x0 arg0=0x1 arg1=0x0 arg2=0x0 arg3=0x0 arg4=0x0 arg5=0x0\n"+
+		"(gosecco)   0: ld_abs 10                A: 00000000 -> 00000001  X: 00000000 -> 00000000\n"+
+		"  1: st 2                     A: 00000001 -> 00000001  X: 00000000 -> 00000000  M[2]: 00000000 -> 00000001\n"+
+		"breakpoint at 2\n"+
+		"(gosecco)   2: jeq_k 00 01 2A           A: 00000001 -> 00000001  X: 00000000 -> 00000000\n"+
+		"  4: ret_k 50001              A: 00000001 -> 00000001  X: 00000000 -> 00000000\n"+
+		"returned 00050001 (EPERM)\n"+
+		"(gosecco) ")
+}
+
+func (s *DebugSuite) Test_debug_reportsProblems(c *C) {
+	out, _, _ := session(c, nil, "frobnicate\nstep x\nbreak 99\nload memory arg9=1\n")
+	c.Assert(out, Equals, ""+
+		"(gosecco) error: unknown command 'frobnicate' - try help\n"+
+		"(gosecco) error: invalid number of steps: 'x'\n"+
+		"(gosecco) error: invalid instruction index: '99'\n"+
+		"(gosecco) error: unknown field 'arg9' - expected nr, arch, ip or arg0 to arg5\n"+
+		"(gosecco) \n")
+
+	_, stderr, code := session(c, []string{"-memory", "nr"}, "")
+	c.Assert(code, Equals, 1)
+	c.Assert(stderr, Equals, "gosecco debug: expected an assignment in the form name=value, but found 'nr'\n")
+}
+
+func (s *DebugSuite) Test_run_reportsUnknownCommands(c *C) {
+	stderr := &bytes.Buffer{}
+	c.Assert(run([]string{"frobnicate"}, nil, nil, stderr), Equals, 2)
+	c.Assert(stderr.String(), Matches, "(?s)gosecco: unknown command 'frobnicate'.*debug .*")
+}
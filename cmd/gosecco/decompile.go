@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/decompiler"
+	"github.com/twtiger/gosecco/equivalence"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+)
+
+func fromText(parse func(string) ([]unix.SockFilter, error)) func([]byte) ([]unix.SockFilter, error) {
+	return func(b []byte) ([]unix.SockFilter, error) {
+		return parse(string(b))
+	}
+}
+
+var programFormats = map[string]func([]byte) ([]unix.SockFilter, error){
+	"asm":    fromText(asm.ParseStrict),
+	"binary": asm.ParseBinary,
+	"c":      fromText(asm.ParseC),
+	"go":     fromText(asm.ParseGo),
+}
+
+func runDecompile(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("decompile", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "asm", "the format of the filter program: asm, binary, c or go")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco decompile [-format <format>] <program>\n")
+		fmt.Fprintf(stderr, "Prints a policy that behaves like the filter program, and warns if it isn't exact.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	parse, ok := programFormats[*format]
+	if fs.NArg() != 1 || !ok {
+		fs.Usage()
+		return 2
+	}
+
+	content, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco decompile: %s\n", err)
+		return 2
+	}
+	program, err := parse(content)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco decompile: %s: %s\n", fs.Arg(0), err)
+		return 2
+	}
+	p, err := decompiler.Decompile(program)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco decompile: %s\n", err)
+		return 1
+	}
+	source := tree.PolicySource(p)
+	fmt.Fprint(stdout, source)
+
+	if err := checkDecompiled(program, p, source); err != nil {
+		fmt.Fprintf(stderr, "gosecco decompile: warning: %s\n", err)
+	}
+	return 0
+}
+
+// checkDecompiled compiles the source of the decompiled policy, and returns an error describing where it
+// behaves differently from the original program, if it does
+func checkDecompiled(program []unix.SockFilter, p tree.Policy, source string) error {
+	recompiled, err := gosecco.PreparePolicy(&parser.StringSource{Name: "decompiled", Content: source}, gosecco.SeccompSettings{})
+	if err != nil {
+		return err
+	}
+	recompiled.ActionOnX32 = p.ActionOnX32
+	recompiled.ActionOnAuditFailure = p.ActionOnAuditFailure
+	result, err := compiler.Compile(recompiled)
+	if err != nil {
+		return err
+	}
+	counterexample, err := equivalence.Check(program, result)
+	if err != nil || counterexample == nil {
+		return err
+	}
+	m := counterexample.Memory
+	return fmt.Errorf("the policy is not exact - with nr=%d arch=0x%X args=%v the filter returns %s, but the policy %s",
+		m.NR, m.Arch, m.Args, actions.Describe(counterexample.Left), actions.Describe(counterexample.Right))
+}
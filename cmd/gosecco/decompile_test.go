@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DecompileSuite struct{}
+
+var _ = Suite(&DecompileSuite{})
+
+func decompile(c *C, args []string, program string) (string, string, int) {
+	path := filepath.Join(c.MkDir(), "filter")
+	ioutil.WriteFile(path, []byte(program), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(append(append([]string{"decompile"}, args...), path), strings.NewReader(""), stdout, stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func (s *DecompileSuite) Test_decompile_printsThePolicy(c *C) {
+	out, errOut, code := decompile(c, nil, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t07\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t05\t0\n"+
+		"ld_abs\t10\n"+
+		"jeq_k\t00\t03\t0\n"+
+		"ld_abs\t14\n"+
+		"jeq_k\t00\t01\t0\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(errOut, Equals, "")
+	c.Assert(out, Equals, ""+
+		"DEFAULT_POSITIVE = allow\n"+
+		"DEFAULT_NEGATIVE = kill\n"+
+		"DEFAULT_POLICY = kill\n"+
+		"read: arg0 == 0\n")
+}
+
+func (s *DecompileSuite) Test_decompile_readsOtherFormats(c *C) {
+	out, _, code := decompile(c, []string{"-format", "c"}, ""+
+		"struct sock_filter filter[] = {\n"+
+		"\tBPF_STMT(BPF_RET | BPF_K, SECCOMP_RET_ALLOW),\n"+
+		"};\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, "# on other architectures: allow\nDEFAULT_POSITIVE = allow\nDEFAULT_NEGATIVE = allow\nDEFAULT_POLICY = allow\n")
+}
+
+func (s *DecompileSuite) Test_decompile_warnsWhenThePolicyIsNotExact(c *C) {
+	out, errOut, code := decompile(c, nil, ""+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t1000\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, "DEFAULT_POSITIVE = kill\nDEFAULT_NEGATIVE = kill\nDEFAULT_POLICY = kill\n")
+	c.Assert(errOut, Matches, "gosecco decompile: warning: the policy is not exact - with nr=4096 .* the filter returns allow, but the policy kill\n")
+}
+
+func (s *DecompileSuite) Test_decompile_failsForFiltersItCantDecompile(c *C) {
+	_, errOut, code := decompile(c, nil, "ld_abs\t8\nret_k\t0\n")
+
+	c.Assert(code, Equals, 1)
+	c.Assert(errOut, Matches, "gosecco decompile: .*instruction pointer.*\n")
+}
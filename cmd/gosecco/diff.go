@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/diff"
+
+	"golang.org/x/sys/unix"
+)
+
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	isAsm := fs.Bool("asm", false, "the files are filter programs in the asm format instead of policies")
+	settings := settingsFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco diff [-asm] [-positive <action>] [-negative <action>] [-default <action>] <old> <new>\n")
+		fmt.Fprintf(stderr, "Exits with 1 if the behavior changed, and with 0 if it didn't.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	programs := [2][]unix.SockFilter{}
+	for i, path := range fs.Args() {
+		p, err := loadFilter(path, *isAsm, *settings)
+		if err != nil {
+			fmt.Fprintf(stderr, "gosecco diff: %s\n", err)
+			return 2
+		}
+		programs[i] = p
+	}
+
+	changes, err := diff.Compare(programs[0], programs[1])
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco diff: %s\n", err)
+		return 2
+	}
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// settingsFlags adds the flags for the actions used when compiling policies
+func settingsFlags(fs *flag.FlagSet) *gosecco.SeccompSettings {
+	s := &gosecco.SeccompSettings{}
+	fs.StringVar(&s.DefaultPositiveAction, "positive", "allow", "the action for rules that match")
+	fs.StringVar(&s.DefaultNegativeAction, "negative", "kill", "the action for rules that don't match")
+	fs.StringVar(&s.DefaultPolicyAction, "default", "kill", "the action for system calls the policy doesn't mention")
+	return s
+}
+
+func loadFilter(path string, isAsm bool, s gosecco.SeccompSettings) ([]unix.SockFilter, error) {
+	if !isAsm {
+		return gosecco.Prepare(path, s)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := asm.ParseStrict(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", path, err)
+	}
+	return result, nil
+}
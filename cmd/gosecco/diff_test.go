@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DiffSuite struct{}
+
+var _ = Suite(&DiffSuite{})
+
+func diffPolicies(c *C, args []string, old, new string) (string, string, int) {
+	dir := c.MkDir()
+	oldPath, newPath := filepath.Join(dir, "old.policy"), filepath.Join(dir, "new.policy")
+	ioutil.WriteFile(oldPath, []byte(old), 0644)
+	ioutil.WriteFile(newPath, []byte(new), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(append(append([]string{"diff"}, args...), oldPath, newPath), strings.NewReader(""), stdout, stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func (s *DiffSuite) Test_diff_describesChanges(c *C) {
+	out, _, code := diffPolicies(c, nil, "read: true\nptrace: true\n", "read: arg0 == 0\nmemfd_create: true\n")
+
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Equals, ""+
+		"new syscall allowed: memfd_create\n"+
+		"ptrace: allow → kill\n"+
+		"read: no longer allowed when (argL0 == 0 && argH0 >= 1) || argL0 >= 1 (now kill)\n")
+}
+
+func (s *DiffSuite) Test_diff_succeedsWithoutChanges(c *C) {
+	out, _, code := diffPolicies(c, []string{"-default", "trap"}, "read: true\n", "read: 1 == 1\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, "")
+}
+
+func (s *DiffSuite) Test_diff_comparesFilterPrograms(c *C) {
+	out, _, code := diffPolicies(c, []string{"-asm"}, "ret_k\t7FFF0000\n", "ret_k\t50001\n")
+
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Equals, "all system calls: allow → EPERM\n")
+}
+
+func (s *DiffSuite) Test_diff_reportsInvalidPolicies(c *C) {
+	_, stderr, code := diffPolicies(c, nil, "read: true\n", "read: arg9 == 0\n")
+
+	c.Assert(code, Equals, 2)
+	c.Assert(strings.HasPrefix(stderr, "gosecco diff: "), Equals, true)
+}
+
+func (s *DiffSuite) Test_diff_reportsInvalidFilterPrograms(c *C) {
+	_, stderr, code := diffPolicies(c, []string{"-asm"}, "ret_k\t7FFF0000\n", "ret_k\tallow\nret_l\t0\n")
+
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr, Matches, "gosecco diff: .*new.policy:2:1: unknown instruction 'ret_l'\n")
+}
@@ -0,0 +1,62 @@
+// Command gosecco contains tools for working with seccomp policies and the filters generated from them.
+//
+// Usage:
+//
+//	gosecco <command> [arguments]
+//
+// Run "gosecco help" to see the available commands.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"cost", "report how many instructions a policy executes per system call", runCost},
+	{"coverage", "show which rules and comparisons of a policy a set of inputs exercises", runCoverage},
+	{"debug", "step through a filter program in the asm format, like bpf_dbg", runDebug},
+	{"decompile", "print a policy that behaves like a filter program", runDecompile},
+	{"diff", "describe how the behavior of a policy changed, per system call", runDiff},
+	{"run", "execute a command with a policy installed", runRun},
+	{"test", "check the expectations in a test file against a policy", runTest},
+	{"vectors", "generate test vectors at the boundaries of the comparisons in a policy", runVectors},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gosecco <command> [arguments]\n\nThe commands are:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun \"gosecco <command> -h\" for the arguments of a command.\n")
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "gosecco: unknown command '%s'\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
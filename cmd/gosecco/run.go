@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/asm"
+
+	"golang.org/x/sys/unix"
+)
+
+// execUnder installs the filter and replaces the process with the command, which only returns on errors
+func execUnder(program []unix.SockFilter, path string, args []string) error {
+	runtime.LockOSThread()
+	if err := gosecco.CheckSupport(); err != nil {
+		return err
+	}
+	if err := gosecco.Install(program); err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+var printFormats = map[string]func([]unix.SockFilter) string{
+	"asm":       asm.Dump,
+	"annotated": asm.DumpAnnotated,
+	"tcpdump":   asm.DumpTcpdump,
+	"c":         func(ss []unix.SockFilter) string { return asm.DumpC(ss, "filter") },
+	"go":        func(ss []unix.SockFilter) string { return asm.DumpGo(ss, "filter") },
+}
+
+func runRun(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settings := settingsFlags(fs)
+	fs.StringVar(&settings.ActionOnX32, "x32", "kill", "the action for x32 system calls, or empty to not check for them")
+	fs.StringVar(&settings.ActionOnAuditFailure, "other-arch", "kill", "the action for system calls from other architectures")
+	dryRun := fs.Bool("dry-run", false, "compile the policy, but don't run the command")
+	printProgram := fs.Bool("print", false, "print the compiled program, to stderr if the command is run")
+	format := fs.String("format", "asm", "the format to print the program in: asm, annotated, tcpdump, c or go")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco run [-dry-run] [-print] [-format <format>] [-positive <action>] [-negative <action>] [-default <action>] [-x32 <action>] [-other-arch <action>] <policy> [--] <command> [arguments]\n")
+		fmt.Fprintf(stderr, "Sets no_new_privs, installs the compiled policy on all threads and executes the command.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dump, ok := printFormats[*format]
+	rest := fs.Args()
+	if len(rest) > 1 && rest[1] == "--" {
+		rest = append(rest[:1:1], rest[2:]...)
+	}
+	if !ok || len(rest) < 1 || len(rest) < 2 && !*dryRun {
+		fs.Usage()
+		return 2
+	}
+
+	program, err := gosecco.Prepare(rest[0], *settings)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco run: %s\n", err)
+		return 2
+	}
+	if *dryRun {
+		if *printProgram {
+			fmt.Fprint(stdout, dump(program))
+		}
+		return 0
+	}
+	if *printProgram {
+		fmt.Fprint(stderr, dump(program))
+	}
+
+	path, err := exec.LookPath(rest[1])
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco run: %s\n", err)
+		return 127
+	}
+	err = execUnder(program, path, rest[1:])
+	fmt.Fprintf(stderr, "gosecco run: %s\n", err)
+	return 126
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// runEnv holds the arguments for gosecco when the test binary is started to run a command under a policy,
+// since that replaces the process
+const runEnv = "GOSECCO_RUN_ARGS"
+
+func TestMain(m *testing.M) {
+	if args := os.Getenv(runEnv); args != "" {
+		os.Exit(run(strings.Split(args, "\n"), os.Stdin, os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+type RunSuite struct{}
+
+var _ = Suite(&RunSuite{})
+
+func writePolicy(c *C, policy string) string {
+	path := filepath.Join(c.MkDir(), "x.policy")
+	ioutil.WriteFile(path, []byte(policy), 0644)
+	return path
+}
+
+// runInChild runs gosecco in a new process, and returns its output and exit code
+func runInChild(c *C, args ...string) (string, string, int) {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), runEnv+"="+strings.Join(append([]string{"run"}, args...), "\n"))
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), exit.Sys().(syscall.WaitStatus).ExitStatus()
+	}
+	c.Assert(err, IsNil)
+	return stdout.String(), stderr.String(), 0
+}
+
+func (s *RunSuite) Test_run_printsTheProgramInADryRun(c *C) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"run", "-dry-run", "-print", "-x32", "", writePolicy(c, "read: 1\n")}, strings.NewReader(""), stdout, stderr)
+
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout.String(), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t03\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t0\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")
+}
+
+func (s *RunSuite) Test_run_requiresACommand(c *C) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"run", writePolicy(c, "read: 1\n")}, strings.NewReader(""), stdout, stderr)
+
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr.String(), Matches, "Usage: gosecco run (.|\n)*")
+}
+
+func (s *RunSuite) Test_run_reportsCommandsThatDontExist(c *C) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"run", "-default", "allow", writePolicy(c, "read: 1\n"), "--", "/does/not/exist"}, strings.NewReader(""), stdout, stderr)
+
+	c.Assert(code, Equals, 127)
+	c.Assert(stderr.String(), Matches, "gosecco run: .*/does/not/exist.*\n")
+}
+
+func (s *RunSuite) Test_run_executesTheCommandUnderThePolicy(c *C) {
+	policy := writePolicy(c, "mkdir[+EPERM]: 1\nmkdirat[+EPERM]: 1\n")
+	dir := c.MkDir()
+
+	out, _, code := runInChild(c, "-default", "allow", policy, "--", "sh", "-c", "echo started; mkdir "+dir+"/new || exit 3")
+
+	c.Assert(out, Equals, "started\n")
+	c.Assert(code, Equals, 3)
+	_, err := os.Stat(filepath.Join(dir, "new"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
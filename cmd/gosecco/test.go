@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/twtiger/gosecco/policytest"
+)
+
+func runTest(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settings := settingsFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco test [-positive <action>] [-negative <action>] [-default <action>] <policy> [tests]\n")
+		fmt.Fprintf(stderr, "The tests are read from <policy>.test if no file is given. Exits with 1 if any expectation fails.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	policy, tests := fs.Arg(0), fs.Arg(0)+".test"
+	if fs.NArg() == 2 {
+		tests = fs.Arg(1)
+	}
+	failures, err := policytest.CheckFile(policy, tests, *settings)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco test: %s\n", err)
+		return 2
+	}
+	for _, f := range failures {
+		fmt.Fprintln(stdout, f)
+	}
+	if len(failures) > 0 {
+		return 1
+	}
+	return 0
+}
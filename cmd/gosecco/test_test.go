@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+func testPolicy(c *C, tests string) (string, string, int) {
+	dir := c.MkDir()
+	path := filepath.Join(dir, "x.policy")
+	ioutil.WriteFile(path, []byte("read: arg0 == 0\n"), 0644)
+	ioutil.WriteFile(path+".test", []byte(tests), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"test", path}, strings.NewReader(""), stdout, stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func (s *TestSuite) Test_test_passes(c *C) {
+	out, _, code := testPolicy(c, "expect read(0) => allow\nexpect write() => kill\n")
+
+	c.Assert(code, Equals, 0)
+	c.Assert(out, Equals, "")
+}
+
+func (s *TestSuite) Test_test_listsFailures(c *C) {
+	out, _, code := testPolicy(c, "expect read(*) => allow\n")
+
+	c.Assert(code, Equals, 1)
+	c.Assert(out, Matches, ".*x.policy.test:1: expect read\\(\\*\\) => allow, but got kill for nr=read arch=0xC000003E arg0=0x1\n")
+}
+
+func (s *TestSuite) Test_test_reportsInvalidTests(c *C) {
+	_, stderr, code := testPolicy(c, "expect read(*) => alow\n")
+
+	c.Assert(code, Equals, 2)
+	c.Assert(stderr, Matches, "gosecco test: .*x.policy.test:1: invalid action 'alow'.*\n")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/vectors"
+)
+
+func runVectors(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("vectors", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	settings := settingsFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gosecco vectors [-positive <action>] [-negative <action>] [-default <action>] <policy>\n")
+		fmt.Fprintf(stderr, "Writes test vectors at the boundaries of the comparisons in the policy, in the format \"gosecco test\" reads.\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	p, err := gosecco.PreparePolicy(&parser.FileSource{fs.Arg(0)}, *settings)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco vectors: %s\n", err)
+		return 1
+	}
+	vs, err := vectors.Generate(p)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosecco vectors: %s\n", err)
+		return 1
+	}
+	for _, v := range vs {
+		fmt.Fprintln(stdout, v.Expectation())
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type VectorsSuite struct{}
+
+var _ = Suite(&VectorsSuite{})
+
+func (s *VectorsSuite) Test_vectors_writesATestFileThePolicyPasses(c *C) {
+	dir := c.MkDir()
+	policy := filepath.Join(dir, "x.policy")
+	ioutil.WriteFile(policy, []byte("read: argL0 == 0\nwrite: in(arg1, 1, 2)\n"), 0644)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"vectors", policy}, strings.NewReader(""), stdout, stderr)
+	c.Assert(code, Equals, 0)
+	c.Assert(strings.SplitN(stdout.String(), "\n", 3)[:2], DeepEquals, []string{
+		"expect read(0, 0, 0, 0, 0, 0) => allow # read: all arguments zero",
+		"expect read(1, 0, 0, 0, 0, 0) => kill # read: argL0 == 0 (one more)",
+	})
+
+	ioutil.WriteFile(policy+".test", stdout.Bytes(), 0644)
+	stdout.Reset()
+	code = run([]string{"test", policy}, strings.NewReader(""), stdout, stderr)
+	c.Assert(code, Equals, 0)
+	c.Assert(stdout.String(), Equals, "")
+}
@@ -9,15 +9,19 @@ import (
 // This is part of the boolean compiler visitor, but needs its own file because of some of the complications involved
 
 var compOps = map[tree.ComparisonType]uint16{
-	tree.EQL:  OP_JEQ_X,
-	tree.NEQL: OP_JEQ_X,
-	tree.GT:   OP_JGT_X,
-	tree.GTE:  OP_JGE_X,
+	tree.EQL:    OP_JEQ_X,
+	tree.NEQL:   OP_JEQ_X,
+	tree.GT:     OP_JGT_X,
+	tree.GTE:    OP_JGE_X,
+	tree.BITSET: OP_JSET_X,
 }
 
 // AcceptComparison implements Visitor
 func (s *booleanCompilerVisitor) AcceptComparison(v tree.Comparison) {
-	// At this point in the cycle, only EQL, NEQL, GT and GTE are valid comparisons
+	// At this point in the cycle, only EQL, NEQL, GT, GTE and BITSET are valid comparisons
+	s.ctx.origin.Comparison = v
+	defer func() { s.ctx.origin.Comparison = nil }()
+
 	if err := compileNumeric(s.ctx, v.Right); err != nil {
 		s.err = err
 		return
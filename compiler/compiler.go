@@ -22,6 +22,16 @@ func Compile(policy tree.Policy) ([]unix.SockFilter, error) {
 	return c.compile(policy)
 }
 
+// CompileWithSourceMap works the same as Compile, but also returns the origin of every instruction in the result
+func CompileWithSourceMap(policy tree.Policy) ([]unix.SockFilter, []Origin, error) {
+	c := createCompilerContext()
+	res, err := c.compile(policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, c.origins, nil
+}
+
 type label string
 
 type compilerContext struct {
@@ -38,6 +48,8 @@ type compilerContext struct {
 	maxJumpSize                                     int // this will always be 0xFF in production, but can be injected for testing.
 	currentlyCompilingSyscall                       string
 	currentlyCompilingExpression                    tree.Expression
+	origin                                          Origin   // the origin of the instructions generated right now
+	origins                                         []Origin // the origin of each instruction in result
 }
 
 func createCompilerContext() *compilerContext {
@@ -89,6 +101,7 @@ func (c *compilerContext) sortedActions() []string {
 
 func (c *compilerContext) compile(policy tree.Policy) ([]unix.SockFilter, error) {
 	c.setDefaults(policy.DefaultPositiveAction, policy.DefaultNegativeAction, policy.DefaultPolicyAction)
+	c.origin = Origin{Part: ArchitectureCheck}
 	c.compileAuditArchCheck(policy.ActionOnAuditFailure)
 	c.compileX32ABICheck(policy.ActionOnX32)
 
@@ -98,9 +111,11 @@ func (c *compilerContext) compile(policy tree.Policy) ([]unix.SockFilter, error)
 		}
 	}
 
+	c.origin = Origin{Part: DefaultAction, Action: c.defaultPolicy}
 	c.unconditionalJumpTo(c.getOrCreateAction(c.defaultPolicy))
 
 	for _, k := range c.sortedActions() {
+		c.origin = Origin{Part: ReturnAction, Action: k}
 		c.labelHere(c.actions[k])
 		action, err := actionDescriptionToK(k)
 		if err != nil {
@@ -151,7 +166,9 @@ func (c *compilerContext) compileRule(r *tree.Rule) error {
 
 	pos, neg := c.compileActions(r.PositiveAction, r.NegativeAction)
 
+	c.origin = Origin{Part: SyscallCheck, Rule: r}
 	c.checkCorrectSyscall(r.Name, next)
+	c.origin.Part = RuleBody
 
 	// These are useful for debugging and helpful error messages
 	c.currentlyCompilingSyscall = r.Name
@@ -187,6 +204,7 @@ func (c *compilerContext) op(code uint16, k uint32) {
 		Jf:   0,
 		K:    k,
 	})
+	c.origins = append(c.origins, c.origin)
 }
 
 func (c *compilerContext) compileExpression(x tree.Expression, pos, neg label) error {
@@ -216,6 +234,7 @@ func (c *compilerContext) unconditionalJumpTo(to label) {
 		Jf:   0,
 		K:    0,
 	})
+	c.origins = append(c.origins, c.origin)
 	c.uconds.registerJump(to, index)
 }
 
@@ -228,6 +247,7 @@ func (c *compilerContext) opWithJumps(code uint16, k uint32, jt, jf label) {
 		Jf:   0,
 		K:    k,
 	})
+	c.origins = append(c.origins, c.origin)
 }
 
 func (c *compilerContext) jumpOnEq(val uint32, jt, jf label) {
@@ -256,3 +276,12 @@ func (c *compilerContext) popStackToX() error {
 	c.op(OP_LOAD_MEM_X, c.stackTop)
 	return nil
 }
+
+func (c *compilerContext) popStackToA() error {
+	if c.stackTop == 0 {
+		return errors.New("popping from empty stack - this is likely a programmer error")
+	}
+	c.stackTop--
+	c.op(OP_LOAD_MEM, c.stackTop)
+	return nil
+}
@@ -33,6 +33,28 @@ func (s *CompilerSuite) Test_simplestCompilation(c *C) {
 		"ret_k	0\n")
 }
 
+func (s *CompilerSuite) Test_compilationOfAlwaysFalseRule(c *C) {
+	p := tree.Policy{
+		DefaultPositiveAction: "allow", DefaultNegativeAction: "trace", DefaultPolicyAction: "kill",
+		Rules: []*tree.Rule{
+			&tree.Rule{
+				Name: "write",
+				Body: tree.BooleanLiteral{false},
+			},
+		},
+	}
+
+	res, _ := Compile(p)
+	c.Assert(asm.Dump(res), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t03\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jeq_k\t02\t01\t1\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n"+
+		"ret_k\t7FF00000\n")
+}
+
 func (s *CompilerSuite) Test_nextSimplestCompilation(c *C) {
 	p := tree.Policy{
 		DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill",
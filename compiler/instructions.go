@@ -35,7 +35,10 @@ const OP_JSET_K = syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_K
 const OP_JEQ_X = syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_X
 const OP_JGT_X = syscall.BPF_JMP | syscall.BPF_JGT | syscall.BPF_X
 const OP_JGE_X = syscall.BPF_JMP | syscall.BPF_JGE | syscall.BPF_X
+const OP_JSET_X = syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_X
 
 const OP_JMP_K = syscall.BPF_JMP | syscall.BPF_JA
 
+const OP_TAX = syscall.BPF_MISC | syscall.BPF_TAX
+
 const OP_RET_K = syscall.BPF_RET | syscall.BPF_K
@@ -112,6 +112,7 @@ func (c *longJumpContext) handleJTLongJumpAt(currentIndex int) bool {
 		}
 
 		c.insertJumps(currentIndex, jmpLen, 0)
+		c.result[currentIndex].Jt = 0
 
 		return true
 	}
@@ -123,6 +124,7 @@ func (c *longJumpContext) handleJFLongJumpAt(currentIndex int, hadJt bool) {
 		jmpLen = fixupWithShifts(currentIndex, jmpLen, c.shifts)
 		incr, jmpLen := c.incrementJt(hadJt, jmpLen, currentIndex)
 		c.insertJumps(currentIndex, jmpLen, incr)
+		c.result[currentIndex].Jf = uint8(incr)
 	}
 }
 
@@ -137,6 +139,7 @@ func (c *longJumpContext) incrementJt(hadJt bool, jmpLen, currentIndex int) (int
 		if c.isLongJump(newJt) {
 			// incr in this case doesn't seem to do much, all tests pass when it is changed to 0
 			c.insertJumps(currentIndex, newJt, incr)
+			c.result[currentIndex].Jt = 0
 			incr++
 		} else {
 			c.result[currentIndex].Jt = uint8(newJt)
@@ -150,6 +153,7 @@ func (c *longJumpContext) shiftJf(hadJt bool, currentIndex int) {
 	if c.isLongJump(newJf) {
 		incr, _ := c.incrementJt(hadJt, 0, currentIndex)
 		c.insertJumps(currentIndex, newJf, incr)
+		c.result[currentIndex].Jf = uint8(incr)
 	} else {
 		c.result[currentIndex].Jf = uint8(newJf)
 	}
@@ -160,15 +164,17 @@ func (c *longJumpContext) shiftJt(currentIndex int) bool {
 	if c.isLongJump(newJt) {
 		// Jf doesn't need to be modified here, because it will be fixed up with the shifts. Hopefully correctly...
 		c.insertJumps(currentIndex, newJt, 0)
+		c.result[currentIndex].Jt = 0
 		return true
 	}
 	c.result[currentIndex].Jt = uint8(newJt)
 	return false
 }
 
+// insertJumps adds an unconditional jump incr instructions after the conditional jump at currentIndex.
+// The caller is responsible for pointing the conditional jump at it
 func (c *longJumpContext) insertJumps(currentIndex, pos, incr int) {
 	c.insertUnconditionalJump(currentIndex+1+incr, pos)
-	c.result[currentIndex].Jf = uint8(incr)
 	c.shifts = append(c.shifts, shift(currentIndex+1+incr))
 }
 
@@ -181,6 +187,8 @@ func insertSockFilter(sfs []unix.SockFilter, ix int, x unix.SockFilter) []unix.S
 func (c *compilerContext) insertUnconditionalJump(from, k int) {
 	x := unix.SockFilter{Code: OP_JMP_K, K: uint32(k)}
 	c.result = insertSockFilter(c.result, from, x)
+	// The jump belongs to the instruction that needed it, which is either right before it or another inserted jump
+	c.origins = append(append(append([]Origin{}, c.origins[:from]...), c.origins[from-1]), c.origins[from:]...)
 }
 
 func (c *compilerContext) shiftJumpsBy(from, incr int) {
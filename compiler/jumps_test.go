@@ -182,3 +182,36 @@ func (s *JumpsSuite) Test_maxSizeJumpSetsWithNotEqualWithMoreThanOneRule(c *C) {
 		"ret_k	7FFF0000\n"+
 		"ret_k	0\n")
 }
+
+func (s *JumpsSuite) Test_longTrueJumpKeepsTheFalseJump(c *C) {
+	ctx := createCompilerContext()
+	ctx.maxJumpSize = 2
+
+	p := tree.Policy{
+		DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", ActionOnX32: "trap",
+		Rules: []*tree.Rule{
+			&tree.Rule{
+				Name: "write",
+				Body: tree.BooleanLiteral{true},
+			},
+			&tree.Rule{
+				Name: "read",
+				Body: tree.BooleanLiteral{true},
+			},
+		},
+	}
+
+	res, _ := ctx.compile(p)
+	c.Assert(asm.Dump(res), Equals, ""+
+		"ld_abs	4\n"+
+		"jeq_k	01	00	C000003E\n"+
+		"jmp	6\n"+
+		"ld_abs	0\n"+
+		"jset_k	00	01	40000000\n"+
+		"jmp	4\n"+
+		"jeq_k	01	00	1\n"+
+		"jeq_k	00	01	0\n"+
+		"ret_k	7FFF0000\n"+
+		"ret_k	0\n"+
+		"ret_k	30000\n")
+}
@@ -102,6 +102,11 @@ func (s *numericCompilerVisitor) AcceptArithmetic(v tree.Arithmetic) {
 		return
 	}
 
+	if v.Op == tree.MOD {
+		s.err = s.compileModulo(do, val)
+		return
+	}
+
 	if do {
 		arithOp = specialCasedOp(arithOp)
 	}
@@ -109,6 +114,27 @@ func (s *numericCompilerVisitor) AcceptArithmetic(v tree.Arithmetic) {
 	s.ctx.op(arithOp, val)
 }
 
+// compileModulo calculates A % right as A - (A / right) * right, since the kernel doesn't allow BPF_MOD in seccomp filters.
+// The right hand side is either in X or the given constant
+func (s *numericCompilerVisitor) compileModulo(do bool, val uint32) error {
+	div, mul := uint16(OP_DIV_X), uint16(OP_MUL_X)
+	if do {
+		div, mul = specialCasedOp(div), specialCasedOp(mul)
+	}
+
+	if err := s.ctx.pushAToStack(); err != nil {
+		return err
+	}
+	s.ctx.op(div, val)
+	s.ctx.op(mul, val)
+	s.ctx.op(OP_TAX, 0)
+	if err := s.ctx.popStackToA(); err != nil {
+		return err
+	}
+	s.ctx.op(OP_SUB_X, 0)
+	return nil
+}
+
 // AcceptBinaryNegation implements Visitor
 func (s *numericCompilerVisitor) AcceptBinaryNegation(v tree.BinaryNegation) {
 	s.err = errors.New("a binary negation was found in an expression - this is likely a programmer error")
@@ -81,6 +81,20 @@ func (s *NumericCompilerSuite) Test_moreComplicatedExpression(c *C) {
 	)
 }
 
+func (s *NumericCompilerSuite) Test_moduloIsCalculatedWithoutTheModInstruction(c *C) {
+	ctx := createCompilerContext()
+	err := compileNumeric(ctx, tree.Arithmetic{Op: tree.MOD, Left: tree.Argument{Type: tree.Low, Index: 0}, Right: tree.NumericLiteral{3}})
+	c.Assert(err, IsNil)
+	c.Assert(asm.Dump(ctx.result), Equals, ""+
+		"ld_abs\t10\n"+
+		"st\t0\n"+
+		"div_k\t3\n"+
+		"mul_k\t3\n"+
+		"tax\n"+
+		"ld_mem\t0\n"+
+		"sub_x\n")
+}
+
 func (s *NumericCompilerSuite) Test_thatAnErrorIsSetWhenWeCompileInvalidExpression(c *C) {
 	ctx := createCompilerContext()
 	ctx.stackTop = syscall.BPF_MEMWORDS
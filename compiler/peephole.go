@@ -125,6 +125,7 @@ func redirectJumpOf(c *compilerContext, ucond, cond int) {
 
 func (c *compilerContext) removeInstructionAt(index int) {
 	c.result = append(c.result[:index], c.result[index+1:]...)
+	c.origins = append(c.origins[:index], c.origins[index+1:]...)
 }
 
 // jumpAfterConditionalJumpOptimizer will optimize situations where a JMP instruction
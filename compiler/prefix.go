@@ -26,6 +26,6 @@ func (c *compilerContext) compileX32ABICheck(on string) {
 	correct := c.newLabel()
 
 	c.loadAt(syscallNameIndex)
-	c.jumpIfBitSet(native.X32SyscallBit, correct, failure)
+	c.jumpIfBitSet(native.X32SyscallBit, failure, correct)
 	c.labelHere(correct)
 }
@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/tree"
 	. "gopkg.in/check.v1"
 )
 
@@ -24,3 +25,14 @@ func (s *PrefixSuite) Test_compiles32ABICheck(c *C) {
 		"ld_abs\t0\n"+
 		"jset_k\t00\t00\t40000000\n")
 }
+
+func (s *PrefixSuite) Test_x32SystemCallsTakeTheX32Action(c *C) {
+	res, _ := Compile(tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", ActionOnX32: "trap"})
+	c.Assert(asm.Dump(res), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t02\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jset_k\t01\t00\t40000000\n"+
+		"ret_k\t0\n"+
+		"ret_k\t30000\n")
+}
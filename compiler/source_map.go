@@ -0,0 +1,32 @@
+package compiler
+
+import "github.com/twtiger/gosecco/tree"
+
+// Part is the part of a policy an instruction was compiled from
+type Part int
+
+// The parts of a policy that generate instructions
+const (
+	// ArchitectureCheck is the check of the architecture and of x32 system calls before the rules
+	ArchitectureCheck Part = iota
+	// SyscallCheck is the comparison of the system call number for a rule
+	SyscallCheck
+	// RuleBody is the evaluation of the expression of a rule
+	RuleBody
+	// DefaultAction is the jump to the default policy action, for system calls no rule matched
+	DefaultAction
+	// ReturnAction is the return of one of the actions
+	ReturnAction
+)
+
+// Origin describes where an instruction in a compiled filter came from
+type Origin struct {
+	Part Part
+	// Rule is the rule the instruction was compiled for, for the SyscallCheck and RuleBody parts
+	Rule *tree.Rule
+	// Comparison is the comparison in the rule body the instruction was compiled for. It is nil for the
+	// jumps that literal true or false bodies compile to
+	Comparison tree.Expression
+	// Action is the description of the action for the DefaultAction and ReturnAction parts
+	Action string
+}
@@ -0,0 +1,66 @@
+package compiler
+
+import (
+	"github.com/twtiger/gosecco/tree"
+	. "gopkg.in/check.v1"
+)
+
+type SourceMapSuite struct{}
+
+var _ = Suite(&SourceMapSuite{})
+
+func parts(origins []Origin) []Part {
+	result := []Part{}
+	for _, o := range origins {
+		result = append(result, o.Part)
+	}
+	return result
+}
+
+func (s *SourceMapSuite) Test_recordsTheOriginOfEveryInstruction(c *C) {
+	comparison := tree.Comparison{Op: tree.EQL, Left: tree.Argument{Index: 0, Type: tree.Low}, Right: tree.NumericLiteral{42}}
+	write := &tree.Rule{Name: "write", Body: comparison}
+	p := tree.Policy{
+		DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill",
+		Rules: []*tree.Rule{write},
+	}
+
+	res, origins, err := CompileWithSourceMap(p)
+	c.Assert(err, IsNil)
+	c.Assert(origins, HasLen, len(res))
+	c.Assert(parts(origins), DeepEquals, []Part{
+		ArchitectureCheck, ArchitectureCheck,
+		SyscallCheck, SyscallCheck,
+		RuleBody, RuleBody,
+		DefaultAction,
+		ReturnAction, ReturnAction,
+	})
+	c.Assert(origins[3].Rule, Equals, write)
+	c.Assert(origins[5].Comparison, DeepEquals, comparison)
+	c.Assert(origins[6].Action, Equals, "kill")
+	c.Assert(origins[7].Action, Equals, "allow")
+	c.Assert(origins[8].Action, Equals, "kill")
+}
+
+func (s *SourceMapSuite) Test_keepsTheOriginsWhenLongJumpsAreInserted(c *C) {
+	ctx := createCompilerContext()
+	ctx.maxJumpSize = 2
+
+	write := &tree.Rule{Name: "write", Body: tree.BooleanLiteral{true}}
+	read := &tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}}
+	p := tree.Policy{
+		DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", ActionOnX32: "trap",
+		Rules: []*tree.Rule{write, read},
+	}
+
+	res, _ := ctx.compile(p)
+	c.Assert(ctx.origins, HasLen, len(res))
+	c.Assert(parts(ctx.origins), DeepEquals, []Part{
+		ArchitectureCheck, ArchitectureCheck, ArchitectureCheck,
+		ArchitectureCheck, ArchitectureCheck, ArchitectureCheck,
+		SyscallCheck, SyscallCheck,
+		ReturnAction, ReturnAction, ReturnAction,
+	})
+	c.Assert(ctx.origins[6].Rule, Equals, write)
+	c.Assert(ctx.origins[7].Rule, Equals, read)
+}
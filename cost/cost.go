@@ -0,0 +1,190 @@
+// Package cost analyses how many instructions a compiled filter executes before it returns. All paths through
+// the control flow of the program are followed, deciding comparisons of the system call number and the architecture,
+// and taking both branches of any other comparison. Since no path is left out, the maximum is an upper bound for
+// every possible input, even if some of the paths can never be taken. The report contains the cost of every system
+// call, the most expensive path, the length of the program against the kernel limit, and how much of the cost
+// each rule is responsible for, if the origins of the instructions are known.
+package cost
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+)
+
+// MaxInstructions is the longest program the kernel accepts, BPF_MAXINSNS
+const MaxInstructions = 4096
+
+// Syscall is the cost of the paths for one system call, or for a group of them that can't be told apart,
+// such as the ones no rule mentions. The average is over the paths, not over all possible inputs
+type Syscall struct {
+	Name     string
+	Min, Max int
+	Average  float64
+	Paths    int
+}
+
+// Path is one path through the program
+type Path struct {
+	Syscall string
+	// Memory is the smallest working memory with the system call and architecture of the path. The arguments
+	// are left at zero, since the comparisons on them are not used to decide which paths are possible
+	Memory data.SeccompWorkingMemory
+	// Instructions are the indexes of the instructions executed, in order
+	Instructions []uint32
+	Result       uint32
+}
+
+// Rule is the number of instructions one rule contributes, summed over all paths
+type Rule struct {
+	Rule         *tree.Rule
+	Instructions int
+	// Share is the part of all instructions executed on all paths that belongs to the rule, between 0 and 1
+	Share float64
+}
+
+// Report is the result of analysing a program
+type Report struct {
+	Length   int
+	Syscalls []Syscall
+	Worst    Path
+	// Rules is sorted with the most expensive rule first. It is empty if the origins of the instructions weren't given
+	Rules []Rule
+}
+
+// Analyse finds the cost of all paths through the program. The origins are optional, and are used to attribute
+// the cost to the rules. The program has to be accepted by the kernel
+func Analyse(program []unix.SockFilter, origins []compiler.Origin) (*Report, error) {
+	if origins != nil && len(origins) != len(program) {
+		return nil, fmt.Errorf("expected the origins of %d instructions, but got %d", len(program), len(origins))
+	}
+	if err := emulator.Verify(program); err != nil {
+		return nil, err
+	}
+	paths, err := controlFlowPaths(program)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Length: len(program)}
+	bySyscall := map[string]*Syscall{}
+	names := []string{}
+	total := 0
+	perRule := map[*tree.Rule]int{}
+	ruleOrder := []*tree.Rule{}
+
+	for _, p := range paths {
+		name := p.syscall()
+		n := len(p.instructions)
+
+		s, ok := bySyscall[name]
+		if !ok {
+			s = &Syscall{Name: name, Min: n, Max: n}
+			bySyscall[name] = s
+			names = append(names, name)
+		}
+		s.Min, s.Max = min(s.Min, n), max(s.Max, n)
+		s.Average += float64(n)
+		s.Paths++
+
+		if n > len(r.Worst.Instructions) {
+			r.Worst = Path{Syscall: name, Memory: p.memory(), Result: p.result, Instructions: p.instructions}
+		}
+
+		total += n
+		if origins != nil {
+			for _, pc := range p.instructions {
+				if rule := origins[pc].Rule; rule != nil {
+					if _, seen := perRule[rule]; !seen {
+						ruleOrder = append(ruleOrder, rule)
+					}
+					perRule[rule]++
+				}
+			}
+		}
+	}
+
+	sort.Stable(byName(names))
+	for _, name := range names {
+		s := bySyscall[name]
+		s.Average /= float64(s.Paths)
+		r.Syscalls = append(r.Syscalls, *s)
+	}
+
+	for _, rule := range ruleOrder {
+		r.Rules = append(r.Rules, Rule{Rule: rule, Instructions: perRule[rule], Share: float64(perRule[rule]) / float64(total)})
+	}
+	sort.Stable(byCost(r.Rules))
+	return r, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// byName sorts the names of real system calls first, in alphabetical order
+type byName []string
+
+func (s byName) Len() int      { return len(s) }
+func (s byName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool {
+	_, ni := constants.GetSyscall(s[i])
+	_, nj := constants.GetSyscall(s[j])
+	if ni != nj {
+		return ni
+	}
+	return s[i] < s[j]
+}
+
+type byCost []Rule
+
+func (s byCost) Len() int           { return len(s) }
+func (s byCost) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byCost) Less(i, j int) bool { return s[i].Instructions > s[j].Instructions }
+
+// WriteText writes the report in a form readable by people
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "program length: %d of %d instructions\n", r.Length, MaxInstructions)
+	fmt.Fprintf(w, "worst case: %d instructions for %s, returning %s\n", len(r.Worst.Instructions), r.Worst.Syscall, actions.Describe(r.Worst.Result))
+
+	width := len("system call")
+	for _, s := range r.Syscalls {
+		if len(s.Name) > width {
+			width = len(s.Name)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  %5s  %5s  %7s  %5s\n", width, "system call", "min", "max", "average", "paths")
+	for _, s := range r.Syscalls {
+		fmt.Fprintf(w, "%-*s  %5d  %5d  %7.2f  %5d\n", width, s.Name, s.Min, s.Max, s.Average, s.Paths)
+	}
+
+	if len(r.Rules) > 0 {
+		fmt.Fprintf(w, "cost by rule:\n")
+		for _, rule := range r.Rules {
+			pos := "<unknown>"
+			if rule.Rule.Position.IsKnown() {
+				pos = rule.Rule.Position.String()
+			}
+			fmt.Fprintf(w, "  %s: %s: %d instructions (%.1f%%)\n", pos, rule.Rule.Name, rule.Instructions, 100*rule.Share)
+		}
+	}
+}
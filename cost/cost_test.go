@@ -0,0 +1,107 @@
+package cost
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/parser"
+
+	"golang.org/x/sys/unix"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type CostSuite struct{}
+
+var _ = Suite(&CostSuite{})
+
+const policy = "" +
+	"read: arg0 == 0 || arg0 == 1\n" +
+	"write: true\n" +
+	"close: true\n"
+
+func analyse(c *C, source string) *Report {
+	program, origins, err := gosecco.PrepareSourceWithSourceMap(&parser.StringSource{Name: "x.policy", Content: source},
+		gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill"})
+	c.Assert(err, IsNil)
+	r, err := Analyse(program, origins)
+	c.Assert(err, IsNil)
+	return r
+}
+
+func (s *CostSuite) Test_findsTheCostOfEverySyscall(c *C) {
+	r := analyse(c, policy)
+
+	c.Assert(r.Length, Equals, 16)
+	c.Assert(r.Syscalls, DeepEquals, []Syscall{
+		{Name: "close", Min: 7, Max: 7, Average: 7, Paths: 1},
+		{Name: "read", Min: 9, Max: 11, Average: 10.2, Paths: 5},
+		{Name: "write", Min: 6, Max: 6, Average: 6, Paths: 1},
+		{Name: "other architectures", Min: 3, Max: 3, Average: 3, Paths: 1},
+		{Name: "other system calls", Min: 7, Max: 7, Average: 7, Paths: 1},
+	})
+	c.Assert(r.Worst.Syscall, Equals, "read")
+	c.Assert(r.Worst.Instructions, HasLen, 11)
+	c.Assert(r.Worst.Result, Equals, actions.RetKill)
+}
+
+func (s *CostSuite) Test_attributesTheCostToRules(c *C) {
+	r := analyse(c, policy)
+
+	c.Assert(r.Rules, HasLen, 3)
+	c.Assert(r.Rules[0].Rule.Name, Equals, "read")
+	c.Assert(r.Rules[0].Instructions, Equals, 42)
+	c.Assert(r.Rules[1].Rule.Name, Equals, "write")
+	c.Assert(r.Rules[2].Rule.Name, Equals, "close")
+
+	out := &bytes.Buffer{}
+	r.WriteText(out)
+	c.Assert(out.String(), Equals, ""+
+		"program length: 16 of 4096 instructions\n"+
+		"worst case: 11 instructions for read, returning kill\n"+
+		"system call            min    max  average  paths\n"+
+		"close                    7      7     7.00      1\n"+
+		"read                     9     11    10.20      5\n"+
+		"write                    6      6     6.00      1\n"+
+		"other architectures      3      3     3.00      1\n"+
+		"other system calls       7      7     7.00      1\n"+
+		"cost by rule:\n"+
+		"  x.policy:0: read: 42 instructions (56.8%)\n"+
+		"  x.policy:1: write: 3 instructions (4.1%)\n"+
+		"  x.policy:2: close: 2 instructions (2.7%)\n")
+}
+
+func (s *CostSuite) Test_worksWithoutOrigins(c *C) {
+	program, err := gosecco.PrepareSource(&parser.StringSource{Name: "x.policy", Content: policy},
+		gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill"})
+	c.Assert(err, IsNil)
+
+	r, err := Analyse(program, nil)
+	c.Assert(err, IsNil)
+	c.Assert(r.Rules, HasLen, 0)
+	c.Assert(r.Syscalls, HasLen, 5)
+
+	_, err = Analyse(program, make([]compiler.Origin, 2))
+	c.Assert(err, ErrorMatches, "expected the origins of 16 instructions, but got 2")
+}
+
+func (s *CostSuite) Test_analysesArithmeticAsTwoWayBranches(c *C) {
+	r := analyse(c, "read: argL0 % 3 == 1\n")
+
+	c.Assert(r.Syscalls, DeepEquals, []Syscall{
+		{Name: "read", Min: 16, Max: 16, Average: 16, Paths: 2},
+		{Name: "other architectures", Min: 3, Max: 3, Average: 3, Paths: 1},
+		{Name: "other system calls", Min: 6, Max: 6, Average: 6, Paths: 1},
+	})
+}
+
+func (s *CostSuite) Test_rejectsProgramsTheKernelDoesNotAccept(c *C) {
+	_, err := Analyse([]unix.SockFilter{{Code: syscall.BPF_LD | syscall.BPF_MEM, K: 20}, {Code: syscall.BPF_RET | syscall.BPF_K}}, nil)
+	c.Assert(err, ErrorMatches, "instruction 0: scratch memory index 20 out of range \\(limit = 16\\)")
+}
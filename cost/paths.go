@@ -0,0 +1,289 @@
+package cost
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/native"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxPaths stops the analysis of programs where the number of paths explodes
+const maxPaths = 100000
+
+const (
+	syscallNameIndex        = 0
+	archIndex               = 4
+	instructionPointerIndex = 8
+	argumentIndex           = 16
+)
+
+type valueKind int
+
+const (
+	unknown valueKind = iota
+	word
+	constant
+)
+
+// value is what is known about a register or a scratch memory cell: either that it contains a word
+// of the working memory, a constant, or something calculated at runtime
+type value struct {
+	kind valueKind
+	k    uint32
+}
+
+// knowledge is what the comparisons on a path have decided about a word of the working memory
+type knowledge struct {
+	known      bool
+	value      uint32
+	excluded   []uint32
+	set, clear uint32
+}
+
+func (k knowledge) unrestricted() bool {
+	return !k.known && len(k.excluded) == 0 && k.set == 0 && k.clear == 0
+}
+
+func (k knowledge) contains(v uint32) bool {
+	if k.known {
+		return k.value == v
+	}
+	if v&k.set != k.set || v&k.clear != 0 {
+		return false
+	}
+	for _, e := range k.excluded {
+		if e == v {
+			return false
+		}
+	}
+	return true
+}
+
+// smallest returns the smallest value that agrees with the knowledge
+func (k knowledge) smallest() uint32 {
+	for v := uint32(0); ; v++ {
+		if k.contains(v | k.set) {
+			return v | k.set
+		}
+	}
+}
+
+// decide returns the outcome of comparing the word against the constant, if the path has already decided it
+func (k knowledge) decide(op uint16, v uint32) (outcome bool, decided bool) {
+	switch {
+	case k.known:
+		return compare(op, k.value, v), true
+	case op == syscall.BPF_JEQ && !k.contains(v):
+		return false, true
+	case op == syscall.BPF_JSET && k.set&v != 0:
+		return true, true
+	case op == syscall.BPF_JSET && v&^k.clear == 0:
+		return false, true
+	}
+	return false, false
+}
+
+// assume returns the knowledge after taking one branch of a comparison against the constant
+func (k knowledge) assume(op uint16, v uint32, outcome bool) knowledge {
+	switch {
+	case op == syscall.BPF_JEQ && outcome:
+		return knowledge{known: true, value: v}
+	case op == syscall.BPF_JEQ:
+		k.excluded = append(k.excluded[:len(k.excluded):len(k.excluded)], v)
+	case op == syscall.BPF_JSET && !outcome:
+		k.clear |= v
+	case op == syscall.BPF_JSET && v&(v-1) == 0:
+		k.set |= v
+	}
+	return k
+}
+
+func compare(op uint16, l, r uint32) bool {
+	switch op {
+	case syscall.BPF_JEQ:
+		return l == r
+	case syscall.BPF_JGT:
+		return l > r
+	case syscall.BPF_JGE:
+		return l >= r
+	}
+	return l&r != 0
+}
+
+type state struct {
+	a, x  value
+	mem   [syscall.BPF_MEMWORDS]value
+	words map[uint32]knowledge
+}
+
+// flowPath is one way through the control flow of a program
+type flowPath struct {
+	words        map[uint32]knowledge
+	instructions []uint32
+	result       uint32
+}
+
+func (p flowPath) syscall() string {
+	nr, arch := p.words[syscallNameIndex], p.words[archIndex]
+	if nr.unrestricted() && arch.unrestricted() {
+		return "all system calls"
+	}
+	if !arch.contains(native.AuditArch) {
+		return "other architectures"
+	}
+	if nr.known {
+		if name, ok := constants.SyscallNumbers[int(nr.value)]; ok {
+			return name
+		}
+		return fmt.Sprintf("system call %d", nr.value)
+	}
+	if nr.set&native.X32SyscallBit != 0 {
+		return "x32 system calls"
+	}
+	return "other system calls"
+}
+
+func (p flowPath) memory() data.SeccompWorkingMemory {
+	d := data.SeccompWorkingMemory{
+		NR:                 int32(p.words[syscallNameIndex].smallest()),
+		Arch:               p.words[archIndex].smallest(),
+		InstructionPointer: uint64(p.words[instructionPointerIndex].smallest()) | uint64(p.words[instructionPointerIndex+4].smallest())<<32,
+	}
+	for i := range d.Args {
+		low := argumentIndex + uint32(i)*8
+		d.Args[i] = uint64(p.words[low].smallest()) | uint64(p.words[low+4].smallest())<<32
+	}
+	return d
+}
+
+// flowWalker follows every path through the control flow of a program. Comparisons of words loaded from the
+// working memory against constants decide which paths are possible, as far as that can be done with equality
+// and bit tests. Any other comparison is opaque, and both of its branches are followed
+type flowWalker struct {
+	program []unix.SockFilter
+	paths   []flowPath
+}
+
+func controlFlowPaths(program []unix.SockFilter) ([]flowPath, error) {
+	w := &flowWalker{program: program}
+	if err := w.walk(0, state{}, nil); err != nil {
+		return nil, err
+	}
+	return w.paths, nil
+}
+
+func (w *flowWalker) walk(pc int, s state, taken []uint32) error {
+	for {
+		if pc >= len(w.program) {
+			return fmt.Errorf("instruction %d: the program continues past its last instruction", pc)
+		}
+		taken = append(taken, uint32(pc))
+
+		current := w.program[pc]
+		k := value{kind: constant, k: current.K}
+		switch current.Code & 0x07 {
+		case syscall.BPF_RET:
+			result := current.K
+			if current.Code&0x18 != syscall.BPF_K {
+				v := s.a
+				if current.Code&0x18 == syscall.BPF_X {
+					v = s.x
+				}
+				if v.kind != constant {
+					return fmt.Errorf("instruction %d: the returned value is calculated at runtime, which can not be analysed", pc)
+				}
+				result = v.k
+			}
+			if len(w.paths) >= maxPaths {
+				return fmt.Errorf("the program has more than %d paths", maxPaths)
+			}
+			w.paths = append(w.paths, flowPath{words: s.words, instructions: taken, result: result})
+			return nil
+		case syscall.BPF_LD, syscall.BPF_LDX:
+			var v value
+			switch current.Code & 0xE0 {
+			case syscall.BPF_ABS:
+				v = value{kind: word, k: current.K}
+			case syscall.BPF_IMM:
+				v = k
+			case syscall.BPF_MEM:
+				v = s.mem[current.K]
+			}
+			if current.Code&0x07 == syscall.BPF_LD {
+				s.a = v
+			} else {
+				s.x = v
+			}
+		case syscall.BPF_ST:
+			s.mem[current.K] = s.a
+		case syscall.BPF_STX:
+			s.mem[current.K] = s.x
+		case syscall.BPF_ALU:
+			s.a = value{kind: unknown}
+		case syscall.BPF_MISC:
+			if current.Code&0xF8 == syscall.BPF_TAX {
+				s.x = s.a
+			} else {
+				s.a = s.x
+			}
+		case syscall.BPF_JMP:
+			op := current.Code & 0xF0
+			if op == syscall.BPF_JA {
+				pc += int(current.K)
+				break
+			}
+			right := k
+			if current.Code&0x08 == syscall.BPF_X {
+				right = s.x
+			}
+			outcome, decided := s.decide(op, right)
+			if decided {
+				if outcome {
+					pc += int(current.Jt)
+				} else {
+					pc += int(current.Jf)
+				}
+				break
+			}
+			return w.branch(pc, s, taken, op, right)
+		}
+		pc++
+	}
+}
+
+func (s *state) decide(op uint16, right value) (bool, bool) {
+	if s.a.kind == constant && right.kind == constant {
+		return compare(op, s.a.k, right.k), true
+	}
+	if s.a.kind == word && right.kind == constant {
+		return s.words[s.a.k].decide(op, right.k)
+	}
+	return false, false
+}
+
+// branch follows both outcomes of a comparison
+func (w *flowWalker) branch(pc int, s state, taken []uint32, op uint16, right value) error {
+	current := w.program[pc]
+	for _, outcome := range []bool{true, false} {
+		next := s
+		if s.a.kind == word && right.kind == constant {
+			next.words = make(map[uint32]knowledge, len(s.words)+1)
+			for offset, known := range s.words {
+				next.words[offset] = known
+			}
+			next.words[s.a.k] = s.words[s.a.k].assume(op, right.k, outcome)
+		}
+		offset := current.Jf
+		if outcome {
+			offset = current.Jt
+		}
+		if err := w.walk(pc+1+int(offset), next, taken[:len(taken):len(taken)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
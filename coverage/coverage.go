@@ -0,0 +1,220 @@
+// Package coverage finds out which rules of a policy, which comparisons in the rule bodies and which
+// instructions of the compiled filter are exercised by a set of inputs, such as the system calls of a
+// recorded workload. The results can be written as a text report, or in the lcov format that coverage
+// tools understand, so that the untested parts of a policy can be found.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+)
+
+// Report contains the coverage of one filter program, together with the origins of its instructions
+type Report struct {
+	Program  []unix.SockFilter
+	Origins  []compiler.Origin
+	Coverage *emulator.Coverage
+	// Results counts how often each value was returned
+	Results map[uint32]int
+}
+
+// NewReport creates an empty report for a program and the origins the compiler returned for it
+func NewReport(program []unix.SockFilter, origins []compiler.Origin) *Report {
+	return &Report{
+		Program:  program,
+		Origins:  origins,
+		Coverage: emulator.NewCoverage(program),
+		Results:  make(map[uint32]int),
+	}
+}
+
+// Add runs the program with the input and adds the result to the report
+func (r *Report) Add(d data.SeccompWorkingMemory) {
+	r.Results[emulator.EmulateWithCoverage(d, r.Program, r.Coverage)]++
+}
+
+// Branch is the coverage of one comparison in a rule body
+type Branch struct {
+	Comparison  tree.Expression
+	True, False int
+}
+
+// Rule is the coverage of one rule
+type Rule struct {
+	Rule *tree.Rule
+	// Checked is how often the system call number was compared for this rule, and Matched how often it was the right one
+	Checked, Matched int
+	Branches         []Branch
+}
+
+// Rules returns the coverage of the rules, in the order they are in the program
+func (r *Report) Rules() []Rule {
+	result := []Rule{}
+	index := map[*tree.Rule]int{}
+	for pc, o := range r.Origins {
+		if o.Rule == nil {
+			continue
+		}
+		i, ok := index[o.Rule]
+		if !ok {
+			i = len(result)
+			index[o.Rule] = i
+			result = append(result, Rule{Rule: o.Rule})
+		}
+		s := r.Program[pc]
+		if !isConditionalJump(s) {
+			continue
+		}
+
+		taken, notTaken := r.Coverage.Taken[pc], r.Coverage.NotTaken[pc]
+		switch {
+		case o.Part == compiler.SyscallCheck:
+			result[i].Checked += taken + notTaken
+			result[i].Matched += taken
+		case o.Comparison != nil:
+			// Not equal comparisons are compiled as equality checks with the jumps swapped
+			if c, ok := o.Comparison.(tree.Comparison); ok && c.Op == tree.NEQL {
+				taken, notTaken = notTaken, taken
+			}
+			result[i].Branches = append(result[i].Branches, Branch{Comparison: o.Comparison, True: taken, False: notTaken})
+		}
+	}
+	return result
+}
+
+func isConditionalJump(s unix.SockFilter) bool {
+	return s.Code&0x07 == syscall.BPF_JMP && s.Code&0xF0 != syscall.BPF_JA
+}
+
+// Executed returns the number of instructions that were executed at least once
+func (r *Report) Executed() int {
+	result := 0
+	for _, n := range r.Coverage.Executed {
+		if n > 0 {
+			result++
+		}
+	}
+	return result
+}
+
+func coverageNote(counts ...int) string {
+	zero := 0
+	for _, c := range counts {
+		if c == 0 {
+			zero++
+		}
+	}
+	switch zero {
+	case 0:
+		return ""
+	case len(counts):
+		return " - not covered"
+	}
+	return " - partially covered"
+}
+
+func describePosition(r *tree.Rule) string {
+	if r.Position.IsKnown() {
+		return r.Position.String()
+	}
+	return "<unknown>"
+}
+
+// WriteText writes a report readable by people, with one line for every rule and comparison, followed by how
+// often each action was returned, and how many instructions were executed
+func (r *Report) WriteText(w io.Writer) {
+	for _, rule := range r.Rules() {
+		fmt.Fprintf(w, "%s: %s: matched %d of %d%s\n", describePosition(rule.Rule), rule.Rule.Name, rule.Matched, rule.Checked, coverageNote(rule.Matched))
+		for _, b := range rule.Branches {
+			fmt.Fprintf(w, "\t%s: true %d, false %d%s\n", tree.SourceString(b.Comparison), b.True, b.False, coverageNote(b.True, b.False))
+		}
+	}
+
+	returned := []string{}
+	for pc, o := range r.Origins {
+		if o.Part == compiler.ReturnAction {
+			k := r.Program[pc].K
+			returned = append(returned, fmt.Sprintf("%s %d%s", actions.Describe(k), r.Results[k], coverageNote(r.Results[k])))
+		}
+	}
+	sort.Strings(returned)
+	fmt.Fprintf(w, "returned: %s\n", strings.Join(returned, ", "))
+	fmt.Fprintf(w, "instructions: %d of %d executed\n", r.Executed(), len(r.Program))
+}
+
+// WriteLcov writes the coverage of the rules in the lcov tracefile format. Every rule is a line, with a hit for
+// every time its system call matched, and every comparison in a rule body is a pair of branches for its true
+// and false outcomes. Rules without a known position are left out
+func (r *Report) WriteLcov(w io.Writer) {
+	files := []string{}
+	byFile := map[string][]Rule{}
+	for _, rule := range r.Rules() {
+		f := rule.Rule.Position.File
+		if f == "" {
+			continue
+		}
+		if _, ok := byFile[f]; !ok {
+			files = append(files, f)
+		}
+		byFile[f] = append(byFile[f], rule)
+	}
+
+	for _, f := range files {
+		fmt.Fprintf(w, "TN:\nSF:%s\n", f)
+		branches, branchesHit, linesHit := 0, 0, 0
+		for block, rule := range byFile[f] {
+			// Positions count lines from zero, but lcov counts them from one
+			line := rule.Rule.Position.Line + 1
+			for i, b := range rule.Branches {
+				for j, count := range []int{b.True, b.False} {
+					branches++
+					if count > 0 {
+						branchesHit++
+					}
+					taken := fmt.Sprintf("%d", count)
+					if rule.Matched == 0 {
+						taken = "-"
+					}
+					fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n", line, block, 2*i+j, taken)
+				}
+			}
+			fmt.Fprintf(w, "DA:%d,%d\n", line, rule.Matched)
+			if rule.Matched > 0 {
+				linesHit++
+			}
+		}
+		fmt.Fprintf(w, "BRF:%d\nBRH:%d\nLF:%d\nLH:%d\nend_of_record\n", branches, branchesHit, len(byFile[f]), linesHit)
+	}
+}
+
+// ParseInputs reads working memory with one input per line, in the format data.ParseWorkingMemory reads.
+// Empty lines and lines starting with # are ignored. The architecture is the native one, unless a line specifies it
+func ParseInputs(r io.Reader) ([]data.SeccompWorkingMemory, error) {
+	result := []data.SeccompWorkingMemory{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		d, err := data.ParseWorkingMemory(fmt.Sprintf("arch=%d %s", native.AuditArch, text))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		result = append(result, d)
+	}
+	return result, scanner.Err()
+}
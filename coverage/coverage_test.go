@@ -0,0 +1,109 @@
+package coverage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/parser"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type CoverageSuite struct{}
+
+var _ = Suite(&CoverageSuite{})
+
+const policy = "" +
+	"read: argL0 == 0\n" +
+	"write: argL0 != 1 && argL2 > 0x10\n" +
+	"close: true\n"
+
+func report(c *C, inputs string) *Report {
+	program, origins, err := gosecco.PrepareSourceWithSourceMap(&parser.StringSource{Name: "x.policy", Content: policy},
+		gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "trap", DefaultPolicyAction: "kill"})
+	c.Assert(err, IsNil)
+
+	ds, err := ParseInputs(strings.NewReader(inputs))
+	c.Assert(err, IsNil)
+	r := NewReport(program, origins)
+	for _, d := range ds {
+		r.Add(d)
+	}
+	return r
+}
+
+const workload = "" +
+	"# a recorded workload\n" +
+	"nr=read arg0=0\n" +
+	"nr=read arg0=0\n" +
+	"nr=read arg0=3\n" +
+	"\n" +
+	"nr=write arg0=2 arg2=0x20\n" +
+	"nr=write arg0=2 arg2=0x8\n" +
+	"nr=getpid\n"
+
+func (s *CoverageSuite) Test_mapsCoverageToRules(c *C) {
+	rules := report(c, workload).Rules()
+
+	c.Assert(rules, HasLen, 3)
+	c.Assert(rules[0].Rule.Name, Equals, "read")
+	c.Assert(rules[0].Matched, Equals, 3)
+	c.Assert(rules[0].Branches, HasLen, 1)
+	c.Assert(rules[0].Branches[0].True, Equals, 2)
+	c.Assert(rules[0].Branches[0].False, Equals, 1)
+
+	c.Assert(rules[1].Rule.Name, Equals, "write")
+	c.Assert(rules[1].Checked, Equals, 3)
+	c.Assert(rules[1].Matched, Equals, 2)
+	c.Assert(rules[1].Branches, HasLen, 2)
+
+	c.Assert(rules[2].Rule.Name, Equals, "close")
+	c.Assert(rules[2].Matched, Equals, 0)
+}
+
+func (s *CoverageSuite) Test_writesATextReport(c *C) {
+	out := &bytes.Buffer{}
+	report(c, workload).WriteText(out)
+
+	c.Assert(out.String(), Equals, ""+
+		"x.policy:0: read: matched 3 of 6\n"+
+		"\targL0 == 0: true 2, false 1\n"+
+		"x.policy:1: write: matched 2 of 3\n"+
+		"\targL0 != 1: true 2, false 0 - partially covered\n"+
+		"\targL2 > 16: true 1, false 1\n"+
+		"x.policy:2: close: matched 0 of 1 - not covered\n"+
+		"returned: allow 3, kill 1, trap 2\n"+
+		"instructions: 15 of 15 executed\n")
+}
+
+func (s *CoverageSuite) Test_writesLcov(c *C) {
+	out := &bytes.Buffer{}
+	report(c, "nr=read arg0=0\n").WriteLcov(out)
+
+	c.Assert(out.String(), Equals, ""+
+		"TN:\n"+
+		"SF:x.policy\n"+
+		"BRDA:1,0,0,1\n"+
+		"BRDA:1,0,1,0\n"+
+		"DA:1,1\n"+
+		"BRDA:2,1,0,-\n"+
+		"BRDA:2,1,1,-\n"+
+		"BRDA:2,1,2,-\n"+
+		"BRDA:2,1,3,-\n"+
+		"DA:2,0\n"+
+		"DA:3,0\n"+
+		"BRF:6\n"+
+		"BRH:1\n"+
+		"LF:3\n"+
+		"LH:1\n"+
+		"end_of_record\n")
+}
+
+func (s *CoverageSuite) Test_reportsInvalidInputs(c *C) {
+	_, err := ParseInputs(strings.NewReader("nr=read\nnr=read arg7=1\n"))
+	c.Assert(err, ErrorMatches, "line 2: unknown field 'arg7'.*")
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/twtiger/gosecco/constants"
+)
+
+// ParseWorkingMemory reads a description of working memory. The description consists of space separated
+// assignments in the form name=value, where the names are nr, arch, ip and arg0 to arg5. All values can be
+// given in decimal, hex with a 0x prefix, or octal with a 0 prefix. The system call number can also be
+// given as the name of a system call. Values that are not mentioned will be zero. An example:
+//
+//	nr=write arch=0xC000003E arg0=1 arg2=0x20
+func ParseWorkingMemory(s string) (SeccompWorkingMemory, error) {
+	result := SeccompWorkingMemory{}
+	for _, assignment := range strings.Fields(s) {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 {
+			return SeccompWorkingMemory{}, fmt.Errorf("expected an assignment in the form name=value, but found '%s'", assignment)
+		}
+		name, value := strings.ToLower(parts[0]), parts[1]
+
+		if name == "nr" {
+			if nr, ok := constants.GetSyscall(value); ok {
+				result.NR = int32(nr)
+				continue
+			}
+		}
+
+		v, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return SeccompWorkingMemory{}, fmt.Errorf("invalid value for %s: '%s'", name, value)
+		}
+
+		switch {
+		case name == "nr" && v <= 0x7FFFFFFF:
+			result.NR = int32(v)
+		case name == "arch" && v <= 0xFFFFFFFF:
+			result.Arch = uint32(v)
+		case name == "ip":
+			result.InstructionPointer = v
+		case len(name) == 4 && strings.HasPrefix(name, "arg") && name[3] >= '0' && name[3] <= '5':
+			result.Args[name[3]-'0'] = v
+		case name == "nr" || name == "arch":
+			return SeccompWorkingMemory{}, fmt.Errorf("value for %s is out of range: '%s'", name, value)
+		default:
+			return SeccompWorkingMemory{}, fmt.Errorf("unknown field '%s' - expected nr, arch, ip or arg0 to arg5", parts[0])
+		}
+	}
+	return result, nil
+}
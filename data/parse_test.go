@@ -0,0 +1,37 @@
+package data
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ParseSuite struct{}
+
+var _ = Suite(&ParseSuite{})
+
+func (s *ParseSuite) Test_ParseWorkingMemory_readsAllFields(c *C) {
+	d, err := ParseWorkingMemory("nr=write arch=0xC000003E ip=0x400000  arg0=1 arg5=0x100000000")
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, SeccompWorkingMemory{NR: 1, Arch: 0xC000003E, InstructionPointer: 0x400000, Args: [6]uint64{1, 0, 0, 0, 0, 0x100000000}})
+
+	d, err = ParseWorkingMemory("NR=42")
+	c.Assert(err, IsNil)
+	c.Assert(d.NR, Equals, int32(42))
+}
+
+func (s *ParseSuite) Test_ParseWorkingMemory_reportsProblems(c *C) {
+	_, err := ParseWorkingMemory("nr")
+	c.Assert(err, ErrorMatches, "expected an assignment in the form name=value, but found 'nr'")
+
+	_, err = ParseWorkingMemory("arg6=1")
+	c.Assert(err, ErrorMatches, "unknown field 'arg6' - expected nr, arch, ip or arg0 to arg5")
+
+	_, err = ParseWorkingMemory("arg0=foo")
+	c.Assert(err, ErrorMatches, "invalid value for arg0: 'foo'")
+
+	_, err = ParseWorkingMemory("arch=0x100000000")
+	c.Assert(err, ErrorMatches, "value for arch is out of range: '0x100000000'")
+}
@@ -0,0 +1,454 @@
+// Package decompiler reconstructs a policy from a compiled filter, so that filters without a source can be
+// audited with the same tools as policies. The filter is executed symbolically once for every system call,
+// with the system call number and the architecture known, and the arguments unknown. Every comparison that
+// depends on the arguments becomes a branch, and the branches are turned back into a boolean expression.
+//
+// A rule can only have two outcomes in a policy, so filters returning more than two different values for
+// one system call can't be decompiled. Some filters can't be expressed exactly as a policy, such as filters
+// that treat numbers which aren't system calls differently from each other, or that don't check the
+// architecture - the result can be compared with the original filter with the equivalence package to find
+// out if it is exact.
+package decompiler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"syscall"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSteps is the largest number of instructions executed for one system call, to stop filters with a
+// very large number of paths
+const maxSteps = 100000
+
+// otherArchitecture is an architecture value that no filter is expected to accept
+const otherArchitecture = 0
+
+// seccompDataSize is the size of struct seccomp_data, which is what BPF_LEN loads
+const seccompDataSize = 64
+
+// decision is either a comparison with a decision for each outcome, or an action when comparison is nil
+type decision struct {
+	comparison          *tree.Comparison
+	whenTrue, whenFalse *decision
+	action              uint32
+}
+
+// state contains the values of the registers and scratch memory, either as literals or as expressions of
+// the arguments
+type state struct {
+	a, x tree.Numeric
+	mem  [16]tree.Numeric
+}
+
+// assumption is the outcome of a comparison on the current path, so that repeated comparisons of the same
+// values aren't turned into branches that can't be taken
+type assumption struct {
+	comparison string
+	outcome    bool
+}
+
+type walker struct {
+	program  []unix.SockFilter
+	nr, arch uint32
+	steps    int
+}
+
+// Decompile returns a policy that behaves like the filter program
+func Decompile(program []unix.SockFilter) (tree.Policy, error) {
+	if err := emulator.Verify(program); err != nil {
+		return tree.Policy{}, err
+	}
+
+	names := []string{}
+	for _, name := range constants.SyscallNumbers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	decisions := map[string]*decision{}
+	constantCounts := map[uint32]int{}
+	for _, name := range names {
+		nr, _ := constants.GetSyscall(name)
+		d, err := decide(program, nr, native.AuditArch)
+		if err != nil {
+			return tree.Policy{}, fmt.Errorf("%s: %s", name, err)
+		}
+		decisions[name] = d
+		if d.comparison == nil {
+			constantCounts[d.action]++
+		}
+	}
+
+	p := tree.Policy{}
+	defaultPolicy := mostCommon(constantCounts)
+	positives, negatives := map[uint32]int{}, map[uint32]int{}
+	outcomes := map[string][2]uint32{}
+	for _, name := range names {
+		d := decisions[name]
+		as := d.actions(map[uint32]bool{})
+		switch {
+		case len(as) == 1 && as[0] == defaultPolicy:
+			continue
+		case len(as) == 1:
+			outcomes[name] = [2]uint32{as[0], as[0]}
+			positives[as[0]]++
+		case len(as) == 2:
+			pos, neg := ordered(as[0], as[1])
+			outcomes[name] = [2]uint32{pos, neg}
+			positives[pos]++
+			negatives[neg]++
+		default:
+			return tree.Policy{}, fmt.Errorf("%s: the filter returns %d different values, but a rule can only have two outcomes", name, len(as))
+		}
+	}
+	if len(positives) == 0 {
+		positives[defaultPolicy]++
+	}
+	if len(negatives) == 0 {
+		negatives[defaultPolicy]++
+	}
+
+	var err error
+	defaultPositive, defaultNegative := mostCommon(positives), mostCommon(negatives)
+	if p.DefaultPositiveAction, err = actionName(defaultPositive); err != nil {
+		return tree.Policy{}, err
+	}
+	if p.DefaultNegativeAction, err = actionName(defaultNegative); err != nil {
+		return tree.Policy{}, err
+	}
+	if p.DefaultPolicyAction, err = actionName(defaultPolicy); err != nil {
+		return tree.Policy{}, err
+	}
+
+	for _, name := range names {
+		o, ok := outcomes[name]
+		if !ok {
+			continue
+		}
+		r := &tree.Rule{Name: name, Body: mergeHalves(decisions[name].expression(o[0]))}
+		if o[0] != defaultPositive {
+			if r.PositiveAction, err = actionName(o[0]); err != nil {
+				return tree.Policy{}, err
+			}
+		}
+		if o[1] != o[0] && o[1] != defaultNegative {
+			if r.NegativeAction, err = actionName(o[1]); err != nil {
+				return tree.Policy{}, err
+			}
+		}
+		p.Rules = append(p.Rules, r)
+	}
+
+	if p.ActionOnX32, err = constantAction(program, defaultPolicy, []uint32{native.X32SyscallBit, native.X32SyscallBit | 1}, native.AuditArch); err != nil {
+		return tree.Policy{}, err
+	}
+	if p.ActionOnAuditFailure, err = constantAction(program, actions.RetKill, []uint32{0, 1}, otherArchitecture); err != nil {
+		return tree.Policy{}, err
+	}
+	return p, nil
+}
+
+// constantAction returns the name of the action the program returns for all the system call numbers on the
+// architecture, or an empty string if it isn't the same for all of them or is the same as the default
+func constantAction(program []unix.SockFilter, def uint32, nrs []uint32, arch uint32) (string, error) {
+	result := []uint32{}
+	for _, nr := range nrs {
+		d, err := decide(program, nr, arch)
+		if err != nil {
+			return "", err
+		}
+		if d.comparison != nil || len(result) > 0 && result[0] != d.action {
+			return "", nil
+		}
+		result = append(result, d.action)
+	}
+	if result[0] == def {
+		return "", nil
+	}
+	return actionName(result[0])
+}
+
+func decide(program []unix.SockFilter, nr, arch uint32) (*decision, error) {
+	w := &walker{program: program, nr: nr, arch: arch}
+	return w.walk(0, state{a: literal(0), x: literal(0)}, nil)
+}
+
+// mostCommon returns the value with the highest count, and the lowest of them if there are several
+func mostCommon(counts map[uint32]int) uint32 {
+	result, best := uint32(0), -1
+	for v, n := range counts {
+		if n > best || n == best && v < result {
+			result, best = v, n
+		}
+	}
+	return result
+}
+
+// ordered returns the most permissive of two actions first, which becomes the positive action of a rule
+func ordered(a, b uint32) (uint32, uint32) {
+	if rank(a) < rank(b) || rank(a) == rank(b) && a > b {
+		return b, a
+	}
+	return a, b
+}
+
+func rank(k uint32) actions.Kind {
+	a, err := actions.Parse(actions.Describe(k))
+	if err != nil && k&0xFFFF0000 == actions.RetErrno {
+		return actions.Errno
+	}
+	return a.Kind
+}
+
+// actionName returns the name of a value returned by the filter, as it is written in policies
+func actionName(k uint32) (string, error) {
+	name := actions.Describe(k)
+	if _, err := actions.Parse(name); err == nil {
+		return name, nil
+	}
+	if k&0xFFFF0000 == actions.RetErrno {
+		return fmt.Sprintf("%d", k&0xFFFF), nil
+	}
+	return "", fmt.Errorf("the return value 0x%08X can't be written as an action", k)
+}
+
+// actions returns the different actions the decision can end in, in the order they are found
+func (d *decision) actions(seen map[uint32]bool) []uint32 {
+	if d.comparison != nil {
+		return append(d.whenTrue.actions(seen), d.whenFalse.actions(seen)...)
+	}
+	if seen[d.action] {
+		return nil
+	}
+	seen[d.action] = true
+	return []uint32{d.action}
+}
+
+func literal(v uint32) tree.Numeric {
+	return tree.NumericLiteral{Value: uint64(v)}
+}
+
+func literalValue(e tree.Numeric) (uint32, bool) {
+	l, ok := e.(tree.NumericLiteral)
+	return uint32(l.Value), ok
+}
+
+// load returns the value of the word at the offset in seccomp_data
+func (w *walker) load(offset uint32) (tree.Numeric, error) {
+	switch {
+	case offset == 0:
+		return literal(w.nr), nil
+	case offset == 4:
+		return literal(w.arch), nil
+	case offset >= 0x10:
+		t := tree.Low
+		if offset%8 == 4 {
+			t = tree.Hi
+		}
+		return tree.Argument{Type: t, Index: int(offset-0x10) / 8}, nil
+	}
+	return nil, fmt.Errorf("the filter loads the instruction pointer, which policies can't use")
+}
+
+var arithmeticOps = map[uint16]tree.ArithmeticType{
+	syscall.BPF_ADD:  tree.PLUS,
+	syscall.BPF_SUB:  tree.MINUS,
+	syscall.BPF_MUL:  tree.MULT,
+	syscall.BPF_DIV:  tree.DIV,
+	syscall.BPF_AND:  tree.BINAND,
+	syscall.BPF_OR:   tree.BINOR,
+	emulator.BPF_XOR: tree.BINXOR,
+	syscall.BPF_LSH:  tree.LSH,
+	syscall.BPF_RSH:  tree.RSH,
+	emulator.BPF_MOD: tree.MOD,
+}
+
+// arithmetic returns the result of an operation, calculated if both operands are known
+func arithmetic(op tree.ArithmeticType, left, right tree.Numeric) (tree.Numeric, error) {
+	l, lok := literalValue(left)
+	r, rok := literalValue(right)
+	if !lok || !rok {
+		return tree.Arithmetic{Op: op, Left: left, Right: right}, nil
+	}
+	switch op {
+	case tree.PLUS:
+		return literal(l + r), nil
+	case tree.MINUS:
+		return literal(l - r), nil
+	case tree.MULT:
+		return literal(l * r), nil
+	case tree.BINAND:
+		return literal(l & r), nil
+	case tree.BINOR:
+		return literal(l | r), nil
+	case tree.BINXOR:
+		return literal(l ^ r), nil
+	case tree.LSH:
+		return literal(l << r), nil
+	case tree.RSH:
+		return literal(l >> r), nil
+	}
+	if r == 0 {
+		return nil, fmt.Errorf("the filter divides by zero")
+	}
+	if op == tree.DIV {
+		return literal(l / r), nil
+	}
+	return literal(l % r), nil
+}
+
+var comparisonOps = map[uint16]tree.ComparisonType{
+	syscall.BPF_JEQ:  tree.EQL,
+	syscall.BPF_JGT:  tree.GT,
+	syscall.BPF_JGE:  tree.GTE,
+	syscall.BPF_JSET: tree.BITSET,
+}
+
+func compare(op tree.ComparisonType, l, r uint32) bool {
+	switch op {
+	case tree.EQL:
+		return l == r
+	case tree.GT:
+		return l > r
+	case tree.GTE:
+		return l >= r
+	}
+	return l&r != 0
+}
+
+func (w *walker) walk(pc int, s state, assumed []assumption) (*decision, error) {
+	for {
+		w.steps++
+		if w.steps > maxSteps {
+			return nil, fmt.Errorf("the filter has too many paths to decompile")
+		}
+
+		current := w.program[pc]
+		k := literal(current.K)
+		var err error
+		switch current.Code & 0x07 {
+		case syscall.BPF_RET:
+			v := k
+			if current.Code&0x18 == syscall.BPF_A {
+				v = s.a
+			} else if current.Code&0x18 == syscall.BPF_X {
+				v = s.x
+			}
+			result, ok := literalValue(v)
+			if !ok {
+				return nil, fmt.Errorf("the filter returns a value calculated from the arguments")
+			}
+			return &decision{action: result}, nil
+		case syscall.BPF_LD, syscall.BPF_LDX:
+			var v tree.Numeric
+			switch current.Code & 0xE0 {
+			case syscall.BPF_ABS:
+				v, err = w.load(current.K)
+			case syscall.BPF_IMM:
+				v = k
+			case syscall.BPF_MEM:
+				v = s.mem[current.K]
+			case syscall.BPF_LEN:
+				v = literal(seccompDataSize)
+			default:
+				err = fmt.Errorf("unsupported instruction code 0x%02X", current.Code)
+			}
+			if current.Code&0x07 == syscall.BPF_LD {
+				s.a = v
+			} else {
+				s.x = v
+			}
+		case syscall.BPF_ST:
+			s.mem[current.K] = s.a
+		case syscall.BPF_STX:
+			s.mem[current.K] = s.x
+		case syscall.BPF_ALU:
+			right := k
+			if current.Code&0x08 == syscall.BPF_X {
+				right = s.x
+			}
+			if current.Code&0xF0 == syscall.BPF_NEG {
+				s.a, err = arithmetic(tree.MINUS, literal(0), s.a)
+			} else {
+				s.a, err = arithmetic(arithmeticOps[current.Code&0xF0], s.a, right)
+			}
+		case syscall.BPF_MISC:
+			if current.Code&0xF8 == syscall.BPF_TAX {
+				s.x = s.a
+			} else {
+				s.a = s.x
+			}
+		case syscall.BPF_JMP:
+			if current.Code&0xF0 == syscall.BPF_JA {
+				pc += int(current.K)
+				break
+			}
+			right := k
+			if current.Code&0x08 == syscall.BPF_X {
+				right = s.x
+			}
+			op := comparisonOps[current.Code&0xF0]
+			l, lok := literalValue(s.a)
+			r, rok := literalValue(right)
+			if lok && rok {
+				if compare(op, l, r) {
+					pc += int(current.Jt)
+				} else {
+					pc += int(current.Jf)
+				}
+				break
+			}
+			c := tree.Comparison{Op: op, Left: s.a, Right: right}
+			if outcome, ok := assumedOutcome(assumed, c); ok {
+				if outcome {
+					pc += int(current.Jt)
+				} else {
+					pc += int(current.Jf)
+				}
+				break
+			}
+			return w.branch(pc, s, assumed, c)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pc++
+	}
+}
+
+func assumedOutcome(assumed []assumption, c tree.Comparison) (bool, bool) {
+	key := tree.SourceString(c)
+	for _, a := range assumed {
+		if a.comparison == key {
+			return a.outcome, true
+		}
+	}
+	return false, false
+}
+
+// branch explores both outcomes of a comparison that depends on the arguments
+func (w *walker) branch(pc int, s state, assumed []assumption, c tree.Comparison) (*decision, error) {
+	current := w.program[pc]
+	key := tree.SourceString(c)
+	whenTrue, err := w.walk(pc+1+int(current.Jt), s, append(assumed[:len(assumed):len(assumed)], assumption{key, true}))
+	if err != nil {
+		return nil, err
+	}
+	whenFalse, err := w.walk(pc+1+int(current.Jf), s, append(assumed[:len(assumed):len(assumed)], assumption{key, false}))
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(whenTrue, whenFalse) {
+		return whenTrue, nil
+	}
+	return &decision{comparison: &c, whenTrue: whenTrue, whenFalse: whenFalse}, nil
+}
@@ -0,0 +1,125 @@
+package decompiler
+
+import (
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/equivalence"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type DecompilerSuite struct{}
+
+var _ = Suite(&DecompilerSuite{})
+
+func compile(c *C, source, onX32, onAuditFailure string) []unix.SockFilter {
+	pol, err := gosecco.PreparePolicy(&parser.StringSource{Name: "x.policy", Content: source},
+		gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill"})
+	c.Assert(err, IsNil)
+	pol.ActionOnX32 = onX32
+	pol.ActionOnAuditFailure = onAuditFailure
+	program, err := compiler.Compile(pol)
+	c.Assert(err, IsNil)
+	return program
+}
+
+// assertRoundTrip decompiles the program, and checks that the source of the policy compiles to a program that
+// behaves exactly like it
+func assertRoundTrip(c *C, program []unix.SockFilter) tree.Policy {
+	p, err := Decompile(program)
+	c.Assert(err, IsNil)
+
+	reparsed, err := gosecco.PreparePolicy(&parser.StringSource{Name: "decompiled.policy", Content: tree.PolicySource(p)}, gosecco.SeccompSettings{})
+	c.Assert(err, IsNil)
+	reparsed.ActionOnX32 = p.ActionOnX32
+	reparsed.ActionOnAuditFailure = p.ActionOnAuditFailure
+	recompiled, err := compiler.Compile(reparsed)
+	c.Assert(err, IsNil)
+	counterexample, err := equivalence.Check(program, recompiled)
+	c.Assert(err, IsNil)
+	c.Assert(counterexample, IsNil)
+	return p
+}
+
+func (s *DecompilerSuite) Test_decompilesConstantRules(c *C) {
+	p := assertRoundTrip(c, compile(c, "read: 1\nwrite: 1\nclose[+trace]: 1\n", "", ""))
+
+	c.Assert(tree.PolicySource(p), Equals, ""+
+		"DEFAULT_POSITIVE = allow\n"+
+		"DEFAULT_NEGATIVE = kill\n"+
+		"DEFAULT_POLICY = kill\n"+
+		"close[+trace]: true\n"+
+		"read: true\n"+
+		"write: true\n")
+}
+
+func (s *DecompilerSuite) Test_decompilesArgumentComparisons(c *C) {
+	p := assertRoundTrip(c, compile(c, "read: arg0 == 4\nwrite: arg1 != 42\nclose[+EPERM]: arg0 == 1 || arg0 == 2\n", "", ""))
+
+	c.Assert(p.Rules, HasLen, 3)
+	c.Assert(tree.RuleSource(p.Rules[1]), Equals, "read: arg0 == 4")
+	c.Assert(tree.RuleSource(p.Rules[2]), Equals, "write: arg1 != 42")
+	c.Assert(tree.RuleSource(p.Rules[0]), Equals, "close[+EPERM]: (argL0 == 1 || argL0 == 2) && argH0 == 0")
+}
+
+func (s *DecompilerSuite) Test_decompilesArithmeticAndBitChecks(c *C) {
+	assertRoundTrip(c, compile(c, "read: (argL0 & 0xF0) == 0x10\nwrite: arg2 > 10 && arg2 <= 100\nmmap: arg2 &? 4\n", "", ""))
+}
+
+func (s *DecompilerSuite) Test_recoversTheArchitectureAndX32Actions(c *C) {
+	p := assertRoundTrip(c, compile(c, "read: 1\n", "trap", "trace"))
+
+	c.Assert(p.ActionOnX32, Equals, "trap")
+	c.Assert(p.ActionOnAuditFailure, Equals, "trace")
+}
+
+func (s *DecompilerSuite) Test_usesTheMostCommonActionsAsDefaults(c *C) {
+	p, err := Decompile(asm.MustParse("" +
+		"ld_abs\t0\n" +
+		"jeq_k\t00\t01\t0\n" +
+		"ret_k\t7FFF0000\n" +
+		"ret_k\t50001\n"))
+
+	c.Assert(err, IsNil)
+	c.Assert(p.DefaultPositiveAction, Equals, "allow")
+	c.Assert(p.DefaultPolicyAction, Equals, "EPERM")
+	c.Assert(p.ActionOnAuditFailure, Equals, "")
+	c.Assert(p.Rules, HasLen, 1)
+	c.Assert(tree.RuleSource(p.Rules[0]), Equals, "read: true")
+}
+
+func (s *DecompilerSuite) Test_failsForRulesWithMoreThanTwoOutcomes(c *C) {
+	_, err := Decompile(asm.MustParse("" +
+		"ld_abs\t10\n" +
+		"jeq_k\t00\t01\t0\n" +
+		"ret_k\t7FFF0000\n" +
+		"jeq_k\t00\t01\t1\n" +
+		"ret_k\t50001\n" +
+		"ret_k\t0\n"))
+
+	c.Assert(err, ErrorMatches, ".*: the filter returns 3 different values, but a rule can only have two outcomes")
+}
+
+func (s *DecompilerSuite) Test_failsForFiltersUsingTheInstructionPointer(c *C) {
+	_, err := Decompile(asm.MustParse("" +
+		"ld_abs\t8\n" +
+		"jeq_k\t00\t01\t0\n" +
+		"ret_k\t7FFF0000\n" +
+		"ret_k\t0\n"))
+
+	c.Assert(err, ErrorMatches, ".*: the filter loads the instruction pointer, which policies can't use")
+}
+
+func (s *DecompilerSuite) Test_failsForInvalidPrograms(c *C) {
+	_, err := Decompile(asm.MustParse("ld_abs\t0\n"))
+
+	c.Assert(err, NotNil)
+}
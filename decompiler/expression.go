@@ -0,0 +1,129 @@
+package decompiler
+
+import (
+	"reflect"
+
+	"github.com/twtiger/gosecco/tree"
+)
+
+var negatedComparisons = map[tree.ComparisonType]tree.ComparisonType{
+	tree.EQL:  tree.NEQL,
+	tree.NEQL: tree.EQL,
+	tree.GT:   tree.LTE,
+	tree.GTE:  tree.LT,
+	tree.LT:   tree.GTE,
+	tree.LTE:  tree.GT,
+}
+
+// negate returns the opposite of a condition, moving the negation into conjunctions and alternatives, and
+// leaving it out when there is a comparison for it
+func negate(e tree.Boolean) tree.Boolean {
+	switch v := e.(type) {
+	case tree.Comparison:
+		if op, ok := negatedComparisons[v.Op]; ok {
+			return tree.Comparison{Op: op, Left: v.Left, Right: v.Right}
+		}
+	case tree.And:
+		return tree.Or{Left: negate(v.Left), Right: negate(v.Right)}
+	case tree.Or:
+		return tree.And{Left: negate(v.Left), Right: negate(v.Right)}
+	case tree.Negation:
+		return v.Operand
+	}
+	return tree.Negation{Operand: e}
+}
+
+// combine joins conditions that lead to a shared decision - when the decision for one outcome of a condition
+// has a branch that is the same as the decision for the other outcome, as compiled && and || do
+func combine(condition tree.Boolean, whenTrue, whenFalse *decision) (tree.Boolean, *decision, *decision) {
+	for {
+		switch {
+		case whenTrue.comparison != nil && reflect.DeepEqual(whenTrue.whenFalse, whenFalse):
+			condition, whenTrue = tree.And{Left: condition, Right: *whenTrue.comparison}, whenTrue.whenTrue
+		case whenTrue.comparison != nil && reflect.DeepEqual(whenTrue.whenTrue, whenFalse):
+			condition, whenTrue = tree.And{Left: condition, Right: negate(*whenTrue.comparison)}, whenTrue.whenFalse
+		case whenFalse.comparison != nil && reflect.DeepEqual(whenFalse.whenTrue, whenTrue):
+			condition, whenFalse = tree.Or{Left: condition, Right: *whenFalse.comparison}, whenFalse.whenFalse
+		case whenFalse.comparison != nil && reflect.DeepEqual(whenFalse.whenFalse, whenTrue):
+			condition, whenFalse = tree.Or{Left: condition, Right: negate(*whenFalse.comparison)}, whenFalse.whenTrue
+		default:
+			return condition, whenTrue, whenFalse
+		}
+	}
+}
+
+// expression returns a boolean expression that is true when the decision ends in the positive action
+func (d *decision) expression(positive uint32) tree.Boolean {
+	if d.comparison == nil {
+		return tree.BooleanLiteral{Value: d.action == positive}
+	}
+
+	c, dt, df := combine(*d.comparison, d.whenTrue, d.whenFalse)
+	whenTrue, whenFalse := dt.expression(positive), df.expression(positive)
+	t, tok := whenTrue.(tree.BooleanLiteral)
+	f, fok := whenFalse.(tree.BooleanLiteral)
+	switch {
+	case tok && fok && t.Value:
+		return c
+	case tok && fok:
+		return negate(c)
+	case tok && t.Value:
+		return tree.Or{Left: c, Right: whenFalse}
+	case tok:
+		return tree.And{Left: negate(c), Right: whenFalse}
+	case fok && f.Value:
+		return tree.Or{Left: negate(c), Right: whenTrue}
+	case fok:
+		return tree.And{Left: c, Right: whenTrue}
+	}
+	return tree.Or{Left: tree.And{Left: c, Right: whenTrue}, Right: tree.And{Left: negate(c), Right: whenFalse}}
+}
+
+// half returns the argument and the value of a comparison between one half of an argument and a literal
+func half(e tree.Boolean, op tree.ComparisonType, t tree.ArgumentType) (int, uint64, bool) {
+	c, ok := e.(tree.Comparison)
+	if !ok || c.Op != op {
+		return 0, 0, false
+	}
+	a, aok := c.Left.(tree.Argument)
+	l, lok := c.Right.(tree.NumericLiteral)
+	if !aok || !lok || a.Type != t {
+		return 0, 0, false
+	}
+	return a.Index, l.Value, true
+}
+
+// wholeArgument returns a comparison of a whole argument, if the two expressions compare both its halves
+// with the operator
+func wholeArgument(left, right tree.Boolean, op tree.ComparisonType) (tree.Boolean, bool) {
+	for _, pair := range [][2]tree.Boolean{{left, right}, {right, left}} {
+		hi, hv, hok := half(pair[0], op, tree.Hi)
+		lo, lv, lok := half(pair[1], op, tree.Low)
+		if hok && lok && hi == lo {
+			return tree.Comparison{Op: op, Left: tree.Argument{Type: tree.Full, Index: hi}, Right: tree.NumericLiteral{Value: hv<<32 | lv}}, true
+		}
+	}
+	return nil, false
+}
+
+// mergeHalves turns the comparisons of the two halves of an argument that compiled policies use back into
+// comparisons of the whole argument - argH0 == 0 && argL0 == 1 becomes arg0 == 1, and the same with != and ||
+func mergeHalves(e tree.Boolean) tree.Boolean {
+	switch v := e.(type) {
+	case tree.And:
+		left, right := mergeHalves(v.Left), mergeHalves(v.Right)
+		if merged, ok := wholeArgument(left, right, tree.EQL); ok {
+			return merged
+		}
+		return tree.And{Left: left, Right: right}
+	case tree.Or:
+		left, right := mergeHalves(v.Left), mergeHalves(v.Right)
+		if merged, ok := wholeArgument(left, right, tree.NEQL); ok {
+			return merged
+		}
+		return tree.Or{Left: left, Right: right}
+	case tree.Negation:
+		return tree.Negation{Operand: mergeHalves(v.Operand)}
+	}
+	return e
+}
@@ -0,0 +1,140 @@
+// Package diff describes what changed in the behavior of a policy, as opposed to what changed in its text.
+// Two compiled filters are compared over all possible working memory, and the regions where they return
+// different values are summarized for each system call, with the conditions on the arguments written in the
+// policy language. For example:
+//
+//	openat: now allowed when argL2 &? 0x40
+//	ptrace: kill → EPERM
+//	new syscall allowed: memfd_create
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/equivalence"
+	"github.com/twtiger/gosecco/parser"
+
+	"golang.org/x/sys/unix"
+)
+
+// Change describes how the outcome of a system call differs between two policies. Syscall is the name of
+// the system call, or a description such as "other system calls" for the system calls the policies don't mention
+type Change struct {
+	Syscall  string
+	Old, New uint32
+	// Conditions contains the alternative conditions on the arguments for which the outcome changed. If it is
+	// empty, the outcome changed for all arguments
+	Conditions []string
+}
+
+func (c Change) String() string {
+	old, new := actions.Describe(c.Old), actions.Describe(c.New)
+	_, named := constants.GetSyscall(c.Syscall)
+
+	was := ""
+	if c.Old != actions.RetKill {
+		was = fmt.Sprintf(" (was %s)", old)
+	}
+
+	if len(c.Conditions) == 0 {
+		if named && c.New == actions.RetAllow {
+			return fmt.Sprintf("new syscall allowed: %s%s", c.Syscall, was)
+		}
+		return fmt.Sprintf("%s: %s → %s", c.Syscall, old, new)
+	}
+
+	when := c.when()
+	switch {
+	case c.New == actions.RetAllow:
+		return fmt.Sprintf("%s: now allowed when %s%s", c.Syscall, when, was)
+	case c.Old == actions.RetAllow:
+		return fmt.Sprintf("%s: no longer allowed when %s (now %s)", c.Syscall, when, new)
+	}
+	return fmt.Sprintf("%s: %s → %s when %s", c.Syscall, old, new, when)
+}
+
+func (c Change) when() string {
+	if len(c.Conditions) == 1 {
+		return c.Conditions[0]
+	}
+	alternatives := make([]string, len(c.Conditions))
+	for i, cond := range c.Conditions {
+		if strings.Contains(cond, " && ") {
+			cond = "(" + cond + ")"
+		}
+		alternatives[i] = cond
+	}
+	return strings.Join(alternatives, " || ")
+}
+
+// Compare returns the changes in behavior from the old to the new filter program, sorted by the name of
+// the system call. The errors are the ones from equivalence.Differences
+func Compare(old, new []unix.SockFilter) ([]Change, error) {
+	ds, err := equivalence.Differences(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		syscall  string
+		old, new uint32
+	}
+	keys := []key{}
+	regions := map[key][]region{}
+	for _, d := range ds {
+		k := key{equivalence.DescribeSyscall(d.Words[0], d.Words[1]), d.Left, d.Right}
+		if _, ok := regions[k]; !ok {
+			keys = append(keys, k)
+		}
+		regions[k] = append(regions[k], region(d.Words))
+	}
+
+	result := []Change{}
+	for _, k := range keys {
+		c := Change{Syscall: k.syscall, Old: k.old, New: k.new}
+		for _, r := range mergeRegions(regions[k]) {
+			if conds := r.conditions(); len(conds) > 0 {
+				c.Conditions = append(c.Conditions, strings.Join(conds, " && "))
+			} else {
+				c.Conditions = nil
+				break
+			}
+		}
+		result = append(result, c)
+	}
+
+	sort.Stable(bySyscall(result))
+	return result, nil
+}
+
+// CompareSources compiles the two policies with the same settings, and compares the results
+func CompareSources(old, new parser.Source, s gosecco.SeccompSettings) ([]Change, error) {
+	o, err := gosecco.PrepareSource(old, s)
+	if err != nil {
+		return nil, err
+	}
+	n, err := gosecco.PrepareSource(new, s)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(o, n)
+}
+
+// bySyscall sorts the changes for named system calls first
+type bySyscall []Change
+
+func (s bySyscall) Len() int      { return len(s) }
+func (s bySyscall) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySyscall) Less(i, j int) bool {
+	_, ni := constants.GetSyscall(s[i].Syscall)
+	_, nj := constants.GetSyscall(s[j].Syscall)
+	if ni != nj {
+		return ni
+	}
+	return s[i].Syscall < s[j].Syscall
+}
@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/parser"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type DiffSuite struct{}
+
+var _ = Suite(&DiffSuite{})
+
+var settings = gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill"}
+
+func source(s string) parser.Source {
+	return &parser.StringSource{Name: "<test>", Content: s}
+}
+
+func describe(changes []Change) []string {
+	result := []string{}
+	for _, c := range changes {
+		result = append(result, c.String())
+	}
+	return result
+}
+
+func (s *DiffSuite) Test_describesChangesPerSyscall(c *C) {
+	changes, err := CompareSources(
+		source("read: true\nopenat: argL2 & 0x40 == 0\nwrite: arg0 == 1\n"),
+		source("read: true\nopenat: true\nptrace: return 1\nmemfd_create: true\nwrite: arg0 == 1 || arg0 == 2\n"),
+		settings)
+
+	c.Assert(err, IsNil)
+	c.Assert(describe(changes), DeepEquals, []string{
+		"new syscall allowed: memfd_create",
+		"openat: now allowed when argL2 &? 0x40",
+		"ptrace: kill → EPERM",
+		"write: now allowed when arg0 == 2",
+	})
+}
+
+func (s *DiffSuite) Test_describesRemovedPermissionsAndOtherActions(c *C) {
+	changes, err := CompareSources(
+		source("read: arg0 <= 5\nclose: true\nopen: argH1 == 0; return 13\n"),
+		source("read: arg0 <= 3\nopen: argH1 == 0; return 1\n"),
+		settings)
+
+	c.Assert(err, IsNil)
+	c.Assert(describe(changes), DeepEquals, []string{
+		"close: allow → kill",
+		"open: EACCES → EPERM when arg1 <= 0xFFFFFFFF",
+		"read: no longer allowed when arg0 >= 4 && arg0 <= 5 (now kill)",
+	})
+}
+
+func (s *DiffSuite) Test_describesSystemCallsOutsideThePolicy(c *C) {
+	old, err := gosecco.PrepareSource(source("read: true\n"), settings)
+	c.Assert(err, IsNil)
+	trapping := settings
+	trapping.DefaultPolicyAction = "trap"
+	new, err := gosecco.PrepareSource(source("read: true\n"), trapping)
+	c.Assert(err, IsNil)
+
+	changes, err := Compare(old, new)
+	c.Assert(err, IsNil)
+	c.Assert(changes, DeepEquals, []Change{{Syscall: "other system calls", Old: actions.RetKill, New: actions.RetTrap}})
+	c.Assert(changes[0].String(), Equals, "other system calls: kill → trap")
+}
+
+func (s *DiffSuite) Test_findsNoChangesForEquivalentPolicies(c *C) {
+	changes, err := CompareSources(
+		source("read: arg0 == 1 || arg0 == 2\nwrite: true\n"),
+		source("write: true\nread: in(arg0, 2, 1)\n"),
+		settings)
+
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 0)
+}
+
+func (s *DiffSuite) Test_joinsAlternativeConditions(c *C) {
+	ch := Change{Syscall: "read", Old: actions.RetTrap, New: actions.RetAllow, Conditions: []string{"arg0 == 1", "argL1 &? 4 && argH1 == 0"}}
+	c.Assert(ch.String(), Equals, "read: now allowed when arg0 == 1 || (argL1 &? 4 && argH1 == 0) (was trap)")
+
+	ch = Change{Syscall: "read", Old: actions.RetErrno | 1, New: actions.RetAllow}
+	c.Assert(ch.String(), Equals, "new syscall allowed: read (was EPERM)")
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/twtiger/gosecco/equivalence"
+)
+
+const (
+	maxWord   = uint64(0xFFFFFFFF)
+	maxDouble = ^uint64(0)
+)
+
+// region is the words of seccomp_data for a part of the input, in the order equivalence uses
+type region [16]equivalence.Word
+
+// interval is an inclusive range of values, for either 32 or 64 bit values
+type interval struct {
+	lo, hi uint64
+}
+
+func intervalsOf(w equivalence.Word) []interval {
+	result := make([]interval, len(w.Intervals))
+	for i, in := range w.Intervals {
+		result[i] = interval{uint64(in.Lo), uint64(in.Hi)}
+	}
+	return result
+}
+
+// mergeRegions joins regions that only differ in the intervals of one word, until no more regions can be joined.
+// This undoes most of the splitting that comparisons not related to the change caused
+func mergeRegions(rs []region) []region {
+	result := append([]region{}, rs...)
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(result) && !merged; i++ {
+			for j := i + 1; j < len(result) && !merged; j++ {
+				if r, ok := join(result[i], result[j]); ok {
+					result[i] = r
+					result = append(result[:j], result[j+1:]...)
+					merged = true
+				}
+			}
+		}
+	}
+	return result
+}
+
+func join(a, b region) (region, bool) {
+	differing := -1
+	for i := range a {
+		if !sameWord(a[i], b[i]) {
+			if differing != -1 || a[i].Set != b[i].Set || a[i].Clear != b[i].Clear {
+				return region{}, false
+			}
+			differing = i
+		}
+	}
+
+	if differing != -1 {
+		ivs := union(intervalsOf(a[differing]), intervalsOf(b[differing]))
+		w := equivalence.Word{Set: a[differing].Set, Clear: a[differing].Clear}
+		for _, i := range ivs {
+			w.Intervals = append(w.Intervals, equivalence.Interval{Lo: uint32(i.lo), Hi: uint32(i.hi)})
+		}
+		a[differing] = w
+	}
+	return a, true
+}
+
+func sameWord(a, b equivalence.Word) bool {
+	if a.Set != b.Set || a.Clear != b.Clear || len(a.Intervals) != len(b.Intervals) {
+		return false
+	}
+	for i := range a.Intervals {
+		if a.Intervals[i] != b.Intervals[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// union returns the sorted, joined intervals of two sorted lists
+func union(a, b []interval) []interval {
+	all := []interval{}
+	for len(a) > 0 || len(b) > 0 {
+		if len(b) == 0 || len(a) > 0 && a[0].lo <= b[0].lo {
+			all, a = append(all, a[0]), a[1:]
+		} else {
+			all, b = append(all, b[0]), b[1:]
+		}
+	}
+
+	result := []interval{}
+	for _, i := range all {
+		if n := len(result); n > 0 && result[n-1].hi+1 >= i.lo {
+			if i.hi > result[n-1].hi {
+				result[n-1].hi = i.hi
+			}
+			continue
+		}
+		result = append(result, i)
+	}
+	return result
+}
+
+// conditions describes the restrictions on the instruction pointer and the arguments in the region
+func (r region) conditions() []string {
+	result := doubleConditions("ip", "ipL", "ipH", r[3], r[2])
+	for i := 0; i < 6; i++ {
+		result = append(result, doubleConditions(
+			fmt.Sprintf("arg%d", i), fmt.Sprintf("argL%d", i), fmt.Sprintf("argH%d", i), r[4+2*i], r[5+2*i])...)
+	}
+	return result
+}
+
+// doubleConditions describes a 64 bit value made from two words. When the restrictions can be described for the
+// whole value, that is preferred - otherwise the halves are described one by one
+func doubleConditions(name, lowName, highName string, low, high equivalence.Word) []string {
+	if low.Unrestricted() && high.Unrestricted() {
+		return nil
+	}
+
+	if h, ok := high.Single(); ok && low.Set == 0 && low.Clear == 0 {
+		ivs := intervalsOf(low)
+		for i := range ivs {
+			ivs[i].lo |= uint64(h) << 32
+			ivs[i].hi |= uint64(h) << 32
+		}
+		return intervalConditions(name, ivs, maxDouble)
+	}
+
+	if low.Unrestricted() && high.Set == 0 && high.Clear == 0 {
+		ivs := intervalsOf(high)
+		for i := range ivs {
+			ivs[i].lo = ivs[i].lo << 32
+			ivs[i].hi = ivs[i].hi<<32 | maxWord
+		}
+		return intervalConditions(name, ivs, maxDouble)
+	}
+
+	return append(wordConditions(lowName, low), wordConditions(highName, high)...)
+}
+
+func wordConditions(name string, w equivalence.Word) []string {
+	result := intervalConditions(name, intervalsOf(w), maxWord)
+	switch {
+	case w.Set == 0:
+	case w.Set&(w.Set-1) == 0:
+		result = append(result, fmt.Sprintf("%s &? %s", name, number(uint64(w.Set))))
+	default:
+		result = append(result, fmt.Sprintf("%s & %s == %s", name, number(uint64(w.Set)), number(uint64(w.Set))))
+	}
+	if w.Clear != 0 {
+		result = append(result, fmt.Sprintf("%s & %s == 0", name, number(uint64(w.Clear))))
+	}
+	return result
+}
+
+// intervalConditions describes the values of the intervals in the simplest way it can find
+func intervalConditions(name string, ivs []interval, max uint64) []string {
+	if len(ivs) == 1 {
+		i := ivs[0]
+		switch {
+		case i.lo == 0 && i.hi == max:
+			return nil
+		case i.lo == i.hi:
+			return []string{fmt.Sprintf("%s == %s", name, number(i.lo))}
+		case i.lo == 0:
+			return []string{fmt.Sprintf("%s <= %s", name, number(i.hi))}
+		case i.hi == max:
+			return []string{fmt.Sprintf("%s >= %s", name, number(i.lo))}
+		}
+		return []string{fmt.Sprintf("%s >= %s", name, number(i.lo)), fmt.Sprintf("%s <= %s", name, number(i.hi))}
+	}
+
+	if values, ok := singletons(ivs); ok {
+		return []string{fmt.Sprintf("in(%s, %s)", name, strings.Join(values, ", "))}
+	}
+
+	if missing, ok := singletons(gaps(ivs, max)); ok {
+		if len(missing) == 1 {
+			return []string{fmt.Sprintf("%s != %s", name, missing[0])}
+		}
+		return []string{fmt.Sprintf("notIn(%s, %s)", name, strings.Join(missing, ", "))}
+	}
+
+	alternatives := []string{}
+	for _, i := range ivs {
+		alternatives = append(alternatives, strings.Join(intervalConditions(name, []interval{i}, max), " && "))
+	}
+	return []string{"(" + strings.Join(alternatives, " || ") + ")"}
+}
+
+func singletons(ivs []interval) ([]string, bool) {
+	result := []string{}
+	for _, i := range ivs {
+		if i.lo != i.hi {
+			return nil, false
+		}
+		result = append(result, number(i.lo))
+	}
+	return result, len(result) > 0
+}
+
+// gaps returns the values between 0 and max that are not in the intervals
+func gaps(ivs []interval, max uint64) []interval {
+	result := []interval{}
+	next, done := uint64(0), false
+	for _, i := range ivs {
+		if i.lo > next {
+			result = append(result, interval{next, i.lo - 1})
+		}
+		if i.hi == max {
+			done = true
+			break
+		}
+		next = i.hi + 1
+	}
+	if !done {
+		result = append(result, interval{next, max})
+	}
+	return result
+}
+
+func number(v uint64) string {
+	if v < 10 {
+		return fmt.Sprintf("%d", v)
+	}
+	return fmt.Sprintf("0x%X", v)
+}
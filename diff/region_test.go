@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"github.com/twtiger/gosecco/equivalence"
+
+	. "gopkg.in/check.v1"
+)
+
+type RegionSuite struct{}
+
+var _ = Suite(&RegionSuite{})
+
+func word(set, clear uint32, ivs ...uint32) equivalence.Word {
+	w := equivalence.Word{Set: set, Clear: clear}
+	for i := 0; i < len(ivs); i += 2 {
+		w.Intervals = append(w.Intervals, equivalence.Interval{Lo: ivs[i], Hi: ivs[i+1]})
+	}
+	return w
+}
+
+var any = word(0, 0, 0, 0xFFFFFFFF)
+
+func (s *RegionSuite) Test_describesIntervals(c *C) {
+	cases := []struct {
+		ivs      []interval
+		expected []string
+	}{
+		{[]interval{{0, maxWord}}, nil},
+		{[]interval{{5, 5}}, []string{"x == 5"}},
+		{[]interval{{0, 0x10}}, []string{"x <= 0x10"}},
+		{[]interval{{0x10, maxWord}}, []string{"x >= 0x10"}},
+		{[]interval{{3, 7}}, []string{"x >= 3", "x <= 7"}},
+		{[]interval{{1, 1}, {4, 4}}, []string{"in(x, 1, 4)"}},
+		{[]interval{{0, 4}, {6, maxWord}}, []string{"x != 5"}},
+		{[]interval{{0, 4}, {6, 6}, {8, maxWord}}, []string{"notIn(x, 5, 7)"}},
+		{[]interval{{0, 4}, {8, 9}}, []string{"(x <= 4 || x >= 8 && x <= 9)"}},
+	}
+
+	for _, t := range cases {
+		c.Assert(intervalConditions("x", t.ivs, maxWord), DeepEquals, t.expected, Commentf("%v", t.ivs))
+	}
+}
+
+func (s *RegionSuite) Test_describesWholeArgumentsWhenPossible(c *C) {
+	c.Assert(doubleConditions("arg0", "argL0", "argH0", word(0, 0, 1, 1), word(0, 0, 0, 0)), DeepEquals, []string{"arg0 == 1"})
+	c.Assert(doubleConditions("arg0", "argL0", "argH0", any, word(0, 0, 1, 0xFFFFFFFF)), DeepEquals, []string{"arg0 >= 0x100000000"})
+	c.Assert(doubleConditions("arg0", "argL0", "argH0", any, word(0, 0, 0, 0)), DeepEquals, []string{"arg0 <= 0xFFFFFFFF"})
+	c.Assert(doubleConditions("arg0", "argL0", "argH0", word(6, 1, 0, 0xFFFFFFFF), word(0, 0, 0, 0)), DeepEquals,
+		[]string{"argL0 & 6 == 6", "argL0 & 1 == 0", "argH0 == 0"})
+	c.Assert(doubleConditions("arg0", "argL0", "argH0", any, any), HasLen, 0)
+}
+
+func (s *RegionSuite) Test_mergesRegionsDifferingInOneWord(c *C) {
+	a, b, other := region{}, region{}, region{}
+	for i := range a {
+		a[i], b[i], other[i] = any, any, any
+	}
+	a[4], b[4] = word(0, 0, 0, 4), word(0, 0, 5, 9)
+	other[4], other[6] = word(0, 0, 20, 20), word(2, 0, 0, 0xFFFFFFFF)
+
+	merged := mergeRegions([]region{a, other, b})
+	c.Assert(merged, HasLen, 2)
+	c.Assert(merged[0].conditions(), DeepEquals, []string{"argL0 <= 9"})
+	c.Assert(merged[1].conditions(), DeepEquals, []string{"argL0 == 0x14", "argL1 &? 2"})
+}
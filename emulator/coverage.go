@@ -0,0 +1,56 @@
+package emulator
+
+import (
+	"syscall"
+
+	"github.com/twtiger/gosecco/data"
+
+	"golang.org/x/sys/unix"
+)
+
+// Coverage counts how often every instruction of a program was executed, and how often every
+// conditional jump was taken or not, over any number of executions
+type Coverage struct {
+	Executed []int
+	Taken    []int
+	NotTaken []int
+}
+
+// NewCoverage creates empty coverage for the given program
+func NewCoverage(filters []unix.SockFilter) *Coverage {
+	return &Coverage{
+		Executed: make([]int, len(filters)),
+		Taken:    make([]int, len(filters)),
+		NotTaken: make([]int, len(filters)),
+	}
+}
+
+func isConditionalJump(s unix.SockFilter) bool {
+	return bpfClass(s.Code) == syscall.BPF_JMP && bpfOp(s.Code) != syscall.BPF_JA
+}
+
+// EmulateWithCoverage works the same as Emulate, but also adds the instructions executed to the coverage, which
+// must have been created for the same program. A conditional jump with the same target for both outcomes counts as taken
+func EmulateWithCoverage(d data.SeccompWorkingMemory, filters []unix.SockFilter, c *Coverage) uint32 {
+	e := &emulator{data: d, filters: filters, pointer: 0}
+	for {
+		pc := e.pointer
+		if pc < uint32(len(filters)) {
+			c.Executed[pc]++
+		}
+		val, finished, err := e.next()
+		if err != nil {
+			panic(err.Error())
+		}
+		if finished {
+			return val
+		}
+		if s := filters[pc]; isConditionalJump(s) {
+			if e.pointer == pc+1+uint32(s.Jt) {
+				c.Taken[pc]++
+			} else {
+				c.NotTaken[pc]++
+			}
+		}
+	}
+}
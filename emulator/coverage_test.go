@@ -0,0 +1,23 @@
+package emulator
+
+import (
+	"github.com/twtiger/gosecco/data"
+
+	. "gopkg.in/check.v1"
+)
+
+type CoverageSuite struct{}
+
+var _ = Suite(&CoverageSuite{})
+
+func (s *CoverageSuite) Test_EmulateWithCoverage_countsInstructionsAndBranches(c *C) {
+	cov := NewCoverage(traceProgram)
+
+	c.Assert(EmulateWithCoverage(data.SeccompWorkingMemory{Args: [6]uint64{42}}, traceProgram, cov), Equals, uint32(1))
+	c.Assert(EmulateWithCoverage(data.SeccompWorkingMemory{Args: [6]uint64{42}}, traceProgram, cov), Equals, uint32(1))
+	c.Assert(EmulateWithCoverage(data.SeccompWorkingMemory{Args: [6]uint64{1}}, traceProgram, cov), Equals, uint32(2))
+
+	c.Assert(cov.Executed, DeepEquals, []int{3, 3, 3, 2, 1})
+	c.Assert(cov.Taken, DeepEquals, []int{0, 0, 2, 0, 0})
+	c.Assert(cov.NotTaken, DeepEquals, []int{0, 0, 1, 0, 0})
+}
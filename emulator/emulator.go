@@ -15,16 +15,50 @@ func init() {
 }
 
 // Emulate will execute a seccomp filter program against the given working memory.
+// It panics if the program contains invalid instructions - use EmulateChecked to get an error instead.
 func Emulate(d data.SeccompWorkingMemory, filters []unix.SockFilter) uint32 {
 	e := &emulator{data: d, filters: filters, pointer: 0}
 	for {
-		val, finished := e.next()
+		val, finished, err := e.next()
+		if err != nil {
+			panic(err.Error())
+		}
 		if finished {
 			return val
 		}
 	}
 }
 
+// EmulationError describes why the execution of a filter program failed
+type EmulationError struct {
+	PC     uint32
+	Reason string
+}
+
+func (e *EmulationError) Error() string {
+	return fmt.Sprintf("instruction %d: %s", e.PC, e.Reason)
+}
+
+// EmulateChecked works the same as Emulate, but returns an error instead of panicking on invalid instructions.
+// Since the kernel never lets a program run past its last instruction, that is an error as well, instead of returning 0.
+// If maxSteps is larger than zero, the execution will fail after executing that many instructions without returning.
+func EmulateChecked(d data.SeccompWorkingMemory, filters []unix.SockFilter, maxSteps int) (uint32, error) {
+	e := &emulator{data: d, filters: filters, pointer: 0}
+	for steps := 0; maxSteps <= 0 || steps < maxSteps; steps++ {
+		if e.pointer >= uint32(len(e.filters)) {
+			return 0, &EmulationError{PC: e.pointer, Reason: "ran past the end of the program"}
+		}
+		val, finished, err := e.next()
+		if err != nil {
+			return 0, err
+		}
+		if finished {
+			return val, nil
+		}
+	}
+	return 0, &EmulationError{PC: e.pointer, Reason: fmt.Sprintf("did not return within %d steps", maxSteps)}
+}
+
 type emulator struct {
 	data    data.SeccompWorkingMemory
 	filters []unix.SockFilter
@@ -58,16 +92,26 @@ func bpfSrc(code uint16) uint16 {
 	return code & 0x08
 }
 
-func (e *emulator) execRet(current unix.SockFilter) (uint32, bool) {
-	switch bpfSrc(current.Code) {
+func bpfRval(code uint16) uint16 {
+	return code & 0x18
+}
+
+// fail creates an error for the instruction currently being executed
+func (e *emulator) fail(format string, args ...interface{}) (uint32, bool, error) {
+	return 0, true, &EmulationError{PC: e.pointer - 1, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *emulator) execRet(current unix.SockFilter) (uint32, bool, error) {
+	switch bpfRval(current.Code) {
 	case syscall.BPF_K:
-		return current.K, true
+		return current.K, true, nil
 	case syscall.BPF_X:
-		return e.X, true
+		return e.X, true, nil
+	case syscall.BPF_A:
+		return e.A, true, nil
 	default:
-		panic(fmt.Sprintf("Invalid ret source: %d", bpfSrc(current.Code)))
+		return e.fail("Invalid ret source: %d", bpfRval(current.Code))
 	}
-	return 0, true
 }
 
 func (e *emulator) getFromWorkingMemory(ix uint32) uint32 {
@@ -77,9 +121,9 @@ func (e *emulator) getFromWorkingMemory(ix uint32) uint32 {
 	case 4:
 		return e.data.Arch
 	case 8:
-		return uint32(e.data.InstructionPointer >> 32)
+		return uint32(e.data.InstructionPointer & 0xFFFFFFFF)
 	case 12:
-		return uint32(e.data.InstructionPointer & 0xFFFF)
+		return uint32(e.data.InstructionPointer >> 32)
 	case 16:
 		return uint32(e.data.Args[0] & 0xFFFFFFFF)
 	case 20:
@@ -109,43 +153,38 @@ func (e *emulator) getFromWorkingMemory(ix uint32) uint32 {
 	}
 }
 
-func (e *emulator) loadFromWorkingMemory(ix uint32) {
-	e.A = e.getFromWorkingMemory(ix)
-}
-
-func (e *emulator) execLd(current unix.SockFilter) (uint32, bool) {
+func (e *emulator) execLd(current unix.SockFilter) (uint32, bool, error) {
 	cd := current.Code
 
 	if bpfSize(cd) != syscall.BPF_W {
-		panic("Invalid code, we can't load smaller values than wide ones")
+		return e.fail("Invalid code, we can't load smaller values than wide ones")
 	}
 
 	switch bpfMode(cd) {
 	case syscall.BPF_ABS:
-		e.loadFromWorkingMemory(current.K)
+		e.A = e.getFromWorkingMemory(current.K)
 	case syscall.BPF_IND:
-		e.loadFromWorkingMemory(e.X + current.K)
+		e.A = e.getFromWorkingMemory(e.X + current.K)
 	case syscall.BPF_LEN:
 		e.A = uint32(64)
 	case syscall.BPF_IMM:
 		e.A = current.K
 	case syscall.BPF_MEM:
-		if current.K < syscall.BPF_MEMWORDS {
-			e.A = e.M[current.K]
-		} else {
-			panic(fmt.Sprintf("Index out of range: %d greater than MEMWORDS %d", current.K, syscall.BPF_MEMWORDS))
+		if current.K >= syscall.BPF_MEMWORDS {
+			return e.fail("Index out of range: %d greater than MEMWORDS %d", current.K, syscall.BPF_MEMWORDS)
 		}
+		e.A = e.M[current.K]
 	default:
-		panic(fmt.Sprintf("Invalid mode: %d", bpfMode(cd)))
+		return e.fail("Invalid mode: %d", bpfMode(cd))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
-func (e *emulator) execLdx(current unix.SockFilter) (uint32, bool) {
+func (e *emulator) execLdx(current unix.SockFilter) (uint32, bool, error) {
 	cd := current.Code
 
 	if bpfSize(cd) != syscall.BPF_W {
-		panic("Invalid code, we can't load smaller values than wide ones")
+		return e.fail("Invalid code, we can't load smaller values than wide ones")
 	}
 
 	switch bpfMode(cd) {
@@ -154,15 +193,14 @@ func (e *emulator) execLdx(current unix.SockFilter) (uint32, bool) {
 	case syscall.BPF_IMM:
 		e.X = current.K
 	case syscall.BPF_MEM:
-		if current.K < syscall.BPF_MEMWORDS {
-			e.X = e.M[current.K]
-		} else {
-			panic(fmt.Sprintf("Index out of range: %d greater than MEMWORDS", current.K, syscall.BPF_MEMWORDS))
+		if current.K >= syscall.BPF_MEMWORDS {
+			return e.fail("Index out of range: %d greater than MEMWORDS %d", current.K, syscall.BPF_MEMWORDS)
 		}
+		e.X = e.M[current.K]
 	default:
-		panic(fmt.Sprintf("Invalid mode: %d", bpfMode(cd)))
+		return e.fail("Invalid mode: %d", bpfMode(cd))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
 // BPF_MOD is BPF_MOD - it is supported in Linux from v3.7+, but not in go's syscall...
@@ -171,18 +209,17 @@ const BPF_MOD = 0x90
 // BPF_XOR is BPF_XOR - it is supported in Linux from v3.7+, but not in go's syscall...
 const BPF_XOR = 0xa0
 
-func (e *emulator) execAlu(current unix.SockFilter) (uint32, bool) {
+func (e *emulator) execAlu(current unix.SockFilter) (uint32, bool, error) {
 	cd := current.Code
 
-	right := uint32(0)
-
-	switch bpfSrc(cd) {
-	case syscall.BPF_K:
-		right = current.K
-	case syscall.BPF_X:
+	right := current.K
+	if bpfSrc(cd) == syscall.BPF_X {
 		right = e.X
-	default:
-		panic(fmt.Sprintf("Invalid source for right hand side of operation: %d", bpfSrc(cd)))
+	}
+
+	if (bpfOp(cd) == syscall.BPF_DIV || bpfOp(cd) == BPF_MOD) && right == 0 {
+		// The kernel stops the program and returns 0 when dividing by zero
+		return 0, true, nil
 	}
 
 	switch bpfOp(cd) {
@@ -209,12 +246,12 @@ func (e *emulator) execAlu(current unix.SockFilter) (uint32, bool) {
 	case syscall.BPF_NEG:
 		e.A = -e.A
 	default:
-		panic(fmt.Sprintf("Invalid op: %d", bpfOp(cd)))
+		return e.fail("Invalid op: %d", bpfOp(cd))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
-func (e *emulator) execMisc(current unix.SockFilter) (uint32, bool) {
+func (e *emulator) execMisc(current unix.SockFilter) (uint32, bool, error) {
 	cd := current.Code
 
 	switch bpfMiscOp(cd) {
@@ -223,22 +260,17 @@ func (e *emulator) execMisc(current unix.SockFilter) (uint32, bool) {
 	case syscall.BPF_TXA:
 		e.A = e.X
 	default:
-		panic(fmt.Sprintf("Invalid op: %d", bpfMiscOp(cd)))
+		return e.fail("Invalid op: %d", bpfMiscOp(cd))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
-func (e *emulator) execJmp(current unix.SockFilter) (uint32, bool) {
+func (e *emulator) execJmp(current unix.SockFilter) (uint32, bool, error) {
 	cd := current.Code
 
-	right := uint32(0)
-	switch bpfSrc(cd) {
-	case syscall.BPF_K:
-		right = current.K
-	case syscall.BPF_X:
+	right := current.K
+	if bpfSrc(cd) == syscall.BPF_X {
 		right = e.X
-	default:
-		panic(fmt.Sprintf("Invalid source for right hand side of operation: %d", bpfSrc(cd)))
 	}
 
 	switch bpfOp(cd) {
@@ -269,28 +301,28 @@ func (e *emulator) execJmp(current unix.SockFilter) (uint32, bool) {
 			e.pointer += uint32(current.Jf)
 		}
 	default:
-		panic(fmt.Sprintf("Invalid op: %d", bpfOp(cd)))
+		return e.fail("Invalid op: %d", bpfOp(cd))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
-func (e *emulator) execStore(current unix.SockFilter) (uint32, bool) {
-	switch bpfClass(current.Code) {
-	case syscall.BPF_ST:
+func (e *emulator) execStore(current unix.SockFilter) (uint32, bool, error) {
+	if current.K >= syscall.BPF_MEMWORDS {
+		return e.fail("Index out of range: %d greater than MEMWORDS %d", current.K, syscall.BPF_MEMWORDS)
+	}
+	if bpfClass(current.Code) == syscall.BPF_ST {
 		e.M[current.K] = e.A
-		return 0, false
-	case syscall.BPF_STX:
+	} else {
 		e.M[current.K] = e.X
-		return 0, false
-	default:
-		panic(fmt.Sprintf("Invalid op: %d", bpfClass(current.Code)))
 	}
-	return 0, false
+	return 0, false, nil
 }
 
-func (e *emulator) next() (uint32, bool) {
+// next executes the next instruction. It returns true if the program has finished, together with the
+// result, or an error if the instruction is invalid
+func (e *emulator) next() (uint32, bool, error) {
 	if e.pointer >= uint32(len(e.filters)) {
-		return 0, true
+		return 0, true, nil
 	}
 
 	current := e.filters[e.pointer]
@@ -314,5 +346,5 @@ func (e *emulator) next() (uint32, bool) {
 		return e.execStore(current)
 	}
 
-	return 0, true
+	return e.fail("Invalid class: %d", bpfClass(current.Code))
 }
@@ -41,7 +41,7 @@ func (s *EmulatorSuite) Test_simpleReturnX(c *C) {
 		X: uint32(23),
 	}
 
-	res, _ := e.next()
+	res, _, _ := e.next()
 
 	c.Assert(res, Equals, uint32(23))
 }
@@ -128,6 +128,19 @@ func loadAbs(k uint32) unix.SockFilter {
 	}
 }
 
+func (s *EmulatorSuite) Test_loadsTheLowHalfOfTheInstructionPointerFirst(c *C) {
+	e := &emulator{
+		data:    data.SeccompWorkingMemory{InstructionPointer: 0x1122334455667788},
+		filters: []unix.SockFilter{loadAbs(8), loadAbs(12)},
+	}
+
+	e.next()
+	c.Assert(e.A, Equals, uint32(0x55667788))
+
+	e.next()
+	c.Assert(e.A, Equals, uint32(0x11223344))
+}
+
 func (s *EmulatorSuite) Test_loadWorkingMemory(c *C) {
 	e := &emulator{
 		data: data.SeccompWorkingMemory{NR: 15, InstructionPointer: 45365364654, Arch: 15, Args: [6]uint64{123234, 5465645, 12132, 12423423, 7766, 12124}},
@@ -160,10 +173,10 @@ func (s *EmulatorSuite) Test_loadWorkingMemory(c *C) {
 	c.Assert(e.A, Equals, uint32(0xF))
 
 	e.next()
-	c.Assert(e.A, Equals, uint32(0xA))
+	c.Assert(e.A, Equals, uint32(0x8FFC87AE))
 
 	e.next()
-	c.Assert(e.A, Equals, uint32(0x87AE))
+	c.Assert(e.A, Equals, uint32(0xA))
 
 	e.next()
 	c.Assert(e.A, Equals, uint32(0x1E162))
@@ -617,3 +630,66 @@ func (s *EmulatorSuite) Test_storeXInScratchMemory(c *C) {
 
 	c.Assert(e.M[1], Equals, uint32(4))
 }
+
+func (s *EmulatorSuite) Test_returnA(c *C) {
+	res, err := EmulateChecked(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 42},
+		unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_A},
+	}, 0)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, uint32(42))
+}
+
+func (s *EmulatorSuite) Test_divisionByZeroReturnsZeroLikeTheKernel(c *C) {
+	for _, op := range []uint16{syscall.BPF_DIV, BPF_MOD} {
+		filters := []unix.SockFilter{
+			unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 42},
+			unix.SockFilter{Code: syscall.BPF_ALU | op | syscall.BPF_X},
+			unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: 0x7FFF0000},
+		}
+		res, err := EmulateChecked(data.SeccompWorkingMemory{}, filters, 0)
+		c.Assert(err, IsNil)
+		c.Assert(res, Equals, uint32(0))
+		c.Assert(Emulate(data.SeccompWorkingMemory{}, filters), Equals, uint32(0))
+	}
+}
+
+func (s *EmulatorSuite) Test_emulateCheckedReturnsErrorsForInvalidInstructions(c *C) {
+	_, err := EmulateChecked(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 42},
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_B | syscall.BPF_ABS, K: 0},
+		unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K},
+	}, 0)
+	c.Assert(err, DeepEquals, &EmulationError{PC: 1, Reason: "Invalid code, we can't load smaller values than wide ones"})
+
+	_, err = EmulateChecked(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_ST, K: 16},
+	}, 0)
+	c.Assert(err, ErrorMatches, "instruction 0: Index out of range: 16 greater than MEMWORDS 16")
+
+	c.Assert(func() {
+		Emulate(data.SeccompWorkingMemory{}, []unix.SockFilter{unix.SockFilter{Code: syscall.BPF_MISC | 0x20}})
+	}, PanicMatches, "instruction 0: Invalid op: 32")
+}
+
+func (s *EmulatorSuite) Test_emulateCheckedFailsWhenRunningPastTheEnd(c *C) {
+	_, err := EmulateChecked(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 42},
+	}, 0)
+	c.Assert(err, ErrorMatches, "instruction 1: ran past the end of the program")
+}
+
+func (s *EmulatorSuite) Test_emulateCheckedStopsAfterTheStepBudget(c *C) {
+	loop := []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 42},
+		unix.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JA, K: 0xFFFFFFFF},
+	}
+	_, err := EmulateChecked(data.SeccompWorkingMemory{}, loop, 1000)
+	c.Assert(err, ErrorMatches, "instruction 1: did not return within 1000 steps")
+
+	res, err := EmulateChecked(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: 1},
+	}, 1)
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, uint32(1))
+}
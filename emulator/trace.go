@@ -0,0 +1,93 @@
+package emulator
+
+import (
+	"syscall"
+
+	"github.com/twtiger/gosecco/data"
+
+	"golang.org/x/sys/unix"
+)
+
+// Registers contains the state of the BPF machine between instructions
+type Registers struct {
+	A uint32
+	X uint32
+	M [syscall.BPF_MEMWORDS]uint32
+}
+
+// Step describes the execution of one instruction
+type Step struct {
+	PC          uint32
+	Instruction unix.SockFilter
+	Before      Registers
+	After       Registers
+}
+
+// Execution allows running a filter program one instruction at a time, while
+// inspecting the state of the machine between the instructions
+type Execution struct {
+	e        *emulator
+	finished bool
+	result   uint32
+	err      error
+}
+
+// NewExecution prepares the filter program for execution against the given working memory
+func NewExecution(d data.SeccompWorkingMemory, filters []unix.SockFilter) *Execution {
+	return &Execution{e: &emulator{data: d, filters: filters, pointer: 0}}
+}
+
+// PC returns the index of the next instruction to execute
+func (x *Execution) PC() uint32 {
+	return x.e.pointer
+}
+
+// Registers returns the current state of the machine
+func (x *Execution) Registers() Registers {
+	return Registers{A: x.e.A, X: x.e.X, M: x.e.M}
+}
+
+// Result returns the value the program returned, and whether it has finished
+func (x *Execution) Result() (uint32, bool) {
+	return x.result, x.finished
+}
+
+// Err returns the error that stopped the execution, if an invalid instruction was executed
+func (x *Execution) Err() error {
+	return x.err
+}
+
+// Step executes the next instruction and returns what happened. It returns false if the program
+// had already finished, in which case nothing will be executed. Running past the end of the program
+// finishes it with the result 0, in the same way as Emulate. An invalid instruction finishes the execution
+// with an error, available from Err
+func (x *Execution) Step() (Step, bool) {
+	if x.finished {
+		return Step{}, false
+	}
+
+	pc := x.e.pointer
+	s := Step{PC: pc, Before: x.Registers()}
+	if pc < uint32(len(x.e.filters)) {
+		s.Instruction = x.e.filters[pc]
+	}
+	x.result, x.finished, x.err = x.e.next()
+	s.After = x.Registers()
+	return s, true
+}
+
+// EmulateWithTrace works the same as Emulate, but also returns every instruction executed, in order
+func EmulateWithTrace(d data.SeccompWorkingMemory, filters []unix.SockFilter) (uint32, []Step) {
+	x := NewExecution(d, filters)
+	trace := []Step{}
+	for {
+		s, ok := x.Step()
+		if !ok {
+			if x.err != nil {
+				panic(x.err.Error())
+			}
+			return x.result, trace
+		}
+		trace = append(trace, s)
+	}
+}
@@ -0,0 +1,78 @@
+package emulator
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/twtiger/gosecco/data"
+
+	. "gopkg.in/check.v1"
+)
+
+type TraceSuite struct{}
+
+var _ = Suite(&TraceSuite{})
+
+var traceProgram = []unix.SockFilter{
+	unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: 16},
+	unix.SockFilter{Code: syscall.BPF_ST, K: 3},
+	unix.SockFilter{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 0, Jf: 1, K: 42},
+	unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: 1},
+	unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_K, K: 2},
+}
+
+func (s *TraceSuite) Test_EmulateWithTrace_recordsEveryInstructionWithRegisters(c *C) {
+	res, trace := EmulateWithTrace(data.SeccompWorkingMemory{Args: [6]uint64{42}}, traceProgram)
+
+	c.Assert(res, Equals, uint32(1))
+	c.Assert(len(trace), Equals, 4)
+	c.Assert(trace[0].PC, Equals, uint32(0))
+	c.Assert(trace[0].Before.A, Equals, uint32(0))
+	c.Assert(trace[0].After.A, Equals, uint32(42))
+	c.Assert(trace[1].Instruction, Equals, traceProgram[1])
+	c.Assert(trace[1].Before.M[3], Equals, uint32(0))
+	c.Assert(trace[1].After.M[3], Equals, uint32(42))
+	c.Assert(trace[3].PC, Equals, uint32(3))
+}
+
+func (s *TraceSuite) Test_Execution_canBeSteppedOneInstructionAtATime(c *C) {
+	x := NewExecution(data.SeccompWorkingMemory{Args: [6]uint64{41}}, traceProgram)
+
+	x.Step()
+	x.Step()
+	c.Assert(x.PC(), Equals, uint32(2))
+	c.Assert(x.Registers().A, Equals, uint32(41))
+	_, finished := x.Result()
+	c.Assert(finished, Equals, false)
+
+	x.Step()
+	c.Assert(x.PC(), Equals, uint32(4))
+	st, ok := x.Step()
+	c.Assert(ok, Equals, true)
+	c.Assert(st.PC, Equals, uint32(4))
+
+	res, finished := x.Result()
+	c.Assert(finished, Equals, true)
+	c.Assert(res, Equals, uint32(2))
+
+	_, ok = x.Step()
+	c.Assert(ok, Equals, false)
+}
+
+func (s *TraceSuite) Test_Execution_stopsWithAnErrorOnInvalidInstructions(c *C) {
+	x := NewExecution(data.SeccompWorkingMemory{}, []unix.SockFilter{
+		unix.SockFilter{Code: syscall.BPF_LD | syscall.BPF_IMM, K: 1},
+		unix.SockFilter{Code: syscall.BPF_LDX | syscall.BPF_MEM, K: 20},
+	})
+
+	x.Step()
+	c.Assert(x.Err(), IsNil)
+	x.Step()
+	c.Assert(x.Err(), ErrorMatches, "instruction 1: Index out of range: 20 greater than MEMWORDS 16")
+
+	_, finished := x.Result()
+	c.Assert(finished, Equals, true)
+	_, ok := x.Step()
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,188 @@
+package emulator
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxInstructions is BPF_MAXINSNS, the largest program the kernel will accept
+const maxInstructions = 4096
+
+// seccompDataSize is the size of struct seccomp_data
+const seccompDataSize = 64
+
+// VerificationError describes the first reason the kernel would refuse to install a filter program.
+// PC is the index of the offending instruction, or -1 if the problem is with the program as a whole
+type VerificationError struct {
+	PC     int
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	if e.PC < 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("instruction %d: %s", e.PC, e.Reason)
+}
+
+func invalidAt(pc int, format string, args ...interface{}) error {
+	return &VerificationError{PC: pc, Reason: fmt.Sprintf(format, args...)}
+}
+
+// classicCodes contains all instructions known by classic BPF, as listed in
+// the codes table in net/core/filter.c
+var classicCodes = map[uint16]bool{}
+
+// seccompCodes contains the instructions seccomp_check_filter in kernel/seccomp.c allows.
+// Notably, this excludes BPF_MOD, BPF_IND and BPF_MSH loads and all loads narrower than a word
+var seccompCodes = map[uint16]bool{}
+
+func init() {
+	for _, op := range []uint16{syscall.BPF_ADD, syscall.BPF_SUB, syscall.BPF_MUL, syscall.BPF_DIV, BPF_MOD,
+		syscall.BPF_AND, syscall.BPF_OR, BPF_XOR, syscall.BPF_LSH, syscall.BPF_RSH} {
+		classicCodes[syscall.BPF_ALU|op|syscall.BPF_K] = true
+		classicCodes[syscall.BPF_ALU|op|syscall.BPF_X] = true
+		if op != BPF_MOD {
+			seccompCodes[syscall.BPF_ALU|op|syscall.BPF_K] = true
+			seccompCodes[syscall.BPF_ALU|op|syscall.BPF_X] = true
+		}
+	}
+	for _, op := range []uint16{syscall.BPF_JEQ, syscall.BPF_JGE, syscall.BPF_JGT, syscall.BPF_JSET} {
+		for _, src := range []uint16{syscall.BPF_K, syscall.BPF_X} {
+			classicCodes[syscall.BPF_JMP|op|src] = true
+			seccompCodes[syscall.BPF_JMP|op|src] = true
+		}
+	}
+	for _, size := range []uint16{syscall.BPF_W, syscall.BPF_H, syscall.BPF_B} {
+		classicCodes[syscall.BPF_LD|size|syscall.BPF_ABS] = true
+		classicCodes[syscall.BPF_LD|size|syscall.BPF_IND] = true
+	}
+	for _, code := range []uint16{
+		syscall.BPF_ALU | syscall.BPF_NEG,
+		syscall.BPF_LD | syscall.BPF_W | syscall.BPF_LEN,
+		syscall.BPF_LD | syscall.BPF_IMM,
+		syscall.BPF_LD | syscall.BPF_MEM,
+		syscall.BPF_LDX | syscall.BPF_W | syscall.BPF_LEN,
+		syscall.BPF_LDX | syscall.BPF_IMM,
+		syscall.BPF_LDX | syscall.BPF_MEM,
+		syscall.BPF_ST,
+		syscall.BPF_STX,
+		syscall.BPF_MISC | syscall.BPF_TAX,
+		syscall.BPF_MISC | syscall.BPF_TXA,
+		syscall.BPF_RET | syscall.BPF_K,
+		syscall.BPF_RET | syscall.BPF_A,
+		syscall.BPF_JMP | syscall.BPF_JA,
+		syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS,
+	} {
+		classicCodes[code] = true
+		seccompCodes[code] = true
+	}
+	classicCodes[syscall.BPF_LDX|syscall.BPF_B|syscall.BPF_MSH] = true
+}
+
+// Verify checks a filter program the same way the kernel does before installing it as a seccomp filter,
+// following bpf_check_classic and check_load_and_stores in net/core/filter.c and seccomp_check_filter in
+// kernel/seccomp.c, in that order
+func Verify(filters []unix.SockFilter) error {
+	if err := checkClassic(filters); err != nil {
+		return err
+	}
+	if err := checkLoadAndStores(filters); err != nil {
+		return err
+	}
+	return checkSeccomp(filters)
+}
+
+func checkClassic(filters []unix.SockFilter) error {
+	flen := len(filters)
+	if flen == 0 {
+		return &VerificationError{PC: -1, Reason: "empty program"}
+	}
+	if flen > maxInstructions {
+		return &VerificationError{PC: -1, Reason: fmt.Sprintf("program too long: %d instructions (limit = %d)", flen, maxInstructions)}
+	}
+
+	for pc, f := range filters {
+		if !classicCodes[f.Code] {
+			return invalidAt(pc, "unknown instruction code 0x%02X", f.Code)
+		}
+
+		switch f.Code {
+		case syscall.BPF_ALU | syscall.BPF_DIV | syscall.BPF_K, syscall.BPF_ALU | BPF_MOD | syscall.BPF_K:
+			if f.K == 0 {
+				return invalidAt(pc, "division by constant zero")
+			}
+		case syscall.BPF_ALU | syscall.BPF_LSH | syscall.BPF_K, syscall.BPF_ALU | syscall.BPF_RSH | syscall.BPF_K:
+			if f.K >= 32 {
+				return invalidAt(pc, "shift by %d, which is not less than 32", f.K)
+			}
+		case syscall.BPF_LD | syscall.BPF_MEM, syscall.BPF_LDX | syscall.BPF_MEM, syscall.BPF_ST, syscall.BPF_STX:
+			if f.K >= syscall.BPF_MEMWORDS {
+				return invalidAt(pc, "scratch memory index %d out of range (limit = %d)", f.K, syscall.BPF_MEMWORDS)
+			}
+		case syscall.BPF_JMP | syscall.BPF_JA:
+			if uint64(f.K) >= uint64(flen-pc-1) {
+				return invalidAt(pc, "jump to %d is outside the program", uint64(pc)+1+uint64(f.K))
+			}
+		default:
+			if bpfClass(f.Code) == syscall.BPF_JMP {
+				if pc+int(f.Jt)+1 >= flen {
+					return invalidAt(pc, "true jump to %d is outside the program", pc+int(f.Jt)+1)
+				}
+				if pc+int(f.Jf)+1 >= flen {
+					return invalidAt(pc, "false jump to %d is outside the program", pc+int(f.Jf)+1)
+				}
+			}
+		}
+	}
+
+	switch filters[flen-1].Code {
+	case syscall.BPF_RET | syscall.BPF_K, syscall.BPF_RET | syscall.BPF_A:
+		return nil
+	}
+	return invalidAt(flen-1, "the last instruction is not a return")
+}
+
+// checkLoadAndStores makes sure no scratch memory is read before it has been written on every path leading to the read.
+// Since jumps can only go forward, this only needs one pass over the program
+func checkLoadAndStores(filters []unix.SockFilter) error {
+	masks := make([]uint16, len(filters))
+	for i := range masks {
+		masks[i] = 0xFFFF
+	}
+
+	memValid := uint16(0)
+	for pc, f := range filters {
+		memValid &= masks[pc]
+		switch {
+		case f.Code == syscall.BPF_ST || f.Code == syscall.BPF_STX:
+			memValid |= 1 << f.K
+		case f.Code == syscall.BPF_LD|syscall.BPF_MEM || f.Code == syscall.BPF_LDX|syscall.BPF_MEM:
+			if memValid&(1<<f.K) == 0 {
+				return invalidAt(pc, "M[%d] might be read before it has been written", f.K)
+			}
+		case f.Code == syscall.BPF_JMP|syscall.BPF_JA:
+			masks[pc+1+int(f.K)] &= memValid
+			memValid = 0xFFFF
+		case bpfClass(f.Code) == syscall.BPF_JMP:
+			masks[pc+1+int(f.Jt)] &= memValid
+			masks[pc+1+int(f.Jf)] &= memValid
+			memValid = 0xFFFF
+		}
+	}
+	return nil
+}
+
+func checkSeccomp(filters []unix.SockFilter) error {
+	for pc, f := range filters {
+		if !seccompCodes[f.Code] {
+			return invalidAt(pc, "instruction code 0x%02X is not allowed in seccomp filters", f.Code)
+		}
+		if f.Code == syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS && (f.K >= seccompDataSize || f.K&3 != 0) {
+			return invalidAt(pc, "load from offset %d, which is not an aligned offset inside seccomp_data", f.K)
+		}
+	}
+	return nil
+}
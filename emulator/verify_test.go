@@ -0,0 +1,87 @@
+package emulator
+
+import (
+	"github.com/twtiger/gosecco/asm"
+
+	"golang.org/x/sys/unix"
+
+	. "gopkg.in/check.v1"
+)
+
+type VerifySuite struct{}
+
+var _ = Suite(&VerifySuite{})
+
+func (s *VerifySuite) Test_acceptsAValidProgram(c *C) {
+	c.Assert(Verify(asm.MustParse(""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t05\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"st\t3\n"+
+		"ld_len\n"+
+		"ldx_mem\t3\n"+
+		"jeq_x\t00\t01\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n")), IsNil)
+}
+
+func (s *VerifySuite) Test_rejectsProgramsOfTheWrongSize(c *C) {
+	c.Assert(Verify(nil), ErrorMatches, "empty program")
+
+	tooLong := make([]unix.SockFilter, 4097)
+	c.Assert(Verify(tooLong), ErrorMatches, "program too long: 4097 instructions \\(limit = 4096\\)")
+}
+
+func (s *VerifySuite) Test_rejectsUnknownInstructions(c *C) {
+	c.Assert(Verify([]unix.SockFilter{{Code: 0xFFFF}}), ErrorMatches, "instruction 0: unknown instruction code 0xFFFF")
+	c.Assert(Verify(asm.MustParse("ret_x\n")), ErrorMatches, "instruction 0: unknown instruction code 0x0E")
+}
+
+func (s *VerifySuite) Test_rejectsProgramsThatDoNotEndWithAReturn(c *C) {
+	c.Assert(Verify(asm.MustParse("ld_abs\t0\n")), ErrorMatches, "instruction 0: the last instruction is not a return")
+}
+
+func (s *VerifySuite) Test_rejectsInvalidConstants(c *C) {
+	c.Assert(Verify(asm.MustParse("div_k\t0\nret_k\t0\n")), ErrorMatches, "instruction 0: division by constant zero")
+	c.Assert(Verify(asm.MustParse("lsh_k\t20\nret_k\t0\n")), ErrorMatches, "instruction 0: shift by 32, which is not less than 32")
+	c.Assert(Verify(asm.MustParse("st\t10\nret_k\t0\n")), ErrorMatches, "instruction 0: scratch memory index 16 out of range \\(limit = 16\\)")
+}
+
+func (s *VerifySuite) Test_rejectsJumpsOutsideTheProgram(c *C) {
+	c.Assert(Verify(asm.MustParse("jmp\t1\nret_k\t0\n")), ErrorMatches, "instruction 0: jump to 2 is outside the program")
+	c.Assert(Verify(asm.MustParse("jmp\tFFFFFFFF\nret_k\t0\n")), ErrorMatches, "instruction 0: jump to 4294967296 is outside the program")
+	c.Assert(Verify(asm.MustParse("jeq_k\t01\t00\t0\nret_k\t0\n")), ErrorMatches, "instruction 0: true jump to 2 is outside the program")
+	c.Assert(Verify(asm.MustParse("jeq_k\t00\t01\t0\nret_k\t0\n")), ErrorMatches, "instruction 0: false jump to 2 is outside the program")
+}
+
+func (s *VerifySuite) Test_rejectsReadsOfScratchMemoryBeforeAStore(c *C) {
+	c.Assert(Verify(asm.MustParse("ld_mem\t0\nret_k\t0\n")), ErrorMatches, "instruction 0: M\\[0\\] might be read before it has been written")
+
+	// M[1] is only written on one of the paths leading to the load
+	c.Assert(Verify(asm.MustParse(""+
+		"ld_abs\t0\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"st\t1\n"+
+		"ldx_mem\t1\n"+
+		"ret_k\t0\n")), ErrorMatches, "instruction 3: M\\[1\\] might be read before it has been written")
+
+	c.Assert(Verify(asm.MustParse(""+
+		"ld_abs\t0\n"+
+		"st\t1\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"st\t2\n"+
+		"ldx_mem\t1\n"+
+		"ret_k\t0\n")), IsNil)
+}
+
+func (s *VerifySuite) Test_rejectsInstructionsSeccompDoesNotAllow(c *C) {
+	c.Assert(Verify(asm.MustParse("ld_ind\t0\nret_k\t0\n")), ErrorMatches, "instruction 0: instruction code 0x40 is not allowed in seccomp filters")
+	c.Assert(Verify(asm.MustParse("mod_k\t2\nret_k\t0\n")), ErrorMatches, "instruction 0: instruction code 0x94 is not allowed in seccomp filters")
+	c.Assert(Verify([]unix.SockFilter{{Code: 0x30, K: 0}, {Code: 0x06}}), ErrorMatches, "instruction 0: instruction code 0x30 is not allowed in seccomp filters")
+}
+
+func (s *VerifySuite) Test_rejectsLoadsOutsideSeccompData(c *C) {
+	c.Assert(Verify(asm.MustParse("ld_abs\t3C\nret_k\t0\n")), IsNil)
+	c.Assert(Verify(asm.MustParse("ld_abs\t40\nret_k\t0\n")), ErrorMatches, "instruction 0: load from offset 64, which is not an aligned offset inside seccomp_data")
+	c.Assert(Verify(asm.MustParse("ld_abs\t6\nret_k\t0\n")), ErrorMatches, "instruction 0: load from offset 6, which is not an aligned offset inside seccomp_data")
+}
@@ -0,0 +1,169 @@
+package equivalence
+
+import "sort"
+
+const maxWord = 0xFFFFFFFF
+
+// interval is an inclusive range of 32 bit values
+type interval struct {
+	lo, hi uint32
+}
+
+// everything is the interval set containing all 32 bit values
+var everything = []interval{{0, maxWord}}
+
+// domain describes the values a word of seccomp_data can still have on one path through a program. A value
+// is in the domain if it is inside one of the intervals, has all the bits in set and none of the bits in clear.
+// The intervals are always sorted and never overlap
+type domain struct {
+	intervals  []interval
+	set, clear uint32
+}
+
+func unrestricted() domain {
+	return domain{intervals: everything}
+}
+
+func (d domain) export() Word {
+	result := Word{Intervals: make([]Interval, len(d.intervals)), Set: d.set, Clear: d.clear}
+	for i, in := range d.intervals {
+		result.Intervals[i] = Interval{in.lo, in.hi}
+	}
+	return result
+}
+
+// witness returns the smallest value in the domain, or false if the domain is empty
+func (d domain) witness() (uint32, bool) {
+	if d.set&d.clear != 0 {
+		return 0, false
+	}
+	for _, i := range d.intervals {
+		if v, ok := smallestMatching(i.lo, d.set, d.clear); ok && v <= i.hi {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// within returns the domain restricted to the given intervals
+func (d domain) within(s []interval) domain {
+	return domain{intervals: intersect(d.intervals, s), set: d.set, clear: d.clear}
+}
+
+// outside returns the domain without the given intervals
+func (d domain) outside(s []interval) domain {
+	return d.within(complement(s))
+}
+
+// withBits returns the domain restricted to values that have all the bits in set and none of the bits in clear
+func (d domain) withBits(set, clear uint32) domain {
+	return domain{intervals: d.intervals, set: d.set | set, clear: d.clear | clear}
+}
+
+// inside returns true if all the intervals of the domain are inside i
+func (d domain) inside(i interval) bool {
+	n := len(d.intervals)
+	return n == 0 || d.intervals[0].lo >= i.lo && d.intervals[n-1].hi <= i.hi
+}
+
+// disjoint returns true if none of the intervals of the domain overlap with i
+func (d domain) disjoint(i interval) bool {
+	ix := sort.Search(len(d.intervals), func(j int) bool { return d.intervals[j].hi >= i.lo })
+	return ix == len(d.intervals) || d.intervals[ix].lo > i.hi
+}
+
+// smallestMatching returns the smallest value not less than lo that has all the bits in set and none
+// of the bits in clear. The value keeps the bits of lo above some position, has a one where lo has a zero at
+// that position, and only the bits in set below it - so trying the positions from the lowest one gives the smallest value
+func smallestMatching(lo, set, clear uint32) (uint32, bool) {
+	if lo&set == set && lo&clear == 0 {
+		return lo, true
+	}
+
+	for i := uint(0); i < 32; i++ {
+		bit := uint32(1) << i
+		if lo&bit != 0 || clear&bit != 0 {
+			continue
+		}
+		above := ^(bit<<1 - 1)
+		if lo&above&clear != 0 || ^lo&above&set != 0 {
+			continue
+		}
+		return lo&above | bit | set&(bit-1), true
+	}
+	return 0, false
+}
+
+func intersect(a, b []interval) []interval {
+	result := []interval{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		lo, hi := a[i].lo, a[i].hi
+		if b[j].lo > lo {
+			lo = b[j].lo
+		}
+		if b[j].hi < hi {
+			hi = b[j].hi
+		}
+		if lo <= hi {
+			result = append(result, interval{lo, hi})
+		}
+		if a[i].hi < b[j].hi {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func complement(a []interval) []interval {
+	result := []interval{}
+	next := uint64(0)
+	for _, i := range a {
+		if uint64(i.lo) > next {
+			result = append(result, interval{uint32(next), i.lo - 1})
+		}
+		next = uint64(i.hi) + 1
+	}
+	if next <= maxWord {
+		result = append(result, interval{uint32(next), maxWord})
+	}
+	return result
+}
+
+// subtract returns the values v - k for all values v in the intervals, wrapping around in the same way as BPF arithmetic
+func subtract(a []interval, k uint32) []interval {
+	if k == 0 {
+		return a
+	}
+
+	low, high := []interval{}, []interval{}
+	for _, i := range a {
+		lo, hi := i.lo-k, i.hi-k
+		switch {
+		case i.lo >= k:
+			low = append(low, interval{lo, hi})
+		case i.hi < k:
+			high = append(high, interval{lo, hi})
+		default:
+			low = append(low, interval{0, hi})
+			high = append(high, interval{lo, maxWord})
+		}
+	}
+	return merge(append(low, high...))
+}
+
+// merge joins adjacent intervals in a sorted list
+func merge(a []interval) []interval {
+	result := []interval{}
+	for _, i := range a {
+		if n := len(result); n > 0 && result[n-1].hi != maxWord && result[n-1].hi+1 >= i.lo {
+			if i.hi > result[n-1].hi {
+				result[n-1].hi = i.hi
+			}
+			continue
+		}
+		result = append(result, i)
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package equivalence
+
+import . "gopkg.in/check.v1"
+
+type DomainSuite struct{}
+
+var _ = Suite(&DomainSuite{})
+
+func (s *DomainSuite) Test_smallestMatchingFindsTheSmallestValueWithTheBits(c *C) {
+	cases := []struct {
+		lo, set, clear uint32
+		expected       uint32
+		ok             bool
+	}{
+		{0, 0, 0, 0, true},
+		{5, 0, 0, 5, true},
+		{5, 2, 0, 6, true},
+		{5, 0, 1, 6, true},
+		{5, 0x10, 0, 0x10, true},
+		{0x11, 0x10, 0x1, 0x12, true},
+		{0x17, 0x8, 0, 0x18, true},
+		{0x80000001, 0x80000000, 0x1, 0x80000002, true},
+		{0x80000001, 0, 0x80000000, 0, false},
+		{0xFFFFFFFF, 0, 1, 0, false},
+	}
+
+	for _, t := range cases {
+		v, ok := smallestMatching(t.lo, t.set, t.clear)
+		c.Assert(ok, Equals, t.ok, Commentf("%#v", t))
+		if ok {
+			c.Assert(v, Equals, t.expected, Commentf("%#v", t))
+		}
+	}
+}
+
+func (s *DomainSuite) Test_witnessLooksInAllIntervals(c *C) {
+	d := domain{intervals: []interval{{1, 3}, {8, 9}, {16, 20}}, set: 0x10}
+	v, ok := d.witness()
+	c.Assert(ok, Equals, true)
+	c.Assert(v, Equals, uint32(16))
+
+	_, ok = d.withBits(0, 0x10).witness()
+	c.Assert(ok, Equals, false)
+
+	_, ok = unrestricted().withBits(4, 4).witness()
+	c.Assert(ok, Equals, false)
+}
+
+func (s *DomainSuite) Test_intervalOperations(c *C) {
+	c.Assert(complement([]interval{{0, 3}, {10, 10}}), DeepEquals, []interval{{4, 9}, {11, maxWord}})
+	c.Assert(complement(everything), DeepEquals, []interval{})
+	c.Assert(complement([]interval{}), DeepEquals, everything)
+
+	c.Assert(intersect([]interval{{0, 10}, {20, 30}}, []interval{{5, 25}}), DeepEquals, []interval{{5, 10}, {20, 25}})
+
+	c.Assert(subtract([]interval{{5, 10}}, 5), DeepEquals, []interval{{0, 5}})
+	c.Assert(subtract([]interval{{0, 0}, {5, 10}}, 5), DeepEquals, []interval{{0, 5}, {maxWord - 4, maxWord - 4}})
+	c.Assert(subtract([]interval{{3, 6}}, 5), DeepEquals, []interval{{0, 1}, {maxWord - 1, maxWord}})
+	c.Assert(subtract(everything, 7), DeepEquals, everything)
+}
@@ -0,0 +1,365 @@
+// Package equivalence decides whether two filter programs behave the same for every possible system call.
+// Instead of trying all inputs, it explores the programs symbolically: every word of seccomp_data starts out
+// unrestricted, and every conditional jump on a loaded word splits the possible values of that word into the
+// values that take the jump and the values that don't. All paths through the first program are then combined
+// with all paths through the second one, and if any combination is possible but returns different values, the
+// smallest working memory following that combination is the counterexample.
+//
+// The analysis understands comparisons and bit tests against loaded words, and words that have been masked
+// with a constant or had a constant added to them. Programs that compare two words with each other, or compare
+// the results of other arithmetic on the input, can not be analysed, and will result in an error instead of an answer.
+package equivalence
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxPaths is the largest number of path combinations that will be explored before giving up
+const maxPaths = 1 << 20
+
+// seccompDataWords is the number of 32 bit words in struct seccomp_data
+const seccompDataWords = 16
+
+// Counterexample describes working memory for which two programs return different values
+type Counterexample struct {
+	Memory      data.SeccompWorkingMemory
+	Left, Right uint32
+}
+
+// Check compares two filter programs for all possible working memory. It returns nil if the programs always
+// return the same value, and a counterexample otherwise. An error is returned if one of the programs would not
+// be accepted by the kernel, or uses arithmetic the analysis can't follow
+func Check(left, right []unix.SockFilter) (*Counterexample, error) {
+	var found state
+	err := compare(left, right, func(s state) error {
+		found = s
+		return errFound
+	})
+
+	if err == errFound {
+		res, err := counterexample(found, left, right)
+		if err != nil {
+			return nil, err
+		}
+		return &Counterexample{Memory: res.Memory, Left: res.Left, Right: res.Right}, nil
+	}
+	return nil, err
+}
+
+// errFound stops the exploration as soon as a difference has been found
+var errFound = errors.New("found a difference")
+
+// Interval is an inclusive range of 32 bit values
+type Interval struct {
+	Lo, Hi uint32
+}
+
+// Word describes the values a word of seccomp_data can have in a region: the value is inside one of the
+// intervals, has all the bits in Set and none of the bits in Clear. The intervals are sorted and never overlap
+type Word struct {
+	Intervals  []Interval
+	Set, Clear uint32
+}
+
+// Difference is a region of working memory where two programs return different values. The words are in the order
+// the emulator loads them: nr, arch, the high and low halves of the instruction pointer, and then the low and high
+// halves of each argument. Left and Right are the values returned for Memory, the smallest working memory in the
+// region - for programs that only return constants, they are the same for the whole region
+type Difference struct {
+	Words       [seccompDataWords]Word
+	Memory      data.SeccompWorkingMemory
+	Left, Right uint32
+}
+
+// Differences returns all the regions of working memory where the programs return different values. The
+// regions never overlap, and the programs are equivalent if there are none. The errors are the same as for Check
+func Differences(left, right []unix.SockFilter) ([]Difference, error) {
+	result := []Difference{}
+	err := compare(left, right, func(s state) error {
+		d, err := counterexample(s, left, right)
+		if err != nil {
+			return err
+		}
+		for i, w := range s.words {
+			d.Words[i] = w.export()
+		}
+		result = append(result, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// compare explores all combinations of paths through the two programs, and calls different for every
+// region of working memory where they return different values
+func compare(left, right []unix.SockFilter, different func(state) error) error {
+	if err := emulator.Verify(left); err != nil {
+		return fmt.Errorf("left program: %s", err)
+	}
+	if err := emulator.Verify(right); err != nil {
+		return fmt.Errorf("right program: %s", err)
+	}
+
+	c := &checker{}
+	l := &explorer{checker: c, name: "left", program: left}
+	r := &explorer{checker: c, name: "right", program: right}
+
+	return l.explore(initialState(), func(ls state, lres value) error {
+		return r.explore(ls.restart(), func(rs state, rres value) error {
+			c.paths++
+			if c.paths > maxPaths {
+				return fmt.Errorf("the programs have too many paths to explore (limit = %d)", maxPaths)
+			}
+
+			regions, err := rs.differing(lres, rres)
+			if err != nil {
+				return err
+			}
+			for _, d := range regions {
+				if err := different(d); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// counterexample returns the smallest working memory in the region, and makes sure the programs really differ for it
+func counterexample(s state, left, right []unix.SockFilter) (Difference, error) {
+	words := [seccompDataWords]uint32{}
+	for i, d := range s.words {
+		words[i], _ = d.witness()
+	}
+
+	mem := memoryFrom(words)
+	l, lerr := emulator.EmulateChecked(mem, left, 0)
+	r, rerr := emulator.EmulateChecked(mem, right, 0)
+	if lerr != nil || rerr != nil || l == r {
+		return Difference{}, fmt.Errorf("the counterexample %#v does not make the programs differ - this is likely a programmer error", mem)
+	}
+	return Difference{Memory: mem, Left: l, Right: r}, nil
+}
+
+// memoryFrom builds working memory with the given words, in the same layout the emulator uses
+func memoryFrom(words [seccompDataWords]uint32) data.SeccompWorkingMemory {
+	mem := data.SeccompWorkingMemory{
+		NR:                 int32(words[0]),
+		Arch:               words[1],
+		InstructionPointer: uint64(words[3])<<32 | uint64(words[2]),
+	}
+	for i := range mem.Args {
+		mem.Args[i] = uint64(words[5+2*i])<<32 | uint64(words[4+2*i])
+	}
+	return mem
+}
+
+type checker struct {
+	paths int
+}
+
+// explorer follows all the paths through one program
+type explorer struct {
+	*checker
+	name    string
+	program []unix.SockFilter
+}
+
+func (e *explorer) fail(pc int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s program, instruction %d: %s", e.name, pc, fmt.Sprintf(format, args...))
+}
+
+// explore executes the program from the start with the given state, and calls leaf with the final state
+// and the return value of every possible path
+func (e *explorer) explore(s state, leaf func(state, value) error) error {
+	return e.from(0, s, leaf)
+}
+
+func (e *explorer) from(pc int, s state, leaf func(state, value) error) error {
+	for ; pc < len(e.program); pc++ {
+		f := e.program[pc]
+		switch bpfClass(f.Code) {
+		case syscall.BPF_RET:
+			switch bpfRval(f.Code) {
+			case syscall.BPF_A:
+				return leaf(s, s.a)
+			case syscall.BPF_X:
+				return leaf(s, s.x)
+			}
+			return leaf(s, known(f.K))
+		case syscall.BPF_LD:
+			s.a = e.load(s, f)
+		case syscall.BPF_LDX:
+			s.x = e.load(s, f)
+		case syscall.BPF_ST:
+			s.m[f.K] = s.a
+		case syscall.BPF_STX:
+			s.m[f.K] = s.x
+		case syscall.BPF_MISC:
+			if bpfMiscOp(f.Code) == syscall.BPF_TAX {
+				s.x = s.a
+			} else {
+				s.a = s.x
+			}
+		case syscall.BPF_ALU:
+			res, divByZero, err := e.alu(pc, s, f)
+			if err != nil {
+				return err
+			}
+			if divByZero {
+				return leaf(s, known(0))
+			}
+			s.a = res
+		case syscall.BPF_JMP:
+			if bpfOp(f.Code) == syscall.BPF_JA {
+				pc += int(f.K)
+				continue
+			}
+
+			right := known(f.K)
+			if bpfSrc(f.Code) == syscall.BPF_X {
+				right = s.x
+			}
+			sp, err := s.branch(bpfOp(f.Code), s.a, right)
+			if err != nil {
+				return e.fail(pc, "%s", err)
+			}
+			if err := e.each(pc+1+int(f.Jt), s, sp.word, sp.taken, leaf); err != nil {
+				return err
+			}
+			return e.each(pc+1+int(f.Jf), s, sp.word, sp.notTaken, leaf)
+		}
+	}
+
+	return e.fail(pc, "ran past the end of the program")
+}
+
+// each continues the exploration at pc once for each of the domains of the word
+func (e *explorer) each(pc int, s state, word int, ds []domain, leaf func(state, value) error) error {
+	for _, d := range ds {
+		s.words[word] = d
+		if err := e.from(pc, s, leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *explorer) load(s state, f unix.SockFilter) value {
+	switch bpfMode(f.Code) {
+	case syscall.BPF_ABS:
+		return inputWord(int(f.K / 4))
+	case syscall.BPF_LEN:
+		return known(seccompDataWords * 4)
+	case syscall.BPF_MEM:
+		return s.m[f.K]
+	}
+	return known(f.K)
+}
+
+// alu calculates the result of an arithmetic instruction. It returns true if the instruction divides by zero,
+// which makes the kernel stop the program and return 0
+func (e *explorer) alu(pc int, s state, f unix.SockFilter) (value, bool, error) {
+	op := bpfOp(f.Code)
+	if op == syscall.BPF_NEG {
+		if s.a.kind == constant {
+			return known(-s.a.k), false, nil
+		}
+		return opaqueAt(pc), false, nil
+	}
+
+	left, right := s.a, known(f.K)
+	if bpfSrc(f.Code) == syscall.BPF_X {
+		right = s.x
+	}
+
+	if op == syscall.BPF_DIV || op == emulator.BPF_MOD {
+		if right.kind != constant {
+			return value{}, false, e.fail(pc, "division by a value that depends on the input")
+		}
+		if right.k == 0 {
+			return value{}, true, nil
+		}
+	}
+
+	if left.kind == constant && right.kind == constant {
+		return known(calculate(op, left.k, right.k)), false, nil
+	}
+
+	if left.kind == constant && commutative[op] {
+		left, right = right, left
+	}
+	if left.kind == input && right.kind == constant {
+		if res, ok := left.combine(op, right.k); ok {
+			return res, false, nil
+		}
+	}
+	return opaqueAt(pc), false, nil
+}
+
+var commutative = map[uint16]bool{
+	syscall.BPF_ADD:  true,
+	syscall.BPF_MUL:  true,
+	syscall.BPF_AND:  true,
+	syscall.BPF_OR:   true,
+	emulator.BPF_XOR: true,
+}
+
+func calculate(op uint16, l, r uint32) uint32 {
+	switch op {
+	case syscall.BPF_ADD:
+		return l + r
+	case syscall.BPF_SUB:
+		return l - r
+	case syscall.BPF_MUL:
+		return l * r
+	case syscall.BPF_DIV:
+		return l / r
+	case emulator.BPF_MOD:
+		return l % r
+	case syscall.BPF_AND:
+		return l & r
+	case syscall.BPF_OR:
+		return l | r
+	case emulator.BPF_XOR:
+		return l ^ r
+	case syscall.BPF_LSH:
+		return l << r
+	case syscall.BPF_RSH:
+		return l >> r
+	}
+	return 0
+}
+
+func bpfClass(code uint16) uint16 {
+	return code & 0x07
+}
+
+func bpfMode(code uint16) uint16 {
+	return code & 0xe0
+}
+
+func bpfOp(code uint16) uint16 {
+	return code & 0xf0
+}
+
+func bpfSrc(code uint16) uint16 {
+	return code & 0x08
+}
+
+func bpfRval(code uint16) uint16 {
+	return code & 0x18
+}
+
+func bpfMiscOp(code uint16) uint16 {
+	return code & 0xf8
+}
@@ -0,0 +1,197 @@
+package equivalence
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/unifier"
+
+	"golang.org/x/sys/unix"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type EquivalenceSuite struct{}
+
+var _ = Suite(&EquivalenceSuite{})
+
+// retA returns the accumulator, which the asm format can't express
+var retA = unix.SockFilter{Code: syscall.BPF_RET | syscall.BPF_A}
+
+func compile(c *C, source string) []unix.SockFilter {
+	rp, err := parser.ParseString(source)
+	c.Assert(err, IsNil)
+	p, err := unifier.Unify(rp, nil, "allow", "kill", "trap")
+	c.Assert(err, IsNil)
+	simplifier.SimplifyPolicy(&p)
+	res, err := compiler.Compile(p)
+	c.Assert(err, IsNil)
+	return res
+}
+
+func assertDifferent(c *C, res *Counterexample, left, right []unix.SockFilter) {
+	c.Assert(res, Not(IsNil))
+	c.Assert(emulator.Emulate(res.Memory, left), Equals, res.Left)
+	c.Assert(emulator.Emulate(res.Memory, right), Equals, res.Right)
+	c.Assert(res.Left, Not(Equals), res.Right)
+}
+
+func (s *EquivalenceSuite) Test_provesEquivalentPoliciesEquivalent(c *C) {
+	cases := [][2]string{
+		{"read: arg0 == 1", "read: arg0 == 1"},
+		{"read: arg0 == 1\nwrite: arg1 > 5", "write: arg1 >= 6\nread: in(arg0, 1)"},
+		{"read: arg0 == 1 || arg0 == 2", "read: in(arg0, 2, 1)"},
+		{"read: argL0 & 4 == 4", "read: argL0 &? 4"},
+		{"read: argL0 + 1 == 0", "read: argL0 == 0xFFFFFFFF"},
+		{"read: !(arg2 < 0x100000000)", "read: argH2 != 0"},
+	}
+
+	for _, t := range cases {
+		res, err := Check(compile(c, t[0]), compile(c, t[1]))
+		c.Assert(err, IsNil, Commentf("%q", t))
+		c.Assert(res, IsNil, Commentf("%q", t))
+	}
+}
+
+func (s *EquivalenceSuite) Test_findsCounterexamples(c *C) {
+	cases := [][2]string{
+		{"read: arg0 == 1", "read: arg0 == 2"},
+		{"read: arg0 > 5", "read: arg0 >= 5"},
+		{"read: argL0 &? 6", "read: argL0 &? 2"},
+		{"read: argL0 & 0xF0 == 0x10", "read: argL0 & 0xF8 == 0x10"},
+		{"read: argL0 + 3 > 10", "read: argL0 > 7"},
+		{"read: arg0 == 1", "read: arg0 == 1\nwrite: arg0 == 1"},
+		{"read: arg0 == 1", "read: arg0 == 1; return 1"},
+	}
+
+	for _, t := range cases {
+		left, right := compile(c, t[0]), compile(c, t[1])
+		res, err := Check(left, right)
+		c.Assert(err, IsNil, Commentf("%q", t))
+		assertDifferent(c, res, left, right)
+	}
+}
+
+func (s *EquivalenceSuite) Test_handlesLongInclusionLists(c *C) {
+	values := []string{}
+	for i := 0; i < 100; i++ {
+		values = append(values, fmt.Sprintf("%d", i*3))
+	}
+	original := compile(c, "read: in(arg0, "+strings.Join(values, ", ")+") || arg1 &? 0xF00000F0\nwrite: arg0 == 1\n")
+
+	res, err := Check(original, compile(c, "write: arg0 == 1\nread: arg1 &? 0xF00000F0 || in(arg0, "+strings.Join(values, ", ")+")\n"))
+	c.Assert(err, IsNil)
+	c.Assert(res, IsNil)
+
+	values[50] = "151"
+	changed := compile(c, "read: in(arg0, "+strings.Join(values, ", ")+") || arg1 &? 0xF00000F0\nwrite: arg0 == 1\n")
+	res, err = Check(original, changed)
+	c.Assert(err, IsNil)
+	assertDifferent(c, res, original, changed)
+	c.Assert(res.Memory.Args[0], Equals, uint64(150))
+}
+
+func (s *EquivalenceSuite) Test_findsTheSmallestInputThatDiffers(c *C) {
+	left, right := compile(c, "read: arg0 > 5"), compile(c, "read: arg0 >= 5")
+	res, _ := Check(left, right)
+
+	c.Assert(res.Memory.NR, Equals, int32(0))
+	c.Assert(res.Memory.Arch, Equals, uint32(0xC000003E))
+	c.Assert(res.Memory.Args[0], Equals, uint64(5))
+}
+
+func (s *EquivalenceSuite) Test_findsCounterexamplesForTheInstructionPointer(c *C) {
+	right := asm.MustParse("ret_k\t1\n")
+
+	left := asm.MustParse("ld_abs\t8\njeq_k\t00\t01\t5\nret_k\t0\nret_k\t1\n")
+	res, err := Check(left, right)
+	c.Assert(err, IsNil)
+	assertDifferent(c, res, left, right)
+	c.Assert(res.Memory.InstructionPointer, Equals, uint64(5))
+
+	left = asm.MustParse("ld_abs\tC\njeq_k\t00\t01\t5\nret_k\t0\nret_k\t1\n")
+	res, err = Check(left, right)
+	c.Assert(err, IsNil)
+	assertDifferent(c, res, left, right)
+	c.Assert(res.Memory.InstructionPointer, Equals, uint64(5)<<32)
+}
+
+func (s *EquivalenceSuite) Test_followsDivisionByZero(c *C) {
+	left := asm.MustParse("ld_imm\t0\ntax\nld_abs\t10\ndiv_x\nret_k\t1\n")
+	right := asm.MustParse("ret_k\t0\n")
+
+	res, err := Check(left, right)
+	c.Assert(err, IsNil)
+	c.Assert(res, IsNil)
+}
+
+func (s *EquivalenceSuite) Test_comparesReturnedRegisters(c *C) {
+	left := append(asm.MustParse("ld_abs\t10\nand_k\tFFFF\n"), retA)
+	right := asm.MustParse("ld_abs\t10\njeq_k\t00\t01\t0\nret_k\t0\nret_k\t1\n")
+
+	res, err := Check(left, right)
+	c.Assert(err, IsNil)
+	assertDifferent(c, res, left, right)
+	c.Assert(res.Memory.Args[0], Equals, uint64(2))
+}
+
+func (s *EquivalenceSuite) Test_reportsProgramsThatCanNotBeAnalysed(c *C) {
+	_, err := Check(compile(c, "read: arg0 == arg1"), compile(c, "read: arg0 == 1"))
+	c.Assert(err, ErrorMatches, "left program, instruction \\d+: comparing two values that both depend on the input can not be analysed")
+
+	_, err = Check(compile(c, "read: arg0 == 1"), compile(c, "read: argL0 * 3 == 6"))
+	c.Assert(err, ErrorMatches, "right program, instruction \\d+: the comparison uses the result of instruction \\d+, which can not be analysed")
+
+	_, err = Check(append(asm.MustParse("ld_abs\t10\ntax\nld_imm\t1\ndiv_x\n"), retA), asm.MustParse("ret_k\t0\n"))
+	c.Assert(err, ErrorMatches, "left program, instruction 3: division by a value that depends on the input")
+
+	_, err = Check(asm.MustParse("ret_k\t0\n"), asm.MustParse("ld_abs\t10\n"))
+	c.Assert(err, ErrorMatches, "right program: instruction 0: the last instruction is not a return")
+}
+
+func (s *EquivalenceSuite) Test_differencesReturnsAllRegions(c *C) {
+	left, right := compile(c, "read: arg0 > 5"), compile(c, "read: arg0 > 7\nwrite: argL1 &? 6")
+
+	res, err := Differences(left, right)
+	c.Assert(err, IsNil)
+	c.Assert(res, HasLen, 4)
+
+	c.Assert(res[0].Words[0].Intervals, DeepEquals, []Interval{{0, 0}})
+	c.Assert(res[0].Words[4].Intervals, DeepEquals, []Interval{{6, 7}})
+	c.Assert(res[0].Words[5].Intervals, DeepEquals, []Interval{{0, 0}})
+	c.Assert(res[0].Memory.Args[0], Equals, uint64(6))
+	c.Assert(res[0].Left, Equals, uint32(0x7FFF0000))
+	c.Assert(res[0].Right, Equals, uint32(0))
+
+	c.Assert(res[1].Words[0].Intervals, DeepEquals, []Interval{{1, 1}})
+	c.Assert(res[1].Words[6], DeepEquals, Word{Intervals: []Interval{{0, 0xFFFFFFFF}}, Set: 2})
+	c.Assert(res[2].Words[6], DeepEquals, Word{Intervals: []Interval{{0, 0xFFFFFFFF}}, Set: 4, Clear: 2})
+	c.Assert(res[3].Words[6], DeepEquals, Word{Intervals: []Interval{{0, 0xFFFFFFFF}}, Clear: 6})
+	c.Assert(res[3].Left, Equals, uint32(0x30000))
+	c.Assert(res[3].Right, Equals, uint32(0))
+
+	res, err = Differences(left, left)
+	c.Assert(err, IsNil)
+	c.Assert(res, HasLen, 0)
+}
+
+func (s *EquivalenceSuite) Test_describesSyscallsOfRegions(c *C) {
+	any := Word{Intervals: []Interval{{0, 0xFFFFFFFF}}}
+	native := Word{Intervals: []Interval{{0xC000003E, 0xC000003E}}}
+
+	c.Assert(DescribeSyscall(any, any), Equals, "all system calls")
+	c.Assert(DescribeSyscall(any, Word{Intervals: []Interval{{0, 5}}}), Equals, "other architectures")
+	c.Assert(DescribeSyscall(Word{Intervals: []Interval{{2, 2}}}, native), Equals, "open")
+	c.Assert(DescribeSyscall(Word{Intervals: []Interval{{5000, 5000}}}, native), Equals, "system call 5000")
+	c.Assert(DescribeSyscall(Word{Intervals: []Interval{{0, 0xFFFFFFFF}}, Set: 0x40000000}, native), Equals, "x32 system calls")
+	c.Assert(DescribeSyscall(Word{Intervals: []Interval{{3, 0xFFFFFFFF}}}, native), Equals, "other system calls")
+}
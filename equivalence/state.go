@@ -0,0 +1,239 @@
+package equivalence
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/twtiger/gosecco/emulator"
+)
+
+type valueKind int
+
+const (
+	constant valueKind = iota
+	// input is a word of seccomp_data, masked with a constant and with a constant added: (word & mask) + k
+	input
+	// opaque is the result of arithmetic on the input that the analysis can't follow
+	opaque
+)
+
+// value is the symbolic content of a register or scratch memory
+type value struct {
+	kind valueKind
+	k    uint32
+	word int
+	mask uint32
+	at   int
+}
+
+func known(k uint32) value {
+	return value{kind: constant, k: k}
+}
+
+func inputWord(word int) value {
+	return value{kind: input, word: word, mask: maxWord}
+}
+
+func opaqueAt(pc int) value {
+	return value{kind: opaque, at: pc}
+}
+
+// combine applies an operation with a constant right hand side to an input value. It returns false if the
+// result can't be described as an input value
+func (v value) combine(op uint16, c uint32) (value, bool) {
+	switch {
+	case op == syscall.BPF_AND && v.k == 0:
+		v.mask &= c
+		if v.mask == 0 {
+			return known(0), true
+		}
+		return v, true
+	case op == syscall.BPF_ADD && v.mask == maxWord:
+		v.k += c
+		return v, true
+	case op == syscall.BPF_SUB && v.mask == maxWord:
+		v.k -= c
+		return v, true
+	case c == 0 && (op == syscall.BPF_ADD || op == syscall.BPF_SUB || op == syscall.BPF_OR ||
+		op == emulator.BPF_XOR || op == syscall.BPF_LSH || op == syscall.BPF_RSH):
+		return v, true
+	case c == 1 && (op == syscall.BPF_MUL || op == syscall.BPF_DIV):
+		return v, true
+	}
+	return value{}, false
+}
+
+// state is everything known at one point of one path through a program
+type state struct {
+	words [seccompDataWords]domain
+	a, x  value
+	m     [syscall.BPF_MEMWORDS]value
+}
+
+func initialState() state {
+	s := state{}
+	for i := range s.words {
+		s.words[i] = unrestricted()
+	}
+	return s
+}
+
+// restart keeps the restrictions on the input, but resets the registers and the scratch memory
+func (s state) restart() state {
+	return state{words: s.words}
+}
+
+// split is the outcome of a conditional jump: the domains of one word for which the jump is taken, and the
+// domains for which it isn't. All other words keep their domains
+type split struct {
+	word            int
+	taken, notTaken []domain
+}
+
+// branch splits the possible input for a conditional jump comparing l to r
+func (s state) branch(op uint16, l, r value) (split, error) {
+	if l.kind == constant && r.kind == constant {
+		unchanged := []domain{s.words[0]}
+		if holds(op, l.k, r.k) {
+			return split{taken: unchanged}, nil
+		}
+		return split{notTaken: unchanged}, nil
+	}
+
+	for _, v := range []value{l, r} {
+		if v.kind == opaque {
+			return split{}, fmt.Errorf("the comparison uses the result of instruction %d, which can not be analysed", v.at)
+		}
+	}
+	if l.kind == input && r.kind == input {
+		return split{}, errors.New("comparing two values that both depend on the input can not be analysed")
+	}
+
+	in, c, inputOnLeft := l, r.k, true
+	if l.kind == constant {
+		in, c, inputOnLeft = r, l.k, false
+	}
+
+	d := s.words[in.word]
+	switch {
+	case op == syscall.BPF_JSET && in.k == 0:
+		return bitTest(in, d, c), nil
+	case op == syscall.BPF_JSET:
+		return split{}, errors.New("testing bits of a value with a constant added to it can not be analysed")
+	case in.mask == maxWord:
+		return inRange(in, d, satisfying(op, c, inputOnLeft)), nil
+	case op == syscall.BPF_JEQ && in.k == 0:
+		return maskedEquals(in, d, c), nil
+	}
+	return split{}, errors.New("only equality comparisons of masked values can be analysed")
+}
+
+func holds(op uint16, l, r uint32) bool {
+	switch op {
+	case syscall.BPF_JEQ:
+		return l == r
+	case syscall.BPF_JGT:
+		return l > r
+	case syscall.BPF_JGE:
+		return l >= r
+	}
+	return l&r != 0
+}
+
+// satisfying returns the values v for which "v op c" is true, or "c op v" if the input isn't on the left
+func satisfying(op uint16, c uint32, inputOnLeft bool) []interval {
+	switch {
+	case op == syscall.BPF_JEQ:
+		return []interval{{c, c}}
+	case op == syscall.BPF_JGT && inputOnLeft && c < maxWord:
+		return []interval{{c + 1, maxWord}}
+	case op == syscall.BPF_JGT && !inputOnLeft && c > 0:
+		return []interval{{0, c - 1}}
+	case op == syscall.BPF_JGE && inputOnLeft:
+		return []interval{{c, maxWord}}
+	case op == syscall.BPF_JGE:
+		return []interval{{0, c}}
+	}
+	return []interval{}
+}
+
+// inRange splits the domain on whether the unmasked input value is one of the given values
+func inRange(v value, d domain, values []interval) split {
+	words := subtract(values, v.k)
+	if len(words) == 1 {
+		// Most comparisons are already decided by earlier ones, and finding that out doesn't need new domains
+		switch {
+		case d.inside(words[0]):
+			return split{word: v.word, taken: []domain{d}}
+		case d.disjoint(words[0]):
+			return split{word: v.word, notTaken: []domain{d}}
+		}
+	}
+	return split{v.word, possible(d.within(words)), possible(d.outside(words))}
+}
+
+// bitTest splits the domain on whether any of the bits in c are set in the input value. The jump is
+// taken if the lowest of the bits is set, or if it is clear and the next one is set, and so on
+func bitTest(v value, d domain, c uint32) split {
+	m := c & v.mask
+
+	taken := []domain{}
+	lower := uint32(0)
+	for bit := uint32(1); bit != 0; bit <<= 1 {
+		if m&bit != 0 {
+			taken = append(taken, d.withBits(bit, lower))
+			lower |= bit
+		}
+	}
+	return split{v.word, possible(taken...), possible(d.withBits(0, m))}
+}
+
+// maskedEquals splits the domain on whether the input value is equal to c. It isn't equal if the lowest bit
+// of the mask differs from c, or if it is the same and the next one differs, and so on
+func maskedEquals(v value, d domain, c uint32) split {
+	m := v.mask
+	if c&^m != 0 {
+		return split{word: v.word, notTaken: []domain{d}}
+	}
+
+	notTaken := []domain{}
+	same := uint32(0)
+	for bit := uint32(1); bit != 0; bit <<= 1 {
+		if m&bit != 0 {
+			notTaken = append(notTaken, d.withBits(c&same|^c&bit, same&^c|c&bit))
+			same |= bit
+		}
+	}
+	return split{v.word, possible(d.withBits(c, m&^c)), possible(notTaken...)}
+}
+
+// possible returns the domains that still contain a value
+func possible(ds ...domain) []domain {
+	result := make([]domain, 0, len(ds))
+	for _, d := range ds {
+		if _, ok := d.witness(); ok {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// differing returns the regions where the two return values are different, which are none if they are the same on every input
+func (s state) differing(l, r value) ([]state, error) {
+	if l.kind != opaque && l == r {
+		return nil, nil
+	}
+
+	sp, err := s.branch(syscall.BPF_JEQ, l, r)
+	if err != nil {
+		return nil, fmt.Errorf("comparing the return values: %s", err)
+	}
+	result := []state{}
+	for _, d := range sp.notTaken {
+		different := s
+		different.words[sp.word] = d
+		result = append(result, different)
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package equivalence
+
+import (
+	"fmt"
+
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/native"
+)
+
+// Contains returns true if the value is one of the values the word can have
+func (w Word) Contains(v uint32) bool {
+	if v&w.Set != w.Set || v&w.Clear != 0 {
+		return false
+	}
+	for _, i := range w.Intervals {
+		if i.Lo <= v && v <= i.Hi {
+			return true
+		}
+	}
+	return false
+}
+
+// Single returns the only value the word can have, if there is only one
+func (w Word) Single() (uint32, bool) {
+	if len(w.Intervals) == 1 && w.Intervals[0].Lo == w.Intervals[0].Hi && w.Contains(w.Intervals[0].Lo) {
+		return w.Intervals[0].Lo, true
+	}
+	return 0, false
+}
+
+// Unrestricted returns true if the word can have any value
+func (w Word) Unrestricted() bool {
+	return w.Set == 0 && w.Clear == 0 && len(w.Intervals) == 1 && w.Intervals[0] == Interval{0, maxWord}
+}
+
+// DescribeSyscall names the system calls in a region from the words for the system call number and the architecture.
+// It is the name of the system call if there is only one, or a description such as "other system calls"
+func DescribeSyscall(nr, arch Word) string {
+	if nr.Unrestricted() && arch.Unrestricted() {
+		return "all system calls"
+	}
+	if !arch.Contains(native.AuditArch) {
+		return "other architectures"
+	}
+	if v, ok := nr.Single(); ok {
+		if name, ok := constants.SyscallNumbers[int(v)]; ok {
+			return name
+		}
+		return fmt.Sprintf("system call %d", v)
+	}
+	if nr.Set&native.X32SyscallBit != 0 {
+		return "x32 system calls"
+	}
+	return "other system calls"
+}
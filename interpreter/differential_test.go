@@ -0,0 +1,263 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/checker"
+	"github.com/twtiger/gosecco/compiler"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/precompilation"
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/tree"
+
+	"golang.org/x/sys/unix"
+
+	. "gopkg.in/check.v1"
+)
+
+// The differential tests generate random policies and working memory, and make sure the interpreter
+// agrees with the emulated result of compiling the same policy. Policies that the checker or the precompilation
+// checker rejects are skipped, but all others have to compile to a filter the kernel would accept.
+
+type DifferentialSuite struct{}
+
+var _ = Suite(&DifferentialSuite{})
+
+const (
+	differentialSeed     = 42
+	differentialPolicies = 3000
+	differentialInputs   = 20
+)
+
+var syscallNames = []string{"read", "write", "open", "close", "ioctl"}
+
+var actionNames = []string{"", "allow", "kill", "trap", "trace", "EPERM", "42"}
+
+var interesting32 = []uint64{0, 1, 2, 3, 7, 0x10, 0xFF, 0x100, 0x7FFFFFFF, 0x80000000, 0xFFFFFFFE, 0xFFFFFFFF}
+
+var interesting64 = []uint64{0x100000000, 0x100000001, 0x1FFFFFFFF, 0x8000000000000000, 0xFFFFFFFF00000000, 0xFFFFFFFFFFFFFFFF}
+
+type generator struct {
+	r *rand.Rand
+}
+
+func (g *generator) choose(n int) int {
+	return g.r.Intn(n)
+}
+
+func (g *generator) literal32() tree.NumericLiteral {
+	if g.choose(4) == 0 {
+		return tree.NumericLiteral{Value: uint64(g.r.Uint32())}
+	}
+	return tree.NumericLiteral{Value: interesting32[g.choose(len(interesting32))]}
+}
+
+func (g *generator) literal64() tree.NumericLiteral {
+	switch g.choose(3) {
+	case 0:
+		return tree.NumericLiteral{Value: interesting64[g.choose(len(interesting64))]}
+	case 1:
+		return tree.NumericLiteral{Value: uint64(g.r.Int63())}
+	}
+	return g.literal32()
+}
+
+func (g *generator) argument(t tree.ArgumentType) tree.Argument {
+	return tree.Argument{Type: t, Index: g.choose(6)}
+}
+
+// half generates a numeric expression that is calculated with 32 bit arithmetic
+func (g *generator) half(depth int) tree.Numeric {
+	if depth <= 0 || g.choose(3) == 0 {
+		if g.choose(3) == 0 {
+			return g.literal32()
+		}
+		return g.argument(tree.ArgumentType(1 + g.choose(2)))
+	}
+
+	switch g.choose(4) {
+	case 0:
+		// Shifts by a runtime value of 32 or more are not consistent between BPF implementations
+		op := []tree.ArithmeticType{tree.LSH, tree.RSH}[g.choose(2)]
+		return tree.Arithmetic{Op: op, Left: g.half(depth - 1), Right: tree.NumericLiteral{Value: uint64(g.choose(32))}}
+	case 1:
+		// Division by a runtime value could be zero, which makes the whole filter return 0
+		op := []tree.ArithmeticType{tree.DIV, tree.MOD}[g.choose(2)]
+		return tree.Arithmetic{Op: op, Left: g.half(depth - 1), Right: tree.NumericLiteral{Value: uint64(1 + g.choose(100))}}
+	}
+	ops := []tree.ArithmeticType{tree.PLUS, tree.MINUS, tree.MULT, tree.BINAND, tree.BINOR, tree.BINXOR}
+	return tree.Arithmetic{Op: ops[g.choose(len(ops))], Left: g.half(depth - 1), Right: g.half(depth - 1)}
+}
+
+func (g *generator) comparisonType() tree.ComparisonType {
+	return tree.ComparisonType(g.choose(int(tree.BITSET) + 1))
+}
+
+func (g *generator) comparison(depth int) tree.Boolean {
+	switch g.choose(5) {
+	case 0:
+		return tree.Comparison{Op: g.comparisonType(), Left: g.argument(tree.Full), Right: g.literal64()}
+	case 1:
+		return tree.Comparison{Op: g.comparisonType(), Left: g.literal64(), Right: g.argument(tree.Full)}
+	case 2:
+		return tree.Comparison{Op: g.comparisonType(), Left: g.argument(tree.Full), Right: g.argument(tree.Full)}
+	case 3:
+		rights := []tree.Numeric{}
+		for i := 0; i < 1+g.choose(4); i++ {
+			rights = append(rights, g.literal64())
+		}
+		left := tree.Numeric(g.argument(tree.Full))
+		if g.choose(2) == 0 {
+			left = g.half(depth)
+			for i := range rights {
+				rights[i] = g.literal32()
+			}
+		}
+		return tree.Inclusion{Positive: g.choose(2) == 0, Left: left, Rights: rights}
+	}
+	return tree.Comparison{Op: g.comparisonType(), Left: g.half(depth), Right: g.half(depth)}
+}
+
+func (g *generator) boolean(depth int) tree.Boolean {
+	if depth <= 0 || g.choose(3) == 0 {
+		if g.choose(20) == 0 {
+			return tree.BooleanLiteral{Value: g.choose(2) == 0}
+		}
+		return g.comparison(2)
+	}
+
+	switch g.choose(3) {
+	case 0:
+		return tree.And{Left: g.boolean(depth - 1), Right: g.boolean(depth - 1)}
+	case 1:
+		return tree.Or{Left: g.boolean(depth - 1), Right: g.boolean(depth - 1)}
+	}
+	return tree.Negation{Operand: g.boolean(depth - 1)}
+}
+
+func (g *generator) action() string {
+	return actionNames[g.choose(len(actionNames))]
+}
+
+func (g *generator) policy() tree.Policy {
+	p := tree.Policy{
+		DefaultPositiveAction: "allow",
+		DefaultNegativeAction: "kill",
+		DefaultPolicyAction:   actionNames[1+g.choose(len(actionNames)-1)],
+	}
+	if g.choose(3) == 0 {
+		p.ActionOnX32 = "trap"
+	}
+	if g.choose(3) == 0 {
+		p.ActionOnAuditFailure = "EACCES"
+	}
+	// The checker rejects policies with more than one rule for a syscall
+	names := g.r.Perm(len(syscallNames))
+	for i := 0; i < 1+g.choose(4); i++ {
+		p.Rules = append(p.Rules, &tree.Rule{
+			Name:           syscallNames[names[i]],
+			Body:           g.boolean(3),
+			PositiveAction: g.action(),
+			NegativeAction: g.action(),
+		})
+	}
+	return p
+}
+
+func (g *generator) argumentValue(p tree.Policy) uint64 {
+	switch g.choose(4) {
+	case 0:
+		return interesting64[g.choose(len(interesting64))]
+	case 1:
+		return interesting32[g.choose(len(interesting32))]
+	case 2:
+		return interesting32[g.choose(len(interesting32))]<<32 | interesting32[g.choose(len(interesting32))]
+	}
+	return g.r.Uint64()
+}
+
+func (g *generator) memory(p tree.Policy) data.SeccompWorkingMemory {
+	d := data.SeccompWorkingMemory{Arch: native.AuditArch}
+	if g.choose(20) == 0 {
+		d.Arch = 0x40000003
+	}
+
+	name := syscallNames[g.choose(len(syscallNames))]
+	if len(p.Rules) > 0 && g.choose(2) == 0 {
+		name = p.Rules[g.choose(len(p.Rules))].Name
+	}
+	nr, _ := constants.GetSyscall(name)
+	if g.choose(10) == 0 {
+		nr |= native.X32SyscallBit
+	}
+	d.NR = int32(nr)
+
+	for i := range d.Args {
+		d.Args[i] = g.argumentValue(p)
+	}
+	return d
+}
+
+// compile runs the same stages as PrepareSource on a copy of the policy. It returns false if the policy
+// is rejected before compilation
+func compile(p tree.Policy) ([]unix.SockFilter, bool, error) {
+	if len(checker.EnsureValid(p)) > 0 {
+		return nil, false, nil
+	}
+
+	rules := []*tree.Rule{}
+	for _, r := range p.Rules {
+		copied := *r
+		rules = append(rules, &copied)
+	}
+	p.Rules = rules
+
+	simplifier.SimplifyPolicy(&p)
+	if len(precompilation.EnsureValid(p)) > 0 {
+		return nil, false, nil
+	}
+
+	result, err := compiler.Compile(p)
+	return result, true, err
+}
+
+func describe(p tree.Policy) string {
+	result := fmt.Sprintf("positive: %s, negative: %s, policy: %s, x32: %q, audit: %q\n",
+		p.DefaultPositiveAction, p.DefaultNegativeAction, p.DefaultPolicyAction, p.ActionOnX32, p.ActionOnAuditFailure)
+	for _, r := range p.Rules {
+		result += fmt.Sprintf("%s: %s => %q, %q\n", r.Name, tree.SourceString(r.Body), r.PositiveAction, r.NegativeAction)
+	}
+	return result
+}
+
+func (s *DifferentialSuite) Test_interpreterAgreesWithTheCompiledFilter(c *C) {
+	g := &generator{r: rand.New(rand.NewSource(differentialSeed))}
+
+	compiled := 0
+	for i := 0; i < differentialPolicies; i++ {
+		p := g.policy()
+		filter, ok, err := compile(p)
+		if !ok {
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("policy:\n%s", describe(p)))
+		c.Assert(emulator.Verify(filter), IsNil, Commentf("policy:\n%s\nfilter:\n%s", describe(p), asm.Dump(filter)))
+		compiled++
+
+		for j := 0; j < differentialInputs; j++ {
+			d := g.memory(p)
+			expected, err := Interpret(p, d)
+			c.Assert(err, IsNil)
+			actual := emulator.Emulate(d, filter)
+			c.Assert(actual, Equals, expected, Commentf("policy:\n%s\nmemory: %#v\nfilter:\n%s", describe(p), d, asm.Dump(filter)))
+		}
+	}
+
+	// Make sure the generator doesn't only create policies that are rejected
+	c.Assert(compiled > differentialPolicies/2, Equals, true, Commentf("only %d policies compiled", compiled))
+}
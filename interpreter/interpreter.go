@@ -0,0 +1,270 @@
+// Package interpreter evaluates unified policies directly at the source level, without compiling them.
+// It is meant as a reference for what a policy means - the result for a given working memory should always
+// be the same as running the compiled filter in the emulator or the kernel.
+//
+// The semantics follow the language definition:
+//   - constant expressions are calculated with 64 bit arithmetic, in the same way as the simplifier does it
+//   - arithmetic involving half arguments (argL0, argH0...) is calculated with the 32 bit arithmetic of BPF
+//   - full arguments can only be compared, and comparisons with them use all 64 bits
+//   - dividing by zero at runtime makes the filter return 0, since that is what the kernel does
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/tree"
+)
+
+// errDivisionByZero stops the evaluation, in the same way the kernel stops a filter that divides by zero
+var errDivisionByZero = errors.New("division by zero")
+
+// Interpret returns the value a filter compiled from the policy would return for the given working memory.
+// The policy should be unified - the error will describe any problem that stops it from being evaluated,
+// such as undefined actions, unknown system calls or expressions of the wrong type
+func Interpret(p tree.Policy, d data.SeccompWorkingMemory) (uint32, error) {
+	if d.Arch != native.AuditArch {
+		return action(p.ActionOnAuditFailure, "kill")
+	}
+
+	if p.ActionOnX32 != "" && uint32(d.NR)&native.X32SyscallBit != 0 {
+		return action(p.ActionOnX32, "")
+	}
+
+	for _, r := range p.Rules {
+		sys, ok := constants.GetSyscall(r.Name)
+		if !ok {
+			return 0, fmt.Errorf("unknown system call '%s'", r.Name)
+		}
+		if uint32(d.NR) != sys {
+			continue
+		}
+
+		e := &evaluator{data: d}
+		result := e.boolean(r.Body)
+		if e.err == errDivisionByZero {
+			return actions.RetKill, nil
+		}
+		if e.err != nil {
+			return 0, fmt.Errorf("[%s] %s", r.Name, e.err)
+		}
+		if result {
+			return action(r.PositiveAction, p.DefaultPositiveAction)
+		}
+		return action(r.NegativeAction, p.DefaultNegativeAction)
+	}
+
+	return action(p.DefaultPolicyAction, "")
+}
+
+func action(a, def string) (uint32, error) {
+	if a == "" {
+		a = def
+	}
+	parsed, err := actions.Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.K(), nil
+}
+
+// kind describes where a numeric value comes from, since that decides which arithmetic is used for it
+type kind int
+
+const (
+	constant kind = iota
+	half
+	full
+)
+
+type value struct {
+	v    uint64
+	kind kind
+}
+
+// evaluator calculates expressions against working memory. The first error encountered stops the evaluation,
+// and every result after that should be ignored
+type evaluator struct {
+	data data.SeccompWorkingMemory
+	err  error
+}
+
+func (e *evaluator) fail(format string, args ...interface{}) {
+	if e.err == nil {
+		e.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (e *evaluator) boolean(x tree.Expression) bool {
+	if e.err != nil {
+		return false
+	}
+
+	switch v := x.(type) {
+	case tree.BooleanLiteral:
+		return v.Value
+	case tree.And:
+		return e.boolean(v.Left) && e.boolean(v.Right)
+	case tree.Or:
+		return e.boolean(v.Left) || e.boolean(v.Right)
+	case tree.Negation:
+		return !e.boolean(v.Operand)
+	case tree.Comparison:
+		return e.comparison(v.Op, e.numeric(v.Left), e.numeric(v.Right))
+	case tree.Inclusion:
+		left := e.numeric(v.Left)
+		for _, r := range v.Rights {
+			if left.v == e.numeric(r).v {
+				return v.Positive
+			}
+		}
+		return !v.Positive
+	}
+
+	e.fail("expected boolean expression but found: %s", tree.ExpressionString(x))
+	return false
+}
+
+func (e *evaluator) comparison(op tree.ComparisonType, l, r value) bool {
+	switch op {
+	case tree.EQL:
+		return l.v == r.v
+	case tree.NEQL:
+		return l.v != r.v
+	case tree.GT:
+		return l.v > r.v
+	case tree.GTE:
+		return l.v >= r.v
+	case tree.LT:
+		return l.v < r.v
+	case tree.LTE:
+		return l.v <= r.v
+	case tree.BITSET:
+		return l.v&r.v != 0
+	}
+	e.fail("unknown comparison operator %d", op)
+	return false
+}
+
+func (e *evaluator) argument(v tree.Argument) value {
+	if v.Index < 0 || v.Index >= len(e.data.Args) {
+		e.fail("invalid argument index %d", v.Index)
+		return value{}
+	}
+
+	arg := e.data.Args[v.Index]
+	switch v.Type {
+	case tree.Low:
+		return value{arg & 0xFFFFFFFF, half}
+	case tree.Hi:
+		return value{arg >> 32, half}
+	}
+	return value{arg, full}
+}
+
+func (e *evaluator) numeric(x tree.Expression) value {
+	if e.err != nil {
+		return value{}
+	}
+
+	switch v := x.(type) {
+	case tree.NumericLiteral:
+		return value{v.Value, constant}
+	case tree.Argument:
+		return e.argument(v)
+	case tree.BinaryNegation:
+		operand := e.numeric(v.Operand)
+		switch operand.kind {
+		case constant:
+			return value{^operand.v, constant}
+		case half:
+			return value{uint64(^uint32(operand.v)), half}
+		}
+		e.fail("full argument cannot be used in arithmetic expressions: %s", tree.ExpressionString(x))
+		return value{}
+	case tree.Arithmetic:
+		return e.arithmetic(v)
+	}
+
+	e.fail("expected numeric expression but found: %s", tree.ExpressionString(x))
+	return value{}
+}
+
+func (e *evaluator) arithmetic(v tree.Arithmetic) value {
+	l := e.numeric(v.Left)
+	r := e.numeric(v.Right)
+	if e.err != nil {
+		return value{}
+	}
+
+	if l.kind == full || r.kind == full {
+		e.fail("full argument cannot be used in arithmetic expressions: %s", tree.ExpressionString(v))
+		return value{}
+	}
+
+	if (v.Op == tree.DIV || v.Op == tree.MOD) && r.v == 0 {
+		e.err = errDivisionByZero
+		return value{}
+	}
+
+	if l.kind == constant && r.kind == constant {
+		return value{calculate64(v.Op, l.v, r.v), constant}
+	}
+	return value{uint64(calculate32(v.Op, uint32(l.v), uint32(r.v))), half}
+}
+
+func calculate64(op tree.ArithmeticType, l, r uint64) uint64 {
+	switch op {
+	case tree.PLUS:
+		return l + r
+	case tree.MINUS:
+		return l - r
+	case tree.MULT:
+		return l * r
+	case tree.DIV:
+		return l / r
+	case tree.MOD:
+		return l % r
+	case tree.BINAND:
+		return l & r
+	case tree.BINOR:
+		return l | r
+	case tree.BINXOR:
+		return l ^ r
+	case tree.LSH:
+		return l << r
+	case tree.RSH:
+		return l >> r
+	}
+	return 0
+}
+
+func calculate32(op tree.ArithmeticType, l, r uint32) uint32 {
+	switch op {
+	case tree.PLUS:
+		return l + r
+	case tree.MINUS:
+		return l - r
+	case tree.MULT:
+		return l * r
+	case tree.DIV:
+		return l / r
+	case tree.MOD:
+		return l % r
+	case tree.BINAND:
+		return l & r
+	case tree.BINOR:
+		return l | r
+	case tree.BINXOR:
+		return l ^ r
+	case tree.LSH:
+		return l << r
+	case tree.RSH:
+		return l >> r
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type InterpreterSuite struct{}
+
+var _ = Suite(&InterpreterSuite{})
+
+const (
+	nrRead  = 0
+	nrWrite = 1
+)
+
+func memory(nr int32, args ...uint64) data.SeccompWorkingMemory {
+	d := data.SeccompWorkingMemory{NR: nr, Arch: native.AuditArch}
+	copy(d.Args[:], args)
+	return d
+}
+
+func policy(rules ...*tree.Rule) tree.Policy {
+	return tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "trap", Rules: rules}
+}
+
+func rule(name string, body tree.Expression) *tree.Rule {
+	return &tree.Rule{Name: name, Body: body}
+}
+
+func arg(t tree.ArgumentType, ix int) tree.Argument {
+	return tree.Argument{Type: t, Index: ix}
+}
+
+func lit(v uint64) tree.NumericLiteral {
+	return tree.NumericLiteral{Value: v}
+}
+
+func (s *InterpreterSuite) Test_usesTheActionsOfTheMatchingRule(c *C) {
+	p := policy(
+		rule("read", tree.Comparison{Op: tree.EQL, Left: arg(tree.Full, 0), Right: lit(1)}),
+		&tree.Rule{Name: "write", Body: tree.BooleanLiteral{Value: false}, NegativeAction: "EPERM"},
+	)
+
+	res, err := Interpret(p, memory(nrRead, 1))
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, actions.RetAllow)
+
+	res, _ = Interpret(p, memory(nrRead, 0x100000001))
+	c.Assert(res, Equals, actions.RetKill)
+
+	res, _ = Interpret(p, memory(nrWrite))
+	c.Assert(res, Equals, actions.RetErrno|1)
+
+	res, _ = Interpret(p, memory(2))
+	c.Assert(res, Equals, actions.RetTrap)
+}
+
+func (s *InterpreterSuite) Test_onlyTheFirstRuleForASyscallIsUsed(c *C) {
+	p := policy(
+		rule("read", tree.BooleanLiteral{Value: false}),
+		rule("read", tree.BooleanLiteral{Value: true}),
+	)
+
+	res, _ := Interpret(p, memory(nrRead))
+	c.Assert(res, Equals, actions.RetKill)
+}
+
+func (s *InterpreterSuite) Test_checksTheArchitectureAndX32(c *C) {
+	p := policy(rule("read", tree.BooleanLiteral{Value: true}))
+
+	wrongArch := memory(nrRead)
+	wrongArch.Arch = 0x40000003
+	res, _ := Interpret(p, wrongArch)
+	c.Assert(res, Equals, actions.RetKill)
+
+	p.ActionOnAuditFailure = "trace"
+	res, _ = Interpret(p, wrongArch)
+	c.Assert(res, Equals, actions.RetTrace)
+
+	x32 := memory(int32(native.X32SyscallBit | nrRead))
+	res, _ = Interpret(p, x32)
+	c.Assert(res, Equals, actions.RetTrap)
+
+	p.ActionOnX32 = "EPERM"
+	res, _ = Interpret(p, x32)
+	c.Assert(res, Equals, actions.RetErrno|1)
+
+	res, _ = Interpret(p, memory(nrRead))
+	c.Assert(res, Equals, actions.RetAllow)
+}
+
+func (s *InterpreterSuite) Test_usesThirtyTwoBitArithmeticForHalfArguments(c *C) {
+	overflowing := tree.Comparison{Op: tree.EQL,
+		Left:  tree.Arithmetic{Op: tree.PLUS, Left: arg(tree.Low, 0), Right: lit(1)},
+		Right: lit(0)}
+	res, _ := Interpret(policy(rule("read", overflowing)), memory(nrRead, 0xFFFFFFFF))
+	c.Assert(res, Equals, actions.RetAllow)
+
+	constant := tree.Comparison{Op: tree.EQL,
+		Left:  tree.Arithmetic{Op: tree.PLUS, Left: lit(0xFFFFFFFF), Right: lit(1)},
+		Right: lit(0x100000000)}
+	res, _ = Interpret(policy(rule("read", constant)), memory(nrRead))
+	c.Assert(res, Equals, actions.RetAllow)
+
+	high := tree.Comparison{Op: tree.EQL, Left: arg(tree.Hi, 1), Right: lit(0x12)}
+	res, _ = Interpret(policy(rule("read", high)), memory(nrRead, 0, 0x1200000034))
+	c.Assert(res, Equals, actions.RetAllow)
+}
+
+func (s *InterpreterSuite) Test_evaluatesAllComparisonsAndInclusions(c *C) {
+	cases := []struct {
+		body     tree.Expression
+		expected bool
+	}{
+		{tree.Comparison{Op: tree.LT, Left: arg(tree.Full, 0), Right: lit(5)}, true},
+		{tree.Comparison{Op: tree.LTE, Left: arg(tree.Full, 0), Right: lit(4)}, true},
+		{tree.Comparison{Op: tree.GT, Left: arg(tree.Full, 0), Right: lit(4)}, false},
+		{tree.Comparison{Op: tree.GTE, Left: arg(tree.Full, 0), Right: lit(4)}, true},
+		{tree.Comparison{Op: tree.NEQL, Left: arg(tree.Full, 0), Right: arg(tree.Full, 1)}, true},
+		{tree.Comparison{Op: tree.BITSET, Left: arg(tree.Full, 1), Right: lit(0x100000004)}, true},
+		{tree.Comparison{Op: tree.BITSET, Left: arg(tree.Full, 1), Right: lit(0x4)}, false},
+		{tree.Inclusion{Positive: true, Left: arg(tree.Full, 0), Rights: []tree.Numeric{lit(1), lit(4)}}, true},
+		{tree.Inclusion{Positive: false, Left: arg(tree.Full, 0), Rights: []tree.Numeric{lit(1), lit(4)}}, false},
+		{tree.Negation{Operand: tree.Or{Left: tree.BooleanLiteral{Value: false}, Right: tree.BooleanLiteral{Value: true}}}, false},
+	}
+
+	for _, t := range cases {
+		res, err := Interpret(policy(rule("read", t.body)), memory(nrRead, 4, 0x100000000))
+		c.Assert(err, IsNil)
+		c.Assert(res == actions.RetAllow, Equals, t.expected, Commentf("%s", tree.SourceString(t.body)))
+	}
+}
+
+func (s *InterpreterSuite) Test_divisionByZeroReturnsZero(c *C) {
+	p := policy(rule("read", tree.Comparison{Op: tree.EQL,
+		Left:  tree.Arithmetic{Op: tree.DIV, Left: lit(1), Right: arg(tree.Low, 0)},
+		Right: lit(0)}))
+	p.DefaultNegativeAction = "trace"
+
+	res, err := Interpret(p, memory(nrRead, 0))
+	c.Assert(err, IsNil)
+	c.Assert(res, Equals, actions.RetKill)
+}
+
+func (s *InterpreterSuite) Test_reportsPoliciesThatCanNotBeEvaluated(c *C) {
+	_, err := Interpret(policy(rule("read", tree.Variable{Name: "foo"})), memory(nrRead))
+	c.Assert(err, ErrorMatches, "\\[read\\] expected boolean expression but found: foo")
+
+	_, err = Interpret(policy(rule("read", tree.Comparison{Op: tree.EQL,
+		Left: tree.Arithmetic{Op: tree.PLUS, Left: arg(tree.Full, 0), Right: lit(1)}, Right: lit(1)})), memory(nrRead))
+	c.Assert(err, ErrorMatches, "\\[read\\] full argument cannot be used in arithmetic expressions: \\(plus arg0 1\\)")
+
+	_, err = Interpret(policy(rule("foobar", tree.BooleanLiteral{Value: true})), memory(nrRead))
+	c.Assert(err, ErrorMatches, "unknown system call 'foobar'")
+
+	p := policy(rule("read", tree.BooleanLiteral{Value: true}))
+	p.DefaultPositiveAction = "allwo"
+	_, err = Interpret(p, memory(nrRead))
+	c.Assert(err, ErrorMatches, "invalid action 'allwo' - did you mean 'allow'\\?")
+}
@@ -0,0 +1,423 @@
+package linter
+
+import (
+	"fmt"
+	"sort"
+	"syscall"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/checker"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/simplifier"
+	"github.com/twtiger/gosecco/tree"
+)
+
+type check struct {
+	code      string
+	severity  Severity
+	rationale string
+	run       func(*context) []Finding
+}
+
+var allChecks = []check{
+	{
+		code:      "S001",
+		severity:  High,
+		rationale: "this system call gives a process capabilities that are very rarely needed, and that are commonly used to escape a sandbox",
+		run:       checkDangerousSyscalls,
+	},
+	{
+		code:      "S002",
+		severity:  Medium,
+		rationale: "without restricting the flags, clone can create new namespaces, which exposes a large amount of kernel attack surface to the process",
+		run:       checkCloneNamespaces,
+	},
+	{
+		code:      "S003",
+		severity:  Medium,
+		rationale: "ioctl multiplexes a huge number of driver specific operations, so it should only be allowed for a known list of requests",
+		run:       checkIoctlRequests,
+	},
+	{
+		code:      "S004",
+		severity:  High,
+		rationale: "the kernel provides several system calls for the same operation, and restricting only one of them is easily bypassed",
+		run:       checkBypasses,
+	},
+}
+
+type dangerousSyscall struct {
+	name      string
+	severity  Severity
+	rationale string
+}
+
+var dangerousSyscalls = []dangerousSyscall{
+	{"ptrace", High, "ptrace allows reading and changing the memory and registers of other processes, and can be used to bypass seccomp filters in older kernels"},
+	{"process_vm_writev", High, "process_vm_writev writes directly into the memory of other processes"},
+	{"process_vm_readv", Medium, "process_vm_readv reads directly from the memory of other processes"},
+	{"kexec_load", High, "kexec_load replaces the running kernel"},
+	{"kexec_file_load", High, "kexec_file_load replaces the running kernel"},
+	{"bpf", High, "bpf exposes the BPF verifier and JIT, which have been the source of many privilege escalations"},
+	{"init_module", High, "init_module loads code into the kernel"},
+	{"finit_module", High, "finit_module loads code into the kernel"},
+	{"delete_module", Medium, "delete_module can remove security relevant kernel modules"},
+	{"unshare", Medium, "unshare can create new namespaces, which exposes a large amount of kernel attack surface to the process"},
+	{"personality", Medium, "personality can turn off address space randomization and enable legacy behaviors"},
+}
+
+type namedFlag struct {
+	name  string
+	value uint64
+}
+
+// namespaceFlags are the clone flags that create new namespaces
+var namespaceFlags = []namedFlag{
+	{"CLONE_NEWNS", syscall.CLONE_NEWNS},
+	{"CLONE_NEWCGROUP", syscall.CLONE_NEWCGROUP},
+	{"CLONE_NEWUTS", syscall.CLONE_NEWUTS},
+	{"CLONE_NEWIPC", syscall.CLONE_NEWIPC},
+	{"CLONE_NEWUSER", syscall.CLONE_NEWUSER},
+	{"CLONE_NEWPID", syscall.CLONE_NEWPID},
+	{"CLONE_NEWNET", syscall.CLONE_NEWNET},
+}
+
+// equivalentSyscalls maps system calls to other system calls that can do the same thing
+var equivalentSyscalls = map[string][]string{
+	"open":     {"openat"},
+	"execve":   {"execveat"},
+	"rename":   {"renameat", "renameat2"},
+	"unlink":   {"unlinkat"},
+	"rmdir":    {"unlinkat"},
+	"mkdir":    {"mkdirat"},
+	"mknod":    {"mknodat"},
+	"link":     {"linkat"},
+	"symlink":  {"symlinkat"},
+	"chmod":    {"fchmodat", "fchmod"},
+	"chown":    {"fchownat", "fchown", "lchown"},
+	"access":   {"faccessat"},
+	"readlink": {"readlinkat"},
+	"fork":     {"vfork", "clone"},
+	"dup2":     {"dup3"},
+	"kill":     {"tkill", "tgkill"},
+	"select":   {"pselect6"},
+	"poll":     {"ppoll"},
+}
+
+// context contains the information about the policy that the checks need
+type context struct {
+	policy tree.Policy
+	rules  map[string]*tree.Rule
+}
+
+func newContext(p tree.Policy) *context {
+	ctx := &context{policy: p, rules: make(map[string]*tree.Rule)}
+	for _, r := range p.Rules {
+		if _, ok := ctx.rules[r.Name]; !ok {
+			ctx.rules[r.Name] = r
+		}
+	}
+	return ctx
+}
+
+// outcomes returns all the actions that can be the result of the given system call
+func (ctx *context) outcomes(name string) []string {
+	r, ok := ctx.rules[name]
+	if !ok {
+		return []string{ctx.policy.DefaultPolicyAction}
+	}
+	positive := checker.OrDefault(r.PositiveAction, ctx.policy.DefaultPositiveAction)
+	negative := checker.OrDefault(r.NegativeAction, ctx.policy.DefaultNegativeAction)
+	if v, ok := simplifier.Simplify(r.Body).(tree.BooleanLiteral); ok {
+		if v.Value {
+			return []string{positive}
+		}
+		return []string{negative}
+	}
+	return []string{positive, negative}
+}
+
+func isAllow(action string) bool {
+	a, err := actions.Parse(action)
+	return err == nil && a.Kind == actions.Allow
+}
+
+func (ctx *context) mayAllow(name string) bool {
+	for _, o := range ctx.outcomes(name) {
+		if isAllow(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx *context) alwaysAllows(name string) bool {
+	for _, o := range ctx.outcomes(name) {
+		if !isAllow(o) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsWith returns true if the system call is allowed when its arguments have the given values. The arguments
+// are replaced by the values and the rule is simplified, so rules that can't be decided that way, such as rules
+// that divide by zero, are assumed to allow the system call
+func (ctx *context) allowsWith(name string, args [6]uint64) bool {
+	r, ok := ctx.rules[name]
+	if !ok {
+		return isAllow(ctx.policy.DefaultPolicyAction)
+	}
+	s := &argumentReplacer{args: args}
+	s.RealSelf = s
+	v, decided := simplifier.Simplify(s.Transform(r.Body)).(tree.BooleanLiteral)
+	switch {
+	case !decided:
+		return true
+	case v.Value:
+		return isAllow(checker.OrDefault(r.PositiveAction, ctx.policy.DefaultPositiveAction))
+	}
+	return isAllow(checker.OrDefault(r.NegativeAction, ctx.policy.DefaultNegativeAction))
+}
+
+// argumentReplacer replaces the arguments in an expression with literal values
+type argumentReplacer struct {
+	tree.EmptyTransformer
+	args [6]uint64
+}
+
+// AcceptArgument implements Visitor
+func (s *argumentReplacer) AcceptArgument(v tree.Argument) {
+	value := s.args[v.Index]
+	switch v.Type {
+	case tree.Low:
+		value &= 0xFFFFFFFF
+	case tree.Hi:
+		value >>= 32
+	}
+	s.Result = tree.NumericLiteral{Value: value}
+}
+
+func (ctx *context) positionOf(name string) tree.Position {
+	if r, ok := ctx.rules[name]; ok {
+		return r.Position
+	}
+	return tree.Position{}
+}
+
+// describeAllowance explains why a system call is allowed, for use in messages
+func (ctx *context) describeAllowance(name string) string {
+	if _, ok := ctx.rules[name]; ok {
+		return "by its rule"
+	}
+	return "by the default policy"
+}
+
+func checkDangerousSyscalls(ctx *context) []Finding {
+	result := []Finding{}
+	for _, d := range dangerousSyscalls {
+		if _, known := constants.GetSyscall(d.name); known && ctx.alwaysAllows(d.name) {
+			result = append(result, Finding{
+				Severity:    d.severity,
+				SyscallName: d.name,
+				Position:    ctx.positionOf(d.name),
+				Message:     fmt.Sprintf("%s is always allowed %s", d.name, ctx.describeAllowance(d.name)),
+				Rationale:   d.rationale,
+			})
+		}
+	}
+	return result
+}
+
+// checkCloneNamespaces makes sure that clone is never allowed with one of the namespace flags set. The rule is
+// evaluated with each flag added to the values the rule compares the arguments against, since those are the
+// values where the outcome of the rule can change
+func checkCloneNamespaces(ctx *context) []Finding {
+	if !ctx.mayAllow("clone") {
+		return nil
+	}
+
+	candidates := []uint64{0}
+	if r, ok := ctx.rules["clone"]; ok {
+		candidates = append(candidates, literalsIn(r.Body)...)
+	}
+
+	for _, flag := range namespaceFlags {
+		for _, flags := range candidates {
+			for _, others := range candidates {
+				args := [6]uint64{}
+				for i := range args {
+					args[i] = others
+				}
+				args[0] = flags | flag.value
+				if ctx.allowsWith("clone", args) {
+					return []Finding{{
+						SyscallName: "clone",
+						Position:    ctx.positionOf("clone"),
+						Message:     fmt.Sprintf("clone can be allowed with %s set in the flags in arg0", flag.name),
+					}}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkIoctlRequests makes sure that ioctl is only allowed for requests that are explicitly listed
+func checkIoctlRequests(ctx *context) []Finding {
+	if !ctx.mayAllow("ioctl") {
+		return nil
+	}
+	if r, ok := ctx.rules["ioctl"]; ok {
+		negative := checker.OrDefault(r.NegativeAction, ctx.policy.DefaultNegativeAction)
+		v, constant := simplifier.Simplify(r.Body).(tree.BooleanLiteral)
+		negativeAllows := isAllow(negative) && !(constant && v.Value)
+		if !negativeAllows && restrictsToList(r.Body, 1) {
+			return nil
+		}
+	}
+	return []Finding{{
+		SyscallName: "ioctl",
+		Position:    ctx.positionOf("ioctl"),
+		Message:     "ioctl can be allowed without restricting the request in arg1 to a list of values",
+	}}
+}
+
+func checkBypasses(ctx *context) []Finding {
+	names := []string{}
+	for name := range equivalentSyscalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []Finding{}
+	for _, name := range names {
+		if _, restricted := ctx.rules[name]; !restricted || ctx.alwaysAllows(name) {
+			continue
+		}
+		for _, alternative := range equivalentSyscalls[name] {
+			if _, known := constants.GetSyscall(alternative); known && ctx.alwaysAllows(alternative) {
+				result = append(result, Finding{
+					SyscallName: name,
+					Position:    ctx.positionOf(name),
+					Message:     fmt.Sprintf("%s is restricted, but the equivalent %s is always allowed %s", name, alternative, ctx.describeAllowance(alternative)),
+				})
+			}
+		}
+	}
+	return result
+}
+
+// restrictsToList returns true if the expression can only be true when the argument is equal to one of
+// a list of values, given with == or in()
+func restrictsToList(x tree.Expression, index int) bool {
+	switch v := x.(type) {
+	case tree.And:
+		return restrictsToList(v.Left, index) || restrictsToList(v.Right, index)
+	case tree.Or:
+		return restrictsToList(v.Left, index) && restrictsToList(v.Right, index)
+	case tree.Comparison:
+		return v.Op == tree.EQL && (isArgumentAndLiteral(v.Left, v.Right, index) || isArgumentAndLiteral(v.Right, v.Left, index))
+	case tree.Inclusion:
+		if !v.Positive || !isArgument(v.Left, index) {
+			return false
+		}
+		for _, r := range v.Rights {
+			if _, ok := r.(tree.NumericLiteral); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isArgument returns true for the full or lower half of the argument, since the upper half alone doesn't restrict the value
+func isArgument(x tree.Numeric, index int) bool {
+	a, ok := x.(tree.Argument)
+	return ok && a.Index == index && a.Type != tree.Hi
+}
+
+func isArgumentAndLiteral(arg, lit tree.Numeric, index int) bool {
+	_, ok := lit.(tree.NumericLiteral)
+	return ok && isArgument(arg, index)
+}
+
+// literalsIn returns all the different numbers used in the expression
+func literalsIn(x tree.Expression) []uint64 {
+	lf := &literalFinder{}
+	x.Accept(lf)
+	return lf.found
+}
+
+type literalFinder struct {
+	found []uint64
+}
+
+// AcceptAnd implements Visitor
+func (lf *literalFinder) AcceptAnd(v tree.And) {
+	v.Left.Accept(lf)
+	v.Right.Accept(lf)
+}
+
+// AcceptArgument implements Visitor
+func (lf *literalFinder) AcceptArgument(v tree.Argument) {}
+
+// AcceptArithmetic implements Visitor
+func (lf *literalFinder) AcceptArithmetic(v tree.Arithmetic) {
+	v.Left.Accept(lf)
+	v.Right.Accept(lf)
+}
+
+// AcceptBinaryNegation implements Visitor
+func (lf *literalFinder) AcceptBinaryNegation(v tree.BinaryNegation) {
+	v.Operand.Accept(lf)
+}
+
+// AcceptBooleanLiteral implements Visitor
+func (lf *literalFinder) AcceptBooleanLiteral(v tree.BooleanLiteral) {}
+
+// AcceptCall implements Visitor
+func (lf *literalFinder) AcceptCall(v tree.Call) {
+	for _, a := range v.Args {
+		a.Accept(lf)
+	}
+}
+
+// AcceptComparison implements Visitor
+func (lf *literalFinder) AcceptComparison(v tree.Comparison) {
+	v.Left.Accept(lf)
+	v.Right.Accept(lf)
+}
+
+// AcceptInclusion implements Visitor
+func (lf *literalFinder) AcceptInclusion(v tree.Inclusion) {
+	v.Left.Accept(lf)
+	for _, r := range v.Rights {
+		r.Accept(lf)
+	}
+}
+
+// AcceptNegation implements Visitor
+func (lf *literalFinder) AcceptNegation(v tree.Negation) {
+	v.Operand.Accept(lf)
+}
+
+// AcceptNumericLiteral implements Visitor
+func (lf *literalFinder) AcceptNumericLiteral(v tree.NumericLiteral) {
+	for _, f := range lf.found {
+		if f == v.Value {
+			return
+		}
+	}
+	lf.found = append(lf.found, v.Value)
+}
+
+// AcceptOr implements Visitor
+func (lf *literalFinder) AcceptOr(v tree.Or) {
+	v.Left.Accept(lf)
+	v.Right.Accept(lf)
+}
+
+// AcceptVariable implements Visitor
+func (lf *literalFinder) AcceptVariable(v tree.Variable) {}
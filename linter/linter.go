@@ -0,0 +1,101 @@
+// Package linter looks for patterns in policies that are valid, but allow things that are risky from a security perspective.
+// Each finding comes from a check with a stable code, a severity and a rationale explaining why the pattern is dangerous.
+// Findings can be suppressed for a specific rule with the same "# gosecco:ignore" comments that are used for checker warnings,
+// and checks can be turned off or given different severities in the Config.
+package linter
+
+import (
+	"fmt"
+
+	"github.com/twtiger/gosecco/checker"
+	"github.com/twtiger/gosecco/tree"
+)
+
+// Severity describes how dangerous a finding is
+type Severity int
+
+// The available severities, from the least to the most severe
+const (
+	Low Severity = iota + 1
+	Medium
+	High
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	}
+	return fmt.Sprintf("<unknown severity %d>", int(s))
+}
+
+// Finding represents one risky pattern found in a policy. The position will only be known
+// if the finding is about a specific rule
+type Finding struct {
+	Code        string
+	Severity    Severity
+	SyscallName string
+	Position    tree.Position
+	Message     string
+	Rationale   string
+}
+
+func (f Finding) String() string {
+	result := fmt.Sprintf("[%s] %s (%s, %s)", f.SyscallName, f.Message, f.Code, f.Severity)
+	if f.Position.IsKnown() {
+		return fmt.Sprintf("%s: %s", f.Position, result)
+	}
+	return result
+}
+
+// Config tweaks which findings are reported. The zero value reports everything with the default severities
+type Config struct {
+	// Disabled contains the codes of the checks that should not be run
+	Disabled []string
+	// Severities overrides the severity of the findings of the checks with the given codes
+	Severities map[string]Severity
+	// MinimumSeverity makes the linter leave out all findings less severe than this
+	MinimumSeverity Severity
+}
+
+func (c Config) isDisabled(code string) bool {
+	for _, d := range c.Disabled {
+		if d == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint takes a unified policy that has passed the checker and returns all findings
+func Lint(p tree.Policy, c Config) []Finding {
+	result := []Finding{}
+	ctx := newContext(p)
+	for _, ch := range allChecks {
+		if c.isDisabled(ch.code) {
+			continue
+		}
+		for _, f := range ch.run(ctx) {
+			f.Code = ch.code
+			if f.Severity == 0 {
+				f.Severity = ch.severity
+			}
+			if s, ok := c.Severities[ch.code]; ok {
+				f.Severity = s
+			}
+			if f.Rationale == "" {
+				f.Rationale = ch.rationale
+			}
+			r := ctx.rules[f.SyscallName]
+			if f.Severity >= c.MinimumSeverity && !(r != nil && checker.IsSuppressed(r, ch.code)) {
+				result = append(result, f)
+			}
+		}
+	}
+	return result
+}
+
@@ -0,0 +1,138 @@
+package linter
+
+import (
+	"testing"
+
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type LinterSuite struct{}
+
+var _ = Suite(&LinterSuite{})
+
+func whitelist(rules ...*tree.Rule) tree.Policy {
+	return tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", Rules: rules}
+}
+
+func blacklist(rules ...*tree.Rule) tree.Policy {
+	return tree.Policy{DefaultPositiveAction: "kill", DefaultNegativeAction: "allow", DefaultPolicyAction: "allow", Rules: rules}
+}
+
+func eq(ix int, v uint64) tree.Comparison {
+	return tree.Comparison{Op: tree.EQL, Left: tree.Argument{Type: tree.Full, Index: ix}, Right: tree.NumericLiteral{v}}
+}
+
+func descriptions(fs []Finding) []string {
+	result := []string{}
+	for _, f := range fs {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+func (s *LinterSuite) Test_reportsNothingForARestrictiveWhitelist(c *C) {
+	p := whitelist(
+		&tree.Rule{Name: "read", Body: tree.BooleanLiteral{true}},
+		&tree.Rule{Name: "clone", Body: eq(0, 0x11)},
+		&tree.Rule{Name: "ioctl", Body: eq(1, 0x5401)},
+		&tree.Rule{Name: "ptrace", Body: eq(0, 0)},
+	)
+	c.Assert(Lint(p, Config{}), HasLen, 0)
+}
+
+func (s *LinterSuite) Test_reportsDangerousSyscallsThatAreAlwaysAllowed(c *C) {
+	p := whitelist(
+		&tree.Rule{Name: "ptrace", Body: tree.BooleanLiteral{true}, Position: tree.Position{File: "a.policy", Line: 3}},
+		&tree.Rule{Name: "personality", Body: tree.BooleanLiteral{false}, NegativeAction: "allow"},
+	)
+
+	fs := Lint(p, Config{})
+	c.Assert(descriptions(fs), DeepEquals, []string{
+		"a.policy:3: [ptrace] ptrace is always allowed by its rule (S001, high)",
+		"[personality] personality is always allowed by its rule (S001, medium)",
+	})
+	c.Assert(fs[0].Rationale, Matches, "ptrace allows reading and changing the memory.*")
+}
+
+func (s *LinterSuite) Test_reportsDangerousSyscallsAllowedByABlacklist(c *C) {
+	fs := Lint(blacklist(), Config{})
+	c.Assert(len(fs) > 5, Equals, true)
+	c.Assert(fs[0].String(), Equals, "[ptrace] ptrace is always allowed by the default policy (S001, high)")
+}
+
+func (s *LinterSuite) Test_reportsCloneAndIoctlWithoutArgumentRestrictions(c *C) {
+	p := whitelist(
+		&tree.Rule{Name: "clone", Body: eq(1, 0)},
+		&tree.Rule{Name: "ioctl", Body: tree.BooleanLiteral{true}},
+	)
+
+	c.Assert(descriptions(Lint(p, Config{})), DeepEquals, []string{
+		"[clone] clone can be allowed with CLONE_NEWNS set in the flags in arg0 (S002, medium)",
+		"[ioctl] ioctl can be allowed without restricting the request in arg1 to a list of values (S003, medium)",
+	})
+}
+
+func (s *LinterSuite) Test_reportsArgumentRestrictionsThatDontCloseTheRisk(c *C) {
+	p := whitelist(
+		&tree.Rule{Name: "clone", Body: tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Full, Index: 0}, Right: tree.NumericLiteral{0x100}}},
+		&tree.Rule{Name: "ioctl", Body: tree.Comparison{Op: tree.NEQL, Left: tree.Argument{Type: tree.Full, Index: 1}, Right: tree.NumericLiteral{0x5412}}},
+	)
+
+	c.Assert(descriptions(Lint(p, Config{})), DeepEquals, []string{
+		"[clone] clone can be allowed with CLONE_NEWNS set in the flags in arg0 (S002, medium)",
+		"[ioctl] ioctl can be allowed without restricting the request in arg1 to a list of values (S003, medium)",
+	})
+}
+
+func (s *LinterSuite) Test_acceptsCloneWithoutNamespacesAndIoctlWithAListOfRequests(c *C) {
+	namespaces := tree.NumericLiteral{0x7E020000}
+	p := whitelist(
+		&tree.Rule{Name: "clone", Body: tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Low, Index: 0}, Right: namespaces}, Right: tree.NumericLiteral{0}}},
+		&tree.Rule{Name: "ioctl", Body: tree.And{
+			Left:  tree.Inclusion{Positive: true, Left: tree.Argument{Type: tree.Full, Index: 1}, Rights: []tree.Numeric{tree.NumericLiteral{0x5401}, tree.NumericLiteral{0x5413}}},
+			Right: eq(0, 1),
+		}},
+	)
+	c.Assert(Lint(p, Config{}), HasLen, 0)
+
+	p = whitelist(
+		&tree.Rule{Name: "clone", Body: eq(0, 0x11), NegativeAction: "allow"},
+		&tree.Rule{Name: "ioctl", Body: eq(1, 0x5401), NegativeAction: "allow"},
+	)
+	c.Assert(descriptions(Lint(p, Config{})), DeepEquals, []string{
+		"[clone] clone can be allowed with CLONE_NEWNS set in the flags in arg0 (S002, medium)",
+		"[ioctl] ioctl can be allowed without restricting the request in arg1 to a list of values (S003, medium)",
+	})
+}
+
+func (s *LinterSuite) Test_reportsBypassesOfBlacklistedSyscalls(c *C) {
+	p := blacklist(
+		&tree.Rule{Name: "open", Body: tree.BooleanLiteral{true}},
+		&tree.Rule{Name: "execve", Body: tree.BooleanLiteral{true}},
+		&tree.Rule{Name: "execveat", Body: tree.BooleanLiteral{true}},
+	)
+
+	fs := Lint(p, Config{Disabled: []string{"S001", "S002", "S003"}})
+	c.Assert(descriptions(fs), DeepEquals, []string{
+		"[open] open is restricted, but the equivalent openat is always allowed by the default policy (S004, high)",
+	})
+}
+
+func (s *LinterSuite) Test_configurationAndSuppressions(c *C) {
+	p := whitelist(
+		&tree.Rule{Name: "ptrace", Body: tree.BooleanLiteral{true}, Suppressions: "S001"},
+		&tree.Rule{Name: "bpf", Body: tree.BooleanLiteral{true}},
+		&tree.Rule{Name: "ioctl", Body: tree.BooleanLiteral{true}},
+	)
+
+	c.Assert(descriptions(Lint(p, Config{MinimumSeverity: High})), DeepEquals, []string{
+		"[bpf] bpf is always allowed by its rule (S001, high)",
+	})
+	c.Assert(descriptions(Lint(p, Config{Severities: map[string]Severity{"S001": Low}, Disabled: []string{"S003"}})), DeepEquals, []string{
+		"[bpf] bpf is always allowed by its rule (S001, low)",
+	})
+}
@@ -1,6 +1,7 @@
 package native
 
 import (
+	"fmt"
 	"syscall"
 	"unsafe"
 
@@ -24,9 +25,54 @@ func seccomp(op, flags uintptr, uargs unsafe.Pointer) error {
 	return nil
 }
 
+// The flags for installing a filter. They are defined here instead of taken from <linux/seccomp.h>,
+// since older headers don't have all of them
+const (
+	FilterFlagTsync            = 1 << 0
+	FilterFlagLog              = 1 << 1
+	FilterFlagSpecAllow        = 1 << 2
+	FilterFlagNewListener      = 1 << 3
+	FilterFlagTsyncESRCH       = 1 << 4
+	FilterFlagWaitKillableRecv = 1 << 5
+)
+
+// TsyncError is returned when a filter can't be installed on all threads, because one of them is in a
+// state that doesn't allow it, such as having a different filter. ThreadID is the ID of that thread, or 0
+// if the kernel didn't report it, which is the case with FilterFlagTsyncESRCH
+type TsyncError struct {
+	ThreadID int
+}
+
+func (e *TsyncError) Error() string {
+	if e.ThreadID == 0 {
+		return "seccomp filter could not be synchronized to all threads"
+	}
+	return fmt.Sprintf("seccomp filter could not be synchronized to thread %d", e.ThreadID)
+}
+
 // InstallSeccomp will install seccomp using native methods
 func InstallSeccomp(prog *data.SockFprog) error {
-	return seccomp(C.SECCOMP_SET_MODE_FILTER, C.SECCOMP_FILTER_FLAG_TSYNC, unsafe.Pointer(prog))
+	_, err := InstallSeccompWithFlags(prog, FilterFlagTsync)
+	return err
+}
+
+// InstallSeccompWithFlags will install seccomp using native methods with the given FilterFlag values.
+// It returns the file descriptor of the notification listener if FilterFlagNewListener is set, and -1 otherwise
+func InstallSeccompWithFlags(prog *data.SockFprog, flags uintptr) (int, error) {
+	nr, _ := constants.GetSyscall("seccomp")
+	r, _, e := syscall.Syscall(uintptr(nr), C.SECCOMP_SET_MODE_FILTER, flags, uintptr(unsafe.Pointer(prog)))
+	switch {
+	case e == syscall.ESRCH && flags&FilterFlagTsyncESRCH != 0:
+		return -1, &TsyncError{}
+	case e != 0:
+		return -1, e
+	case flags&FilterFlagNewListener != 0:
+		return int(r), nil
+	case r != 0 && flags&FilterFlagTsync != 0:
+		// Without FilterFlagTsyncESRCH, the kernel reports the thread that couldn't be synchronized by returning its ID
+		return -1, &TsyncError{ThreadID: int(r)}
+	}
+	return -1, nil
 }
 
 // prctl is a wrapper for the 'prctl' system call.
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/twtiger/gosecco/constants"
+)
+
+// Reply is what a handler decided for a notification
+type Reply struct {
+	// Errno makes the system call fail, if it isn't 0
+	Errno syscall.Errno
+	// Value is returned from the system call if Errno is 0
+	Value int64
+	// Continue makes the kernel execute the system call instead
+	Continue bool
+	// Sent means the handler has already responded, for example with AddFd and Send set
+	Sent bool
+}
+
+// Handler decides the result of a system call. The listener can be used to add file descriptors to the process
+type Handler func(l *Listener, n *Notification) Reply
+
+// Dispatcher calls the handler registered for the system call of every notification
+type Dispatcher struct {
+	handlers map[uint32]Handler
+	// Default is called for system calls without a handler. If it is nil, they fail with ENOSYS
+	Default Handler
+}
+
+// NewDispatcher returns a dispatcher without any handlers
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[uint32]Handler)}
+}
+
+// Handle registers the handler for the system call with the name
+func (d *Dispatcher) Handle(name string, h Handler) error {
+	nr, ok := constants.GetSyscall(name)
+	if !ok {
+		return fmt.Errorf("invalid syscall name '%s'", name)
+	}
+	d.handlers[nr] = h
+	return nil
+}
+
+func (d *Dispatcher) handlerFor(n *Notification) Handler {
+	if h, ok := d.handlers[uint32(n.Data.NR)]; ok {
+		return h
+	}
+	if d.Default != nil {
+		return d.Default
+	}
+	return func(*Listener, *Notification) Reply {
+		return Reply{Errno: syscall.ENOSYS}
+	}
+}
+
+// Dispatch decides one notification with its handler, and sends the response. A notification that is no
+// longer valid, because the thread making the system call was interrupted or killed, is not an error
+func (d *Dispatcher) Dispatch(l *Listener, n *Notification) error {
+	r := d.handlerFor(n)(l, n)
+	var err error
+	switch {
+	case r.Sent:
+		return nil
+	case r.Continue:
+		err = l.RespondContinue(n.ID)
+	case r.Errno != 0:
+		err = l.RespondErrno(n.ID, r.Errno)
+	default:
+		err = l.RespondValue(n.ID, r.Value)
+	}
+	if err == syscall.ENOENT {
+		return nil
+	}
+	return err
+}
+
+// Serve receives and dispatches notifications one at a time, until no process uses the filter anymore
+func (d *Dispatcher) Serve(l *Listener) error {
+	for {
+		more, err := l.wait()
+		if err != nil || !more {
+			return err
+		}
+		n, err := l.Receive()
+		if err == syscall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.Dispatch(l, n); err != nil {
+			return err
+		}
+	}
+}
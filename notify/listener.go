@@ -0,0 +1,201 @@
+// Package notify implements a supervisor for filters that return the notify action. The kernel stops the
+// thread making such a system call, and sends a notification to a listener file descriptor, which is created
+// when the filter is installed with gosecco.InstallOptions{NewListener: true}. The supervisor then decides
+// the result of the system call - an errno, a value, or letting the kernel continue with it.
+//
+// The listener is usually passed to another process, since the process the filter is installed in can't
+// handle its own notifications from the stopped thread.
+package notify
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+)
+
+// The ioctls on the listener, from <linux/seccomp.h>. They are defined here since older headers don't have them
+const (
+	ioctlRecv    = 0xC0502100 // SECCOMP_IOCTL_NOTIF_RECV
+	ioctlSend    = 0xC0182101 // SECCOMP_IOCTL_NOTIF_SEND
+	ioctlIDValid = 0x40082102 // SECCOMP_IOCTL_NOTIF_ID_VALID
+	ioctlAddFd   = 0x40182103 // SECCOMP_IOCTL_NOTIF_ADDFD
+)
+
+// FlagContinue makes the kernel execute the system call as if the filter had allowed it
+// (SECCOMP_USER_NOTIF_FLAG_CONTINUE)
+const FlagContinue = 1
+
+// The flags for adding a file descriptor to the process making the system call
+const (
+	addFdFlagSetFd = 1 // SECCOMP_ADDFD_FLAG_SETFD
+	addFdFlagSend  = 2 // SECCOMP_ADDFD_FLAG_SEND
+)
+
+// Notification is a system call waiting for a decision, in the layout of struct seccomp_notif
+type Notification struct {
+	ID    uint64
+	Pid   uint32
+	Flags uint32
+	Data  data.SeccompWorkingMemory
+}
+
+// Syscall returns the name of the system call, or an empty string if the number isn't a known system call
+func (n *Notification) Syscall() string {
+	return constants.SyscallNumbers[int(n.Data.NR)]
+}
+
+// Response is the decision for a notification, in the layout of struct seccomp_notif_resp. The system call
+// returns Val if Error is 0, and fails with the errno -Error otherwise
+type Response struct {
+	ID    uint64
+	Val   int64
+	Error int32
+	Flags uint32
+}
+
+// addFd is the layout of struct seccomp_notif_addfd
+type addFd struct {
+	id         uint64
+	flags      uint32
+	srcFd      uint32
+	newFd      uint32
+	newFdFlags uint32
+}
+
+// AddFd describes a file descriptor to add to the process making a system call
+type AddFd struct {
+	// SrcFd is the file descriptor in the supervisor to add
+	SrcFd int
+	// NewFd is the number the file descriptor gets in the target, if SetFd is true. Otherwise the lowest
+	// available number is used
+	NewFd int
+	SetFd bool
+	// Send also responds to the notification, with the new file descriptor as the result of the system call
+	Send bool
+	// CloseOnExec sets O_CLOEXEC on the new file descriptor
+	CloseOnExec bool
+}
+
+// Listener receives and responds to notifications on a listener file descriptor
+type Listener struct {
+	fd int
+}
+
+// NewListener returns a listener for the file descriptor returned when installing a filter
+func NewListener(fd int) *Listener {
+	return &Listener{fd: fd}
+}
+
+// Fd returns the file descriptor of the listener
+func (l *Listener) Fd() int {
+	return l.fd
+}
+
+// Close closes the file descriptor of the listener. Threads waiting for a response fail with ENOSYS
+func (l *Listener) Close() error {
+	return syscall.Close(l.fd)
+}
+
+func (l *Listener) ioctl(request uintptr, arg unsafe.Pointer) (uintptr, error) {
+	r, _, e := syscall.Syscall(syscall.SYS_IOCTL, uintptr(l.fd), request, uintptr(arg))
+	if e != 0 {
+		return 0, e
+	}
+	return r, nil
+}
+
+// Receive waits for the next notification. It fails with ENOENT if the thread that made the system call was
+// interrupted before the notification could be received, in which case the caller should try again
+func (l *Listener) Receive() (*Notification, error) {
+	n := &Notification{}
+	for {
+		_, err := l.ioctl(ioctlRecv, unsafe.Pointer(n))
+		if err != syscall.EINTR {
+			if err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+}
+
+// IDValid checks that the notification is still waiting for a response. It should be called after reading
+// memory of the target process based on a notification, before acting on what was read, since the process
+// could have been killed and its ID reused in between. It returns ENOENT if the notification is no longer valid
+func (l *Listener) IDValid(id uint64) error {
+	_, err := l.ioctl(ioctlIDValid, unsafe.Pointer(&id))
+	return err
+}
+
+// Respond sends the response for a notification. It returns ENOENT if the notification is no longer valid,
+// for example because the thread making the system call was killed
+func (l *Listener) Respond(r Response) error {
+	_, err := l.ioctl(ioctlSend, unsafe.Pointer(&r))
+	return err
+}
+
+// RespondErrno makes the system call fail with the errno
+func (l *Listener) RespondErrno(id uint64, errno syscall.Errno) error {
+	return l.Respond(Response{ID: id, Error: -int32(errno)})
+}
+
+// RespondValue makes the system call return the value
+func (l *Listener) RespondValue(id uint64, val int64) error {
+	return l.Respond(Response{ID: id, Val: val})
+}
+
+// RespondContinue makes the kernel execute the system call. Since the arguments can change after the
+// supervisor has looked at them, this must not be used to allow system calls based on their pointer arguments
+func (l *Listener) RespondContinue(id uint64) error {
+	return l.Respond(Response{ID: id, Flags: FlagContinue})
+}
+
+// AddFd adds a file descriptor to the process that made the system call of the notification, and returns its
+// number in that process
+func (l *Listener) AddFd(id uint64, a AddFd) (int, error) {
+	arg := addFd{id: id, srcFd: uint32(a.SrcFd)}
+	if a.SetFd {
+		arg.flags |= addFdFlagSetFd
+		arg.newFd = uint32(a.NewFd)
+	}
+	if a.Send {
+		arg.flags |= addFdFlagSend
+	}
+	if a.CloseOnExec {
+		arg.newFdFlags = syscall.O_CLOEXEC
+	}
+	r, err := l.ioctl(ioctlAddFd, unsafe.Pointer(&arg))
+	return int(r), err
+}
+
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+const (
+	pollIn  = 0x1
+	pollHup = 0x10
+)
+
+// wait waits until there is a notification to receive, and returns false if there will be no more
+// notifications because no process uses the filter anymore
+func (l *Listener) wait() (bool, error) {
+	for {
+		p := pollFd{fd: int32(l.fd), events: pollIn}
+		_, _, e := syscall.Syscall(syscall.SYS_POLL, uintptr(unsafe.Pointer(&p)), 1, ^uintptr(0))
+		switch {
+		case e == syscall.EINTR:
+			continue
+		case e != 0:
+			return false, e
+		case p.revents&pollIn != 0:
+			return true, nil
+		case p.revents&pollHup != 0:
+			return false, nil
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/parser"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type NotifySuite struct{}
+
+var _ = Suite(&NotifySuite{})
+
+// childEnv marks the test binary started as the process that installs the filter
+const childEnv = "GOSECCO_NOTIFY_CHILD"
+
+const childPolicy = "" +
+	"getppid[+notify]: 1\n" +
+	"getuid[+notify]: 1\n" +
+	"getgid[+notify]: 1\n" +
+	"getpgrp[+notify]: 1\n" +
+	"dup[+notify]: 1\n"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(childEnv) != "" {
+		runChild()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runChild installs the filter on its thread, sends the listener to the test over the socket it got as file
+// descriptor 3, and prints the results of the system calls the filter sends to the supervisor
+func runChild() {
+	runtime.LockOSThread()
+	program, err := gosecco.PrepareSource(&parser.StringSource{Name: "child.policy", Content: childPolicy},
+		gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "allow"})
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	fd, err := gosecco.InstallWithOptions(program, gosecco.InstallOptions{CurrentThreadOnly: true, NewListener: true})
+	if err != nil {
+		fmt.Printf("unsupported: %s\n", err)
+		return
+	}
+	if err := syscall.Sendmsg(3, []byte{0}, syscall.UnixRights(fd), nil, 0); err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+
+	for _, call := range []struct {
+		name string
+		nr   uintptr
+	}{
+		{"getppid", syscall.SYS_GETPPID},
+		{"getuid", syscall.SYS_GETUID},
+		{"getgid", syscall.SYS_GETGID},
+		{"getpgrp", syscall.SYS_GETPGRP},
+	} {
+		r, _, e := syscall.RawSyscall(call.nr, 0, 0, 0)
+		if e != 0 {
+			fmt.Printf("%s: %s\n", call.name, e)
+		} else {
+			fmt.Printf("%s: %d\n", call.name, r)
+		}
+	}
+
+	r, _, e := syscall.RawSyscall(syscall.SYS_DUP, 0, 0, 0)
+	if e != 0 {
+		fmt.Printf("dup: %s\n", e)
+		return
+	}
+	content := make([]byte, 64)
+	n, _ := syscall.Read(int(r), content)
+	fmt.Printf("dup: %s\n", content[:n])
+}
+
+// startChild starts the child process and returns the listener it sent, or nil if the kernel doesn't
+// support the notify action
+func startChild(c *C, out *bytes.Buffer) (*exec.Cmd, *Listener) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	c.Assert(err, IsNil)
+	defer syscall.Close(fds[0])
+	childSocket := os.NewFile(uintptr(fds[1]), "child")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.ExtraFiles = []*os.File{childSocket}
+	cmd.Stdout = out
+	c.Assert(cmd.Start(), IsNil)
+	childSocket.Close()
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := syscall.Recvmsg(fds[0], make([]byte, 1), oob, 0)
+	c.Assert(err, IsNil)
+	if oobn == 0 {
+		cmd.Wait()
+		return nil, nil
+	}
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	c.Assert(err, IsNil)
+	received, err := syscall.ParseUnixRights(&messages[0])
+	c.Assert(err, IsNil)
+	return cmd, NewListener(received[0])
+}
+
+func (s *NotifySuite) Test_supervisesSystemCallsOfAChildProcess(c *C) {
+	path := filepath.Join(c.MkDir(), "injected")
+	c.Assert(ioutil.WriteFile(path, []byte("hello from the supervisor"), 0644), IsNil)
+	injected, err := os.Open(path)
+	c.Assert(err, IsNil)
+	defer injected.Close()
+
+	out := &bytes.Buffer{}
+	cmd, l := startChild(c, out)
+	if l == nil {
+		c.Skip("the kernel doesn't support user notifications: " + out.String())
+	}
+	defer l.Close()
+
+	d := NewDispatcher()
+	c.Assert(d.Handle("getppid", func(l *Listener, n *Notification) Reply {
+		if n.Syscall() != "getppid" || l.IDValid(n.ID) != nil {
+			return Reply{Errno: syscall.EINVAL}
+		}
+		return Reply{Value: 4242}
+	}), IsNil)
+	c.Assert(d.Handle("getuid", func(*Listener, *Notification) Reply {
+		return Reply{Errno: syscall.EPERM}
+	}), IsNil)
+	c.Assert(d.Handle("getgid", func(*Listener, *Notification) Reply {
+		return Reply{Continue: true}
+	}), IsNil)
+	c.Assert(d.Handle("dup", func(l *Listener, n *Notification) Reply {
+		if _, err := l.AddFd(n.ID, AddFd{SrcFd: int(injected.Fd()), Send: true, CloseOnExec: true}); err != nil {
+			return Reply{Errno: err.(syscall.Errno)}
+		}
+		return Reply{Sent: true}
+	}), IsNil)
+
+	c.Assert(d.Serve(l), IsNil)
+	c.Assert(cmd.Wait(), IsNil)
+	c.Assert(out.String(), Equals, fmt.Sprintf(""+
+		"getppid: 4242\n"+
+		"getuid: operation not permitted\n"+
+		"getgid: %d\n"+
+		"getpgrp: function not implemented\n"+
+		"dup: hello from the supervisor\n", os.Getgid()))
+}
+
+func (s *NotifySuite) Test_handlersAreRegisteredForKnownSystemCalls(c *C) {
+	err := NewDispatcher().Handle("not_a_syscall", func(*Listener, *Notification) Reply { return Reply{} })
+	c.Assert(err, ErrorMatches, "invalid syscall name 'not_a_syscall'")
+}
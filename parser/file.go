@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/twtiger/gosecco/tree"
 )
@@ -17,32 +18,67 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("%s:%d: %s", e.file, e.line, e.originalError)
 }
 
+// Position returns the place in the source where the error happened
+func (e *ParseError) Position() tree.Position {
+	return tree.Position{File: e.file, Line: e.line}
+}
+
 func parseLines(path string, lines []string) (tree.RawPolicy, error) {
+	rp, errs := parseAllLines(path, lines, true)
+	if len(errs) > 0 {
+		return tree.RawPolicy{}, errs[0]
+	}
+	return rp, nil
+}
+
+// parseAllLines parses the lines and collects the errors for the lines that can't be parsed. If stopAtError is false,
+// those lines are skipped and the rest of the lines are still parsed
+func parseAllLines(path string, lines []string, stopAtError bool) (tree.RawPolicy, []*ParseError) {
 	result := []interface{}{}
+	errs := []*ParseError{}
+	// Suppressions apply to the rule directly following the comments they are in
+	suppressions := []string{}
 
 	for ix, l := range lines {
-		switch lineType(l) {
-		case commentLine: //ignore
+		if stopAtError && len(errs) > 0 {
+			break
+		}
+		lt := lineType(l)
+		if lt != commentLine && lt != ruleLine {
+			suppressions = []string{}
+		}
+		switch lt {
+		case commentLine:
+			if codes, ok := suppressionsIn(l); ok {
+				suppressions = append(suppressions, codes...)
+			}
 		case emptyLine: //ignore
 		case ruleLine:
 			parsedRule, err := parseRule(l)
 			if err != nil {
-				return tree.RawPolicy{}, &ParseError{err, path, ix}
+				errs = append(errs, &ParseError{err, path, ix})
+				suppressions = []string{}
+				continue
 			}
+			parsedRule.Position = tree.Position{File: path, Line: ix}
+			parsedRule.Suppressions = strings.Join(suppressions, " ")
+			suppressions = []string{}
 			result = append(result, parsedRule)
 		case assignmentLine, defaultAssignmentLine:
 			parsedBinding, err := parseBinding(l)
 			if err != nil {
-				return tree.RawPolicy{}, &ParseError{err, path, ix}
+				errs = append(errs, &ParseError{err, path, ix})
+				continue
 			}
+			parsedBinding.Position = tree.Position{File: path, Line: ix}
 			result = append(result, parsedBinding)
 
 		case unknownLine:
-			return tree.RawPolicy{}, &ParseError{fmt.Errorf("Couldn't parse line: '%s' - it doesn't match any kind of valid syntax", l), path, ix}
+			errs = append(errs, &ParseError{fmt.Errorf("Couldn't parse line: '%s' - it doesn't match any kind of valid syntax", l), path, ix})
 		}
 	}
 
-	return tree.RawPolicy{RuleOrMacros: result}, nil
+	return tree.RawPolicy{RuleOrMacros: result}, errs
 }
 
 // ParseFile will parse the given file and return a raw parse tree or the error generated
@@ -61,3 +97,10 @@ func ParseString(str string) (tree.RawPolicy, error) {
 func Parse(s Source) (tree.RawPolicy, error) {
 	return s.Parse()
 }
+
+// ParseLenient will parse the given string in the same way as Parse, but lines that can't be parsed are left out
+// instead of stopping the parsing. It returns the raw parse tree for the rest of the lines and the errors for the
+// lines left out, so that tools can report every problem in a source at once
+func ParseLenient(s *StringSource) (tree.RawPolicy, []*ParseError) {
+	return parseAllLines(s.Name, strings.Split(s.Content, "\n"), false)
+}
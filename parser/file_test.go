@@ -29,20 +29,24 @@ func (s *FileSuite) Test_ParseFile(c *C) {
 			tree.Macro{
 				Name:          "DEFAULT_POSITIVE",
 				ArgumentNames: nil,
-				Body:          tree.Variable{Name: "kill"}},
+				Body:          tree.Variable{Name: "kill"},
+				Position:      tree.Position{File: getActualTestFolder() + "/simple_test_policy", Line: 3}},
 			tree.Macro{
 				Name:          "something",
 				ArgumentNames: []string{"a"},
-				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}}},
+				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}},
+				Position:      tree.Position{File: getActualTestFolder() + "/simple_test_policy", Line: 5}},
 			tree.Macro{
 				Name:          "VAL",
 				ArgumentNames: nil,
-				Body:          tree.NumericLiteral{Value: 0x2a}},
+				Body:          tree.NumericLiteral{Value: 0x2a},
+				Position:      tree.Position{File: getActualTestFolder() + "/simple_test_policy", Line: 6}},
 			tree.Rule{
 				Name:           "read",
 				PositiveAction: "",
 				NegativeAction: "",
-				Body:           tree.NumericLiteral{Value: 0x2a}},
+				Body:           tree.NumericLiteral{Value: 0x2a},
+				Position:       tree.Position{File: getActualTestFolder() + "/simple_test_policy", Line: 8}},
 		}})
 }
 
@@ -62,20 +66,24 @@ func (s *FileSuite) Test_ParseString(c *C) {
 			tree.Macro{
 				Name:          "DEFAULT_POSITIVE",
 				ArgumentNames: nil,
-				Body:          tree.Variable{Name: "kill"}},
+				Body:          tree.Variable{Name: "kill"},
+				Position:      tree.Position{File: "<string>", Line: 2}},
 			tree.Macro{
 				Name:          "something",
 				ArgumentNames: []string{"a"},
-				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}}},
+				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}},
+				Position:      tree.Position{File: "<string>", Line: 4}},
 			tree.Macro{
 				Name:          "VAL",
 				ArgumentNames: nil,
-				Body:          tree.NumericLiteral{Value: 0x2a}},
+				Body:          tree.NumericLiteral{Value: 0x2a},
+				Position:      tree.Position{File: "<string>", Line: 5}},
 			tree.Rule{
 				Name:           "read",
 				PositiveAction: "",
 				NegativeAction: "",
-				Body:           tree.NumericLiteral{Value: 0x2a}},
+				Body:           tree.NumericLiteral{Value: 0x2a},
+				Position:       tree.Position{File: "<string>", Line: 7}},
 		}})
 }
 
@@ -89,25 +97,30 @@ func (s *FileSuite) Test_Parse_fromCombinedSource(c *C) {
 			tree.Macro{
 				Name:          "DEFAULT_POSITIVE",
 				ArgumentNames: nil,
-				Body:          tree.Variable{Name: "kill"}},
+				Body:          tree.Variable{Name: "kill"},
+				Position:      tree.Position{File: source1.Filename, Line: 3}},
 			tree.Macro{
 				Name:          "something",
 				ArgumentNames: []string{"a"},
-				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}}},
+				Body:          tree.Arithmetic{Op: 0, Left: tree.NumericLiteral{Value: 0x1}, Right: tree.Variable{Name: "a"}},
+				Position:      tree.Position{File: source1.Filename, Line: 5}},
 			tree.Macro{
 				Name:          "VAL",
 				ArgumentNames: nil,
-				Body:          tree.NumericLiteral{Value: 0x2a}},
+				Body:          tree.NumericLiteral{Value: 0x2a},
+				Position:      tree.Position{File: source1.Filename, Line: 6}},
 			tree.Rule{
 				Name:           "read",
 				PositiveAction: "",
 				NegativeAction: "",
-				Body:           tree.NumericLiteral{Value: 0x2a}},
+				Body:           tree.NumericLiteral{Value: 0x2a},
+				Position:       tree.Position{File: source1.Filename, Line: 8}},
 			tree.Rule{
 				Name:           "write",
 				PositiveAction: "",
 				NegativeAction: "",
-				Body:           tree.NumericLiteral{Value: 0x2b}},
+				Body:           tree.NumericLiteral{Value: 0x2b},
+				Position:       tree.Position{File: "<tmp1>", Line: 0}},
 		}})
 }
 
@@ -116,3 +129,36 @@ func (s *FileSuite) Test_ParseFile_failing(c *C) {
 	c.Assert(rp.RuleOrMacros, IsNil)
 	c.Assert(ee, ErrorMatches, ".*parser/test_policies/failing_test_policy:1: unexpected end of line")
 }
+
+func (s *FileSuite) Test_ParseLenient_skipsTheLinesThatCantBeParsed(c *C) {
+	rp, errs := ParseLenient(&StringSource{"<tmp1>", "read: arg0 ==\nVAL = 42\nfoo\nwrite: 43\n"})
+	c.Assert(rp, DeepEquals, tree.RawPolicy{
+		RuleOrMacros: []interface{}{
+			tree.Macro{
+				Name:     "VAL",
+				Body:     tree.NumericLiteral{Value: 0x2a},
+				Position: tree.Position{File: "<tmp1>", Line: 1}},
+			tree.Rule{
+				Name:     "write",
+				Body:     tree.NumericLiteral{Value: 0x2b},
+				Position: tree.Position{File: "<tmp1>", Line: 3}},
+		}})
+	c.Assert(errs, HasLen, 2)
+	c.Assert(errs[0], ErrorMatches, "<tmp1>:0: unexpected end of line")
+	c.Assert(errs[1], ErrorMatches, "<tmp1>:2: Couldn't parse line: 'foo' - it doesn't match any kind of valid syntax")
+}
+
+func (s *FileSuite) Test_Parse_collectsSuppressionsForTheFollowingRule(c *C) {
+	rp, err := ParseString("# gosecco:ignore W002\n" +
+		"#   gosecco:ignore W003, W005\n" +
+		"read: 1\n" +
+		"# gosecco:ignore W001\n" +
+		"\n" +
+		"write: 1\n" +
+		"# a normal comment\n" +
+		"close: 1\n")
+	c.Assert(err, IsNil)
+	c.Assert(rp.RuleOrMacros[0].(tree.Rule).Suppressions, Equals, "W002 W003 W005")
+	c.Assert(rp.RuleOrMacros[1].(tree.Rule).Suppressions, Equals, "")
+	c.Assert(rp.RuleOrMacros[2].(tree.Rule).Suppressions, Equals, "")
+}
@@ -18,6 +18,19 @@ func isComment(s string) bool {
 	return strings.HasPrefix(strings.TrimSpace(s), "#")
 }
 
+const suppressionMarker = "gosecco:ignore"
+
+// suppressionsIn returns the warning codes listed in a comment of the form "# gosecco:ignore W001 W002"
+func suppressionsIn(s string) ([]string, bool) {
+	c := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if !strings.HasPrefix(c, suppressionMarker) {
+		return nil, false
+	}
+	return strings.FieldsFunc(strings.TrimPrefix(c, suppressionMarker), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}), true
+}
+
 func isRule(s string) bool {
 	return len(strings.SplitN(s, ":", 2)) == 2
 }
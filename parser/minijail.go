@@ -0,0 +1,276 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/twtiger/gosecco/tree"
+)
+
+// MinijailFileSource represents a policy file written in the minijail/Chromium .policy syntax.
+// It will be translated into the same raw policy the gosecco syntax would generate
+type MinijailFileSource struct {
+	// Filename is the name of the file to parse definitions from
+	Filename string
+}
+
+// MinijailStringSource contains a policy in the minijail/Chromium .policy syntax as a string
+type MinijailStringSource struct {
+	// Name is the name to report for this string during parsing errors
+	Name string
+	// Content is the actual string containing the policy
+	Content string
+}
+
+// Parse implements the Source interface by parsing the minijail file
+func (s *MinijailFileSource) Parse() (tree.RawPolicy, error) {
+	return parseMinijailFile(s.Filename, nil)
+}
+
+// Parse implements the Source interface by parsing the minijail string.
+// Relative include paths will be resolved against the current working directory
+func (s *MinijailStringSource) Parse() (tree.RawPolicy, error) {
+	return parseMinijailLines(s.Name, ".", strings.Split(s.Content, "\n"), nil)
+}
+
+var (
+	minijailDirectiveRE = regexp.MustCompile(`^@([[:word:]]+)(?:[[:space:]]+(.*))?$`)
+	minijailRuleRE      = regexp.MustCompile(`^([[:word:]]+)[[:space:]]*:[[:space:]]*(.*)$`)
+	minijailReturnRE    = regexp.MustCompile(`^return[[:space:]]+([[:word:]]+)$`)
+	minijailAtomRE      = regexp.MustCompile(`^arg([0-9]+)[[:space:]]*(==|!=|<=|>=|<|>|&|in[[:space:]])[[:space:]]*(.+)$`)
+)
+
+// minijailActions maps the minijail action keywords to the gosecco equivalent.
+// An empty value means that the action exists in minijail but has no equivalent here
+var minijailActions = map[string]string{
+	"1":            "allow",
+	"allow":        "allow",
+	"kill":         "kill",
+	"kill-thread":  "kill",
+	"trap":         "trap",
+	"trace":        "trace",
+	"kill-process": "",
+	"log":          "",
+	"user-notify":  "notify",
+}
+
+func parseMinijailFile(path string, including []string) (tree.RawPolicy, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return tree.RawPolicy{}, err
+	}
+	for _, inc := range including {
+		if inc == abs {
+			return tree.RawPolicy{}, fmt.Errorf("recursive include of '%s'", path)
+		}
+	}
+
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return tree.RawPolicy{}, err
+	}
+	return parseMinijailLines(path, filepath.Dir(path), strings.Split(string(file), "\n"), append(including, abs))
+}
+
+func parseMinijailLines(path, dir string, lines []string, including []string) (tree.RawPolicy, error) {
+	result := []interface{}{}
+
+	for ix, l := range lines {
+		if i := strings.Index(l, "#"); i != -1 {
+			l = l[:i]
+		}
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+
+		if strings.HasPrefix(l, "@") {
+			included, err := parseMinijailDirective(l, dir, including)
+			if err != nil {
+				if _, ok := err.(*ParseError); ok {
+					return tree.RawPolicy{}, err
+				}
+				return tree.RawPolicy{}, &ParseError{err, path, ix}
+			}
+			result = append(result, included...)
+			continue
+		}
+
+		r, err := parseMinijailRule(l)
+		if err != nil {
+			return tree.RawPolicy{}, &ParseError{err, path, ix}
+		}
+		r.Position = tree.Position{File: path, Line: ix}
+		result = append(result, r)
+	}
+
+	return tree.RawPolicy{RuleOrMacros: result}, nil
+}
+
+func parseMinijailDirective(l, dir string, including []string) ([]interface{}, error) {
+	match := minijailDirectiveRE.FindStringSubmatch(l)
+	if match == nil {
+		return nil, fmt.Errorf("invalid minijail directive: '%s'", l)
+	}
+
+	switch match[1] {
+	case "include":
+		file := strings.TrimSpace(match[2])
+		if file == "" {
+			return nil, fmt.Errorf("@include needs a file name")
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		rp, err := parseMinijailFile(file, including)
+		return rp.RuleOrMacros, err
+	case "frequency":
+		// Frequency files only guide the order of the generated BPF in minijail,
+		// they have no influence on the semantics of the policy
+		return nil, nil
+	}
+	return nil, fmt.Errorf("the minijail directive '@%s' is not supported", match[1])
+}
+
+func parseMinijailRule(l string) (tree.Rule, error) {
+	match := minijailRuleRE.FindStringSubmatch(l)
+	if match == nil {
+		if strings.HasPrefix(l, "{") {
+			return tree.Rule{}, fmt.Errorf("syscall groups are not supported: '%s'", l)
+		}
+		return tree.Rule{}, fmt.Errorf("Couldn't parse line: '%s' - it doesn't match any kind of valid minijail syntax", l)
+	}
+
+	rule := tree.Rule{Name: match[1]}
+	value := strings.TrimSpace(match[2])
+	if value == "" {
+		return tree.Rule{}, fmt.Errorf("No expression specified for rule: %s", rule.Name)
+	}
+
+	if act, ok, err := parseMinijailAction(value); ok {
+		if err != nil {
+			return tree.Rule{}, err
+		}
+		rule.PositiveAction = act
+		rule.Body = tree.BooleanLiteral{true}
+		return rule, nil
+	}
+
+	parts := strings.SplitN(value, ";", 2)
+	if len(parts) == 2 {
+		act, ok, err := parseMinijailAction(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return tree.Rule{}, err
+		}
+		if !ok || act == "allow" {
+			return tree.Rule{}, fmt.Errorf("expected a return action after ';', found '%s'", strings.TrimSpace(parts[1]))
+		}
+		rule.NegativeAction = act
+	}
+
+	x, err := parseMinijailExpression(parts[0])
+	if err != nil {
+		return tree.Rule{}, err
+	}
+	rule.PositiveAction = "allow"
+	rule.Body = x
+	return rule, nil
+}
+
+// parseMinijailAction returns the gosecco action for the given minijail action,
+// and whether the string was recognized as an action at all
+func parseMinijailAction(s string) (string, bool, error) {
+	if match := minijailReturnRE.FindStringSubmatch(s); match != nil {
+		if _, err := strconv.ParseUint(match[1], 0, 16); err == nil {
+			return match[1], true, nil
+		}
+		if _, err := strconv.ParseUint(match[1], 0, 64); err == nil {
+			return "", true, fmt.Errorf("the errno value %s is out of range", match[1])
+		}
+		return match[1], true, nil
+	}
+
+	act, ok := minijailActions[s]
+	if ok && act == "" {
+		return "", true, fmt.Errorf("the minijail action '%s' has no equivalent in gosecco", s)
+	}
+	return act, ok, nil
+}
+
+func parseMinijailExpression(s string) (tree.Expression, error) {
+	var result tree.Expression
+	for _, disjunct := range strings.Split(s, "||") {
+		var conj tree.Expression
+		for _, atom := range strings.Split(disjunct, "&&") {
+			x, err := parseMinijailAtom(strings.TrimSpace(atom))
+			if err != nil {
+				return nil, err
+			}
+			if conj == nil {
+				conj = x
+			} else {
+				conj = tree.And{Left: conj, Right: x}
+			}
+		}
+		if result == nil {
+			result = conj
+		} else {
+			result = tree.Or{Left: result, Right: conj}
+		}
+	}
+	return result, nil
+}
+
+func parseMinijailAtom(s string) (tree.Expression, error) {
+	match := minijailAtomRE.FindStringSubmatch(s)
+	if match == nil {
+		return nil, fmt.Errorf("unable to translate minijail expression: '%s'", s)
+	}
+
+	index, err := strconv.Atoi(match[1])
+	if err != nil || index > 5 {
+		return nil, fmt.Errorf("invalid argument index in minijail expression: '%s'", s)
+	}
+
+	value, _, _, err := parseExpressionForBinding(match[3])
+	if err != nil {
+		return nil, err
+	}
+
+	arg := tree.Argument{Type: tree.Full, Index: index}
+	switch op := strings.TrimSpace(match[2]); op {
+	case "&":
+		// In minijail '&' means that any of the bits are set, which is what '&?' means in gosecco
+		return tree.Comparison{Op: tree.BITSET, Left: arg, Right: value}, nil
+	case "in":
+		// In minijail 'in' means that the argument is a subset of the mask - not that it is a member of a set.
+		// Since arithmetic can't be done on full arguments, we have to check each half on its own
+		return tree.And{
+			Left:  noBitsOutsideMask(tree.Argument{Type: tree.Low, Index: index}, value),
+			Right: noBitsOutsideMask(tree.Argument{Type: tree.Hi, Index: index}, tree.Arithmetic{Op: tree.RSH, Left: value, Right: tree.NumericLiteral{32}}),
+		}, nil
+	default:
+		for k, v := range tree.ComparisonNames {
+			if v == op {
+				return tree.Comparison{Op: k, Left: arg, Right: value}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unable to translate minijail expression: '%s'", s)
+}
+
+func noBitsOutsideMask(arg tree.Argument, mask tree.Numeric) tree.Boolean {
+	return tree.Comparison{
+		Op: tree.EQL,
+		Left: tree.Arithmetic{
+			Op:    tree.BINAND,
+			Left:  arg,
+			Right: tree.Arithmetic{Op: tree.BINAND, Left: tree.BinaryNegation{mask}, Right: tree.NumericLiteral{0xFFFFFFFF}},
+		},
+		Right: tree.NumericLiteral{0},
+	}
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+type MinijailSuite struct{}
+
+var _ = Suite(&MinijailSuite{})
+
+func parseMinijail(s string) (tree.RawPolicy, error) {
+	return Parse(&MinijailStringSource{"<minijail>", s})
+}
+
+func (s *MinijailSuite) Test_parsesActions(c *C) {
+	rp, err := parseMinijail("read: 1\nwrite: allow\nopen: kill\nclose: trap\nfork: return 1\nexit: return EPERM\n")
+	c.Assert(err, IsNil)
+	c.Assert(rp.RuleOrMacros, DeepEquals, []interface{}{
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 0}, Name: "read", PositiveAction: "allow", Body: tree.BooleanLiteral{true}},
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 1}, Name: "write", PositiveAction: "allow", Body: tree.BooleanLiteral{true}},
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 2}, Name: "open", PositiveAction: "kill", Body: tree.BooleanLiteral{true}},
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 3}, Name: "close", PositiveAction: "trap", Body: tree.BooleanLiteral{true}},
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 4}, Name: "fork", PositiveAction: "1", Body: tree.BooleanLiteral{true}},
+		tree.Rule{Position: tree.Position{File: "<minijail>", Line: 5}, Name: "exit", PositiveAction: "EPERM", Body: tree.BooleanLiteral{true}},
+	})
+}
+
+func (s *MinijailSuite) Test_parsesExpressionsWithDisjunctionsOfConjunctions(c *C) {
+	rp, err := parseMinijail("read: arg0 == 1 || arg1 != 2 && arg2 <= FOO")
+	c.Assert(err, IsNil)
+	c.Assert(rp.RuleOrMacros[0], DeepEquals, tree.Rule{
+		Position:       tree.Position{File: "<minijail>", Line: 0},
+		Name:           "read",
+		PositiveAction: "allow",
+		Body: tree.Or{
+			Left: tree.Comparison{Op: tree.EQL, Left: tree.Argument{Type: tree.Full, Index: 0}, Right: tree.NumericLiteral{1}},
+			Right: tree.And{
+				Left:  tree.Comparison{Op: tree.NEQL, Left: tree.Argument{Type: tree.Full, Index: 1}, Right: tree.NumericLiteral{2}},
+				Right: tree.Comparison{Op: tree.LTE, Left: tree.Argument{Type: tree.Full, Index: 2}, Right: tree.Variable{"FOO"}},
+			},
+		},
+	})
+}
+
+func (s *MinijailSuite) Test_translatesBitmaskToBitset(c *C) {
+	rp, err := parseMinijail("mmap: arg2 & PROT_EXEC|PROT_WRITE")
+	c.Assert(err, IsNil)
+	c.Assert(tree.ExpressionString(rp.RuleOrMacros[0].(tree.Rule).Body), Equals, "(bitset arg2 (binor PROT_EXEC PROT_WRITE))")
+}
+
+func (s *MinijailSuite) Test_translatesInToSubsetOfMask(c *C) {
+	rp, err := parseMinijail("open: arg1 in O_RDONLY|O_CLOEXEC")
+	c.Assert(err, IsNil)
+	c.Assert(tree.ExpressionString(rp.RuleOrMacros[0].(tree.Rule).Body), Equals,
+		"(and (eq (binand argL1 (binand (binNeg (binor O_RDONLY O_CLOEXEC)) 4294967295)) 0) "+
+			"(eq (binand argH1 (binand (binNeg (rsh (binor O_RDONLY O_CLOEXEC) 32)) 4294967295)) 0))")
+}
+
+func (s *MinijailSuite) Test_returnAfterExpressionBecomesNegativeAction(c *C) {
+	rp, err := parseMinijail("ioctl: arg1 == TCGETS; return ENOTTY")
+	c.Assert(err, IsNil)
+	r := rp.RuleOrMacros[0].(tree.Rule)
+	c.Assert(r.PositiveAction, Equals, "allow")
+	c.Assert(r.NegativeAction, Equals, "ENOTTY")
+}
+
+func (s *MinijailSuite) Test_translatesUserNotifyToNotify(c *C) {
+	rp, err := parseMinijail("openat: user-notify")
+	c.Assert(err, IsNil)
+	c.Assert(rp.RuleOrMacros[0].(tree.Rule).PositiveAction, Equals, "notify")
+}
+
+func (s *MinijailSuite) Test_reportsFeaturesThatDontMap(c *C) {
+	_, err := parseMinijail("\nread: kill-process")
+	c.Assert(err, ErrorMatches, "<minijail>:1: the minijail action 'kill-process' has no equivalent in gosecco")
+
+	_, err = parseMinijail("read: log")
+	c.Assert(err, ErrorMatches, "<minijail>:0: the minijail action 'log' has no equivalent in gosecco")
+
+	_, err = parseMinijail("@denylist")
+	c.Assert(err, ErrorMatches, "<minijail>:0: the minijail directive '@denylist' is not supported")
+
+	_, err = parseMinijail("{read, write}: 1")
+	c.Assert(err, ErrorMatches, "<minijail>:0: syscall groups are not supported: .*")
+
+	_, err = parseMinijail("read: arg0 == 1 || !arg1")
+	c.Assert(err, ErrorMatches, "<minijail>:0: unable to translate minijail expression: '!arg1'")
+
+	_, err = parseMinijail("read: arg6 == 1")
+	c.Assert(err, ErrorMatches, "<minijail>:0: invalid argument index in minijail expression: 'arg6 == 1'")
+
+	_, err = parseMinijail("read: arg0 == 1; allow")
+	c.Assert(err, ErrorMatches, "<minijail>:0: expected a return action after ';', found 'allow'")
+
+	_, err = parseMinijail("read: return 70000")
+	c.Assert(err, ErrorMatches, "<minijail>:0: the errno value 70000 is out of range")
+}
+
+func (s *MinijailSuite) Test_parsesFileWithIncludesAndFrequency(c *C) {
+	rp, err := Parse(&MinijailFileSource{getActualTestFolder() + "/minijail_test.policy"})
+	c.Assert(err, IsNil)
+	c.Assert(len(rp.RuleOrMacros), Equals, 3)
+	c.Assert(rp.RuleOrMacros[0].(tree.Rule).Name, Equals, "close")
+	c.Assert(rp.RuleOrMacros[1].(tree.Rule).Name, Equals, "read")
+	c.Assert(rp.RuleOrMacros[2].(tree.Rule).Name, Equals, "openat")
+	c.Assert(rp.RuleOrMacros[2].(tree.Rule).NegativeAction, Equals, "EPERM")
+	c.Assert(rp.RuleOrMacros[0].(tree.Rule).Position, Equals, tree.Position{File: getActualTestFolder() + "/minijail_included.policy", Line: 0})
+	c.Assert(rp.RuleOrMacros[2].(tree.Rule).Position.Line, Equals, 5)
+}
+
+func (s *MinijailSuite) Test_reportsRecursiveIncludes(c *C) {
+	_, err := Parse(&MinijailFileSource{getActualTestFolder() + "/minijail_recursive.policy"})
+	c.Assert(err, ErrorMatches, ".*minijail_recursive.policy:0: recursive include of '.*minijail_recursive.policy'")
+}
@@ -61,6 +61,10 @@ func parseRule(s string) (tree.Rule, error) {
 	if hasReturn {
 		rule.PositiveAction = fmt.Sprintf("%d", ret)
 	}
+	if x == nil {
+		// A plain return always generates the positive action
+		x = tree.BooleanLiteral{true}
+	}
 	rule.Body = x
 	return rule, nil
 }
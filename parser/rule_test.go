@@ -44,3 +44,10 @@ func (s *RuleSuite) Test_parseRule_returnsErrorForInvalidLine(c *C) {
 	_, err := parseRule("  read:  ")
 	c.Assert(err, ErrorMatches, "No expression specified for rule: read")
 }
+
+func (s *RuleSuite) Test_parseRule_withOnlyReturnAlwaysGeneratesPositiveAction(c *C) {
+	r, err := parseRule("read: return 42")
+	c.Assert(err, IsNil)
+	c.Assert(r.PositiveAction, Equals, "42")
+	c.Assert(r.Body, Equals, tree.BooleanLiteral{true})
+}
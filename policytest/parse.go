@@ -0,0 +1,185 @@
+package policytest
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/native"
+)
+
+// Architectures contains the names that can be used for arch selectors, with their AUDIT_ARCH_* values
+var Architectures = map[string]uint32{
+	"x86_64":  native.AuditArch,
+	"i386":    0x40000003,
+	"aarch64": 0xC00000B7,
+	"arm":     0x40000028,
+}
+
+// Expectation is one expect line from a test file
+type Expectation struct {
+	// Line is the line of the expectation in the file, starting from 1
+	Line int
+	// Text is the expectation as it was written, without the expect keyword
+	Text    string
+	Syscall uint32
+	X32     bool
+	Arch    uint32
+	// Args contains the value of each argument, or nil for arguments that can have any value
+	Args   [6]*uint64
+	Action uint32
+}
+
+// ParseError is returned when a test file is invalid
+type ParseError struct {
+	File string
+	Line int
+	Err  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+var expectRE = regexp.MustCompile(`^expect\s+(.*?)\s*=>\s*(\S+)$`)
+var callRE = regexp.MustCompile(`^(\w+)\s*\((.*)\)$`)
+
+// Parse reads the expectations of a test file. Comments start with # and last until the end of the line. Every
+// line that isn't empty or a comment must be an expectation in the form:
+//
+//	expect [x32] [arch=<arch>] <syscall>(<arg>, ...) => <action>
+//
+// The system call can be given by name or number. Each argument is either *, for any value, or
+// a number or constant name, or several of them combined with |. Arguments that are left out can have
+// any value. The architecture is one of the names in Architectures or a number, and defaults to
+// the native one. The action is anything actions.Parse accepts.
+func Parse(file, content string) ([]Expectation, error) {
+	result := []Expectation{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i != -1 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		e, err := parseExpectation(text)
+		if err != nil {
+			return nil, &ParseError{File: file, Line: line, Err: err.Error()}
+		}
+		e.Line = line
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func parseExpectation(text string) (Expectation, error) {
+	match := expectRE.FindStringSubmatch(text)
+	if match == nil {
+		return Expectation{}, fmt.Errorf("expected 'expect <syscall>(<args>) => <action>', but found '%s'", text)
+	}
+	e := Expectation{Text: strings.TrimSpace(strings.TrimPrefix(text, "expect")), Arch: native.AuditArch}
+
+	action, err := actions.Parse(match[2])
+	if err != nil {
+		return Expectation{}, err
+	}
+	e.Action = action.K()
+
+	call := match[1]
+	for {
+		switch {
+		case strings.HasPrefix(call, "x32 "):
+			e.X32 = true
+		case strings.HasPrefix(call, "arch="):
+			end := strings.IndexAny(call, " \t")
+			if end == -1 {
+				return Expectation{}, fmt.Errorf("missing system call after '%s'", call)
+			}
+			if e.Arch, err = parseArch(call[len("arch="):end]); err != nil {
+				return Expectation{}, err
+			}
+		default:
+			return e, parseCall(&e, call)
+		}
+		call = strings.TrimSpace(call[strings.IndexAny(call, " \t"):])
+	}
+}
+
+func parseArch(s string) (uint32, error) {
+	if v, ok := Architectures[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown architecture '%s'", s)
+	}
+	return uint32(v), nil
+}
+
+func parseCall(e *Expectation, call string) error {
+	match := callRE.FindStringSubmatch(call)
+	if match == nil {
+		return fmt.Errorf("expected a system call in the form name(args), but found '%s'", call)
+	}
+
+	if nr, ok := constants.GetSyscall(match[1]); ok {
+		e.Syscall = nr
+	} else if nr, err := strconv.ParseUint(match[1], 0, 32); err == nil {
+		e.Syscall = uint32(nr)
+	} else {
+		return fmt.Errorf("unknown system call '%s'", match[1])
+	}
+
+	if strings.TrimSpace(match[2]) == "" {
+		return nil
+	}
+	args := strings.Split(match[2], ",")
+	if len(args) > len(e.Args) {
+		return fmt.Errorf("system calls have at most %d arguments, but found %d", len(e.Args), len(args))
+	}
+	for i, arg := range args {
+		v, err := parseArgument(strings.TrimSpace(arg))
+		if err != nil {
+			return err
+		}
+		e.Args[i] = v
+	}
+	return nil
+}
+
+func parseArgument(s string) (*uint64, error) {
+	if s == "*" {
+		return nil, nil
+	}
+
+	result := uint64(0)
+	for _, part := range strings.Split(s, "|") {
+		v, err := parseValue(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		result |= v
+	}
+	return &result, nil
+}
+
+func parseValue(s string) (uint64, error) {
+	if v, ok := constants.GetConstant(s); ok {
+		return uint64(v), nil
+	}
+	if v, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return uint64(v), nil
+	}
+	return 0, fmt.Errorf("invalid argument '%s' - expected *, a number or the name of a constant", s)
+}
@@ -0,0 +1,127 @@
+// Package policytest runs expectations about a policy against the filter compiled from it. The
+// expectations are kept in test files next to the policies, with lines such as:
+//
+//	expect read(0, *, *) => allow
+//	expect openat(*, *, O_WRONLY|O_CREAT) => EACCES
+//	expect x32 read(0) => kill
+//
+// Every expectation is emulated for a set of sample values of the arguments that can have any value,
+// so a wildcard gives confidence rather than proof. The equivalence package can be used for proofs.
+package policytest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/native"
+
+	"golang.org/x/sys/unix"
+)
+
+// samples are the values tried for arguments that can have any value. They include the edges of
+// both halves of the arguments, since filters compare the halves separately
+var samples = []uint64{0, 1, 0x7FFFFFFF, 0xFFFFFFFF, 0x100000000, 0xFFFFFFFFFFFFFFFF}
+
+// Failure describes an expectation that didn't hold, with the first input that showed it
+type Failure struct {
+	File        string
+	Expectation Expectation
+	Memory      data.SeccompWorkingMemory
+	Actual      uint32
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s:%d: expect %s, but got %s for %s",
+		f.File, f.Expectation.Line, f.Expectation.Text, actions.Describe(f.Actual), describeMemory(f.Memory))
+}
+
+// describeMemory writes the working memory in the form data.ParseWorkingMemory reads, leaving out zero values
+func describeMemory(d data.SeccompWorkingMemory) string {
+	nr := fmt.Sprintf("%d", d.NR)
+	if name, ok := constants.SyscallNumbers[int(d.NR)]; ok {
+		nr = name
+	}
+	result := []string{"nr=" + nr, fmt.Sprintf("arch=0x%X", d.Arch)}
+	for i, a := range d.Args {
+		if a != 0 {
+			result = append(result, fmt.Sprintf("arg%d=0x%X", i, a))
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// inputs returns the working memory to try for an expectation. Every argument that can have any value is
+// set to each sample on its own, and then all of them together
+func inputs(e Expectation) []data.SeccompWorkingMemory {
+	base := data.SeccompWorkingMemory{NR: int32(e.Syscall), Arch: e.Arch}
+	if e.X32 {
+		base.NR |= int32(native.X32SyscallBit)
+	}
+	wildcards := []int{}
+	for i, a := range e.Args {
+		if a == nil {
+			wildcards = append(wildcards, i)
+		} else {
+			base.Args[i] = *a
+		}
+	}
+
+	result := []data.SeccompWorkingMemory{base}
+	for _, v := range samples[1:] {
+		for _, i := range wildcards {
+			d := base
+			d.Args[i] = v
+			result = append(result, d)
+		}
+		if len(wildcards) > 1 {
+			d := base
+			for _, i := range wildcards {
+				d.Args[i] = v
+			}
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// Check emulates the filter for every expectation, and returns the ones that failed. The error reports
+// a filter that can't be run, such as one with an invalid instruction
+func Check(file string, filters []unix.SockFilter, es []Expectation) ([]Failure, error) {
+	result := []Failure{}
+	for _, e := range es {
+		for _, d := range inputs(e) {
+			actual, err := emulator.EmulateChecked(d, filters, 0)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: the filter failed for %s: %s", file, e.Line, describeMemory(d), err)
+			}
+			if actual != e.Action {
+				result = append(result, Failure{File: file, Expectation: e, Memory: d, Actual: actual})
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// CheckFile compiles the policy with the given settings, and runs the expectations in the test file against it
+func CheckFile(policy, tests string, s gosecco.SeccompSettings) ([]Failure, error) {
+	filters, err := gosecco.Prepare(policy, s)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(tests)
+	if err != nil {
+		return nil, err
+	}
+	es, err := Parse(tests, string(content))
+	if err != nil {
+		return nil, err
+	}
+	return Check(tests, filters, es)
+}
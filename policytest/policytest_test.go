@@ -0,0 +1,126 @@
+package policytest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/parser"
+
+	"golang.org/x/sys/unix"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type PolicyTestSuite struct{}
+
+var _ = Suite(&PolicyTestSuite{})
+
+var settings = gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", ActionOnX32: "kill"}
+
+const policy = "" +
+	"read: arg0 == 0\n" +
+	"write: true\n" +
+	"openat: argL2 &? O_CREAT; return 13\n"
+
+func compile(c *C, source string) []Failure {
+	filters, err := gosecco.PrepareSource(&parser.StringSource{Name: "<policy>", Content: policy}, settings)
+	c.Assert(err, IsNil)
+	es, err := Parse("policy.test", source)
+	c.Assert(err, IsNil)
+	fs, err := Check("policy.test", filters, es)
+	c.Assert(err, IsNil)
+	return fs
+}
+
+func describe(fs []Failure) []string {
+	result := []string{}
+	for _, f := range fs {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+func (s *PolicyTestSuite) Test_passingExpectations(c *C) {
+	c.Assert(compile(c, ""+
+		"# reading is only allowed from stdin\n"+
+		"expect read(0, *, *) => allow\n"+
+		"expect read(1) => kill # anything else is killed\n"+
+		"\n"+
+		"expect write() => allow\n"+
+		"expect openat(*, *, O_WRONLY|O_CREAT) => EACCES\n"+
+		"expect close(*) => kill\n"+
+		"expect arch=i386 write() => kill\n"), HasLen, 0)
+}
+
+func (s *PolicyTestSuite) Test_reportsFailingExpectations(c *C) {
+	c.Assert(describe(compile(c, ""+
+		"expect read(*) => allow\n"+
+		"expect openat(*, *, O_WRONLY) => EACCES\n"+
+		"expect write(1, *) => allow\n"+
+		"expect x32 write(1) => allow\n")), DeepEquals, []string{
+		"policy.test:1: expect read(*) => allow, but got kill for nr=read arch=0xC000003E arg0=0x1",
+		"policy.test:2: expect openat(*, *, O_WRONLY) => EACCES, but got kill for nr=openat arch=0xC000003E arg2=0x1",
+		"policy.test:4: expect x32 write(1) => allow, but got kill for nr=1073741825 arch=0xC000003E arg0=0x1",
+	})
+}
+
+func (s *PolicyTestSuite) Test_reportsFiltersThatCantBeEmulated(c *C) {
+	es, err := Parse("policy.test", "expect read(0) => allow\n")
+	c.Assert(err, IsNil)
+
+	_, err = Check("policy.test", []unix.SockFilter{{Code: 0xFFFF}}, es)
+	c.Assert(err, ErrorMatches, "policy.test:1: the filter failed for nr=read arch=0xC000003E: instruction 0: .*")
+}
+
+func (s *PolicyTestSuite) Test_parsesExpectations(c *C) {
+	es, err := Parse("t", "expect x32 arch=0x40000003 42(-100, O_CREAT | 1, *) => 13\n")
+	c.Assert(err, IsNil)
+
+	minus100, flags := ^uint64(99), uint64(0x41)
+	c.Assert(es, DeepEquals, []Expectation{{
+		Line:    1,
+		Text:    "x32 arch=0x40000003 42(-100, O_CREAT | 1, *) => 13",
+		Syscall: 42,
+		X32:     true,
+		Arch:    0x40000003,
+		Args:    [6]*uint64{&minus100, &flags},
+		Action:  actions.RetErrno | 13,
+	}})
+}
+
+func (s *PolicyTestSuite) Test_reportsInvalidExpectations(c *C) {
+	cases := []struct {
+		source, err string
+	}{
+		{"read(0) => allow", "t:1: expected 'expect <syscall>\\(<args>\\) => <action>', but found 'read\\(0\\) => allow'"},
+		{"\nexpect foo() => allow", "t:2: unknown system call 'foo'"},
+		{"expect read(0) => alow", "t:1: invalid action 'alow' - did you mean 'allow'\\?"},
+		{"expect read(O_FOO) => allow", "t:1: invalid argument 'O_FOO' - expected \\*, a number or the name of a constant"},
+		{"expect read(1,2,3,4,5,6,7) => allow", "t:1: system calls have at most 6 arguments, but found 7"},
+		{"expect arch=sparc read() => allow", "t:1: unknown architecture 'sparc'"},
+		{"expect read => allow", "t:1: expected a system call in the form name\\(args\\), but found 'read'"},
+	}
+
+	for _, t := range cases {
+		_, err := Parse("t", t.source)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *PolicyTestSuite) Test_runsTestFilesAgainstPolicies(c *C) {
+	dir := c.MkDir()
+	policyPath, testPath := filepath.Join(dir, "x.policy"), filepath.Join(dir, "x.policy.test")
+	ioutil.WriteFile(policyPath, []byte(policy), 0644)
+	ioutil.WriteFile(testPath, []byte("expect write() => allow\nexpect read(2) => allow\n"), 0644)
+
+	failures, err := CheckFile(policyPath, testPath, settings)
+	c.Assert(err, IsNil)
+	c.Assert(failures, HasLen, 1)
+	c.Assert(failures[0].Expectation.Line, Equals, 2)
+	c.Assert(failures[0].Actual, Equals, actions.RetKill)
+}
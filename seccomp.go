@@ -64,6 +64,9 @@ type SeccompSettings struct {
 	// for. If not specified, it will default to "kill". The actions are specified using the same syntax as described for
 	// DefaultPositiveAction.
 	ActionOnAuditFailure string
+	// OnWarning will be called with every warning the checker finds in the policy. Warnings
+	// don't stop the compilation. If not specified, warnings will be ignored.
+	OnWarning func(checker.Warning)
 }
 
 // InlineMarker is the marker a string should start with in order to
@@ -73,6 +76,25 @@ const InlineMarker = "{inline}"
 // PrepareSource will take the given source and settings, parse and compile the given
 // data, combined with the settings - and returns the bytecode
 func PrepareSource(source parser.Source, s SeccompSettings) ([]unix.SockFilter, error) {
+	pol, err := PreparePolicy(source, s)
+	if err != nil {
+		return nil, err
+	}
+	return compiler.Compile(pol)
+}
+
+// PrepareSourceWithSourceMap works the same as PrepareSource, but also returns the rule and
+// comparison every instruction was compiled from
+func PrepareSourceWithSourceMap(source parser.Source, s SeccompSettings) ([]unix.SockFilter, []compiler.Origin, error) {
+	pol, err := PreparePolicy(source, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return compiler.CompileWithSourceMap(pol)
+}
+
+// PreparePolicy parses, checks and simplifies the source with the settings, and returns the policy ready to be compiled
+func PreparePolicy(source parser.Source, s SeccompSettings) (tree.Policy, error) {
 	var e error
 	var rp tree.RawPolicy
 
@@ -85,11 +107,11 @@ func PrepareSource(source parser.Source, s SeccompSettings) ([]unix.SockFilter,
 			rp, e = parser.ParseFile(ed)
 		}
 		if e != nil {
-			return nil, e
+			return tree.Policy{}, e
 		}
 		p, e2 := unifier.Unify(rp, nil, "", "", "")
 		if e2 != nil {
-			return nil, e2
+			return tree.Policy{}, e2
 		}
 		extras[ix] = p.Macros
 	}
@@ -97,19 +119,26 @@ func PrepareSource(source parser.Source, s SeccompSettings) ([]unix.SockFilter,
 	// Parsing
 	rp, e = parser.Parse(source)
 	if e != nil {
-		return nil, e
+		return tree.Policy{}, e
 	}
 
 	// Unifying
 	pol, err := unifier.Unify(rp, extras, s.DefaultPositiveAction, s.DefaultNegativeAction, s.DefaultPolicyAction)
 	if err != nil {
-		return nil, err
+		return tree.Policy{}, err
 	}
+	pol.ActionOnX32 = s.ActionOnX32
+	pol.ActionOnAuditFailure = s.ActionOnAuditFailure
 
 	// Type checking
 	errors := checker.EnsureValid(pol)
 	if len(errors) > 0 {
-		return nil, errors[0]
+		return tree.Policy{}, errors[0]
+	}
+	if s.OnWarning != nil {
+		for _, w := range checker.Warnings(pol) {
+			s.OnWarning(w)
+		}
 	}
 
 	// Simplification
@@ -118,11 +147,10 @@ func PrepareSource(source parser.Source, s SeccompSettings) ([]unix.SockFilter,
 	// Pre-compilation
 	errors = precompilation.EnsureValid(pol)
 	if len(errors) > 0 {
-		return nil, errors[0]
+		return tree.Policy{}, errors[0]
 	}
 
-	// Compilation
-	return compiler.Compile(pol)
+	return pol, nil
 }
 
 // Prepare will take the given path and settings, parse and compile the given
@@ -172,13 +200,74 @@ func CompileBlacklist(path string, enforce bool) ([]unix.SockFilter, error) {
 	return Prepare(path, settings)
 }
 
+// InstallOptions contains the flags to install a filter with. The zero value installs the filter on all
+// threads of the process, which is what Load and Install do
+type InstallOptions struct {
+	// CurrentThreadOnly installs the filter on the calling thread only, instead of on all threads of the
+	// process. The goroutine should be locked to its thread with runtime.LockOSThread before installing,
+	// and stay locked, since otherwise the filter applies to whichever thread the goroutine happened to run on
+	CurrentThreadOnly bool
+	// Log makes the kernel log all the actions taken by the filter, except allow
+	Log bool
+	// SpecAllow turns off the mitigation for speculative store bypass that is otherwise enabled with the filter
+	SpecAllow bool
+	// NewListener creates a file descriptor to receive user notifications from the filter on. It is returned
+	// from LoadWithOptions and InstallWithOptions. When installing on all threads, TsyncESRCH has to be set too
+	NewListener bool
+	// TsyncESRCH makes the kernel report a failure to install on all threads with ESRCH instead of the ID of
+	// the thread, so the TsyncError has no thread ID
+	TsyncESRCH bool
+	// WaitKillableRecv makes the threads waiting for a user notification to be handled only wake up for fatal
+	// signals once the notification has been received. It can only be used together with NewListener
+	WaitKillableRecv bool
+}
+
+func (o InstallOptions) flags() (uintptr, error) {
+	if o.NewListener && !o.CurrentThreadOnly && !o.TsyncESRCH {
+		return 0, fmt.Errorf("a listener can only be created when installing on all threads if TsyncESRCH is set")
+	}
+	if o.WaitKillableRecv && !o.NewListener {
+		return 0, fmt.Errorf("WaitKillableRecv can only be used together with NewListener")
+	}
+
+	flags := uintptr(0)
+	for _, f := range []struct {
+		set  bool
+		flag uintptr
+	}{
+		{!o.CurrentThreadOnly, native.FilterFlagTsync},
+		{o.Log, native.FilterFlagLog},
+		{o.SpecAllow, native.FilterFlagSpecAllow},
+		{o.NewListener, native.FilterFlagNewListener},
+		{o.TsyncESRCH, native.FilterFlagTsyncESRCH},
+		{o.WaitKillableRecv, native.FilterFlagWaitKillableRecv},
+	} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+	return flags, nil
+}
+
 // Load makes the seccomp system call to install the bpf filter for
 // all threads (with tsync). Most users of this library should use
 // Install instead of Load, since Install ensures that prctl(set_no_new_privs, 1)
 // has been called
 func Load(bpf []unix.SockFilter) error {
+	_, err := LoadWithOptions(bpf, InstallOptions{})
+	return err
+}
+
+// LoadWithOptions works like Load, but installs the filter with the given options. It returns the file
+// descriptor of the notification listener if NewListener is set, and -1 otherwise. If the filter can't be
+// installed on all threads, the error is a *native.TsyncError
+func LoadWithOptions(bpf []unix.SockFilter, opts InstallOptions) (int, error) {
 	if size, limit := len(bpf), 0xffff; size > limit {
-		return fmt.Errorf("filter program too big: %d bpf instructions (limit = %d)", size, limit)
+		return -1, fmt.Errorf("filter program too big: %d bpf instructions (limit = %d)", size, limit)
+	}
+	flags, err := opts.flags()
+	if err != nil {
+		return -1, err
 	}
 
 	prog := &data.SockFprog{
@@ -186,17 +275,24 @@ func Load(bpf []unix.SockFilter) error {
 		Len:    uint16(len(bpf)),
 	}
 
-	return native.InstallSeccomp(prog)
+	return native.InstallSeccompWithFlags(prog, flags)
 }
 
 // Install will install the given policy filters into the kernel
 func Install(bpf []unix.SockFilter) error {
+	_, err := InstallWithOptions(bpf, InstallOptions{})
+	return err
+}
+
+// InstallWithOptions works like Install, but installs the filter with the given options, and returns the
+// file descriptor of the notification listener if NewListener is set, and -1 otherwise
+func InstallWithOptions(bpf []unix.SockFilter, opts InstallOptions) (int, error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	if err := native.NoNewPrivs(); err != nil {
-		return err
+		return -1, err
 	}
-	return Load(bpf)
+	return LoadWithOptions(bpf, opts)
 }
 
 // InstallBlacklist makes the necessary system calls to install the Seccomp-BPF
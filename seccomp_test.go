@@ -1,12 +1,17 @@
 package gosecco
 
 import (
+	"fmt"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
 	"github.com/twtiger/gosecco/asm"
+	"github.com/twtiger/gosecco/checker"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/parser"
 	"golang.org/x/sys/unix"
 
 	. "gopkg.in/check.v1"
@@ -24,6 +29,33 @@ func (s *SeccompSuite) Test_loadingTooBigBpf(c *C) {
 	c.Assert(res, ErrorMatches, "filter program too big: 65536 bpf instructions \\(limit = 65535\\)")
 }
 
+func (s *SeccompSuite) Test_installOptionsMapToFilterFlags(c *C) {
+	flags, err := InstallOptions{}.flags()
+	c.Assert(err, IsNil)
+	c.Assert(flags, Equals, uintptr(native.FilterFlagTsync))
+
+	flags, err = InstallOptions{CurrentThreadOnly: true, Log: true, SpecAllow: true}.flags()
+	c.Assert(err, IsNil)
+	c.Assert(flags, Equals, uintptr(native.FilterFlagLog|native.FilterFlagSpecAllow))
+
+	flags, err = InstallOptions{NewListener: true, TsyncESRCH: true, WaitKillableRecv: true}.flags()
+	c.Assert(err, IsNil)
+	c.Assert(flags, Equals, uintptr(native.FilterFlagTsync|native.FilterFlagNewListener|native.FilterFlagTsyncESRCH|native.FilterFlagWaitKillableRecv))
+}
+
+func (s *SeccompSuite) Test_installOptionsRejectCombinationsTheKernelDoesnt(c *C) {
+	_, err := LoadWithOptions(make([]unix.SockFilter, 1), InstallOptions{NewListener: true})
+	c.Assert(err, ErrorMatches, "a listener can only be created when installing on all threads if TsyncESRCH is set")
+
+	_, err = LoadWithOptions(make([]unix.SockFilter, 1), InstallOptions{CurrentThreadOnly: true, WaitKillableRecv: true})
+	c.Assert(err, ErrorMatches, "WaitKillableRecv can only be used together with NewListener")
+}
+
+func (s *SeccompSuite) Test_tsyncErrorsIncludeTheThread(c *C) {
+	c.Assert(&native.TsyncError{ThreadID: 4242}, ErrorMatches, "seccomp filter could not be synchronized to thread 4242")
+	c.Assert(&native.TsyncError{}, ErrorMatches, "seccomp filter could not be synchronized to all threads")
+}
+
 func getActualTestFolder() string {
 	wd, _ := os.Getwd()
 	if strings.HasSuffix(wd, "/parser/test_policies/") {
@@ -106,6 +138,25 @@ func (s *SeccompSuite) Test_parseSetsDefaultActions(c *C) {
 		"ret_k\t7FF00000\n")
 }
 
+func (s *SeccompSuite) Test_parseSetsTheArchitectureActions(c *C) {
+	set := SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill", ActionOnX32: "trace", ActionOnAuditFailure: "trap"}
+	f := getActualTestFolder() + "/valid_test_policy"
+	res, ee := Prepare(f, set)
+
+	c.Assert(ee, Equals, nil)
+
+	c.Assert(asm.Dump(res), Equals, ""+
+		"ld_abs\t4\n"+
+		"jeq_k\t00\t06\tC000003E\n"+
+		"ld_abs\t0\n"+
+		"jset_k\t03\t00\t40000000\n"+
+		"jeq_k\t00\t01\t1\n"+
+		"ret_k\t7FFF0000\n"+
+		"ret_k\t0\n"+
+		"ret_k\t7FF00000\n"+
+		"ret_k\t30000\n")
+}
+
 func (s *SeccompSuite) Test_compileWithEnforce(c *C) {
 	f := getActualTestFolder() + "/valid_test_policy"
 	res, ee := Compile(f, true)
@@ -145,8 +196,9 @@ func (s *SeccompSuite) Test_compileBlacklistWithEnforce(c *C) {
 
 	c.Assert(asm.Dump(res), Equals, ""+
 		"ld_abs\t4\n"+
-		"jeq_k\t00\t03\tC000003E\n"+
+		"jeq_k\t00\t04\tC000003E\n"+
 		"ld_abs\t0\n"+
+		"jset_k\t02\t00\t40000000\n"+
 		"jeq_k\t01\t00\t1\n"+
 		"ret_k\t7FFF0000\n"+
 		"ret_k\t0\n")
@@ -160,8 +212,9 @@ func (s *SeccompSuite) Test_compileBlacklistWithoutEnforce(c *C) {
 
 	c.Assert(asm.Dump(res), Equals, ""+
 		"ld_abs\t4\n"+
-		"jeq_k\t00\t03\tC000003E\n"+
+		"jeq_k\t00\t04\tC000003E\n"+
 		"ld_abs\t0\n"+
+		"jset_k\t02\t00\t40000000\n"+
 		"jeq_k\t02\t00\t1\n"+
 		"ret_k\t7FFF0000\n"+
 		"ret_k\t0\n"+
@@ -174,3 +227,39 @@ func (s *SeccompSuite) Test_emptySyscallExpression(c *C) {
 
 	c.Assert(ee, ErrorMatches, ".*?No expression specified for rule: write")
 }
+
+func (s *SeccompSuite) Test_warningsAreReportedThroughTheCallback(c *C) {
+	warnings := []string{}
+	set := SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill",
+		OnWarning: func(w checker.Warning) { warnings = append(warnings, w.String()) }}
+	_, ee := PrepareSource(&parser.StringSource{Name: "<test>", Content: "read: arg0 > 5 && arg0 < 3\n# gosecco:ignore W002\nwrite: 1 == 1\n"}, set)
+
+	c.Assert(ee, IsNil)
+	c.Assert(warnings, DeepEquals, []string{"<test>:0: [read] the conditions on arg0 can never be true at the same time (W004)"})
+}
+
+func (s *SeccompSuite) Test_compiledPoliciesPassKernelVerification(c *C) {
+	set := SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "kill", DefaultPolicyAction: "kill"}
+
+	for _, name := range []string{"valid_test_policy", "valid_unsimplified_policy"} {
+		res, ee := Prepare(getActualTestFolder()+"/"+name, set)
+		c.Assert(ee, IsNil)
+		c.Assert(emulator.Verify(res), IsNil, Commentf("policy %s", name))
+	}
+
+	values := []string{}
+	for i := 0; i < 300; i++ {
+		values = append(values, fmt.Sprintf("%d", i*7))
+	}
+	for _, source := range []string{
+		"read: arg0 == 1 || (argL1 + 2) * argL2 > argH3 ^ 0x10\nwrite: argL0 & 0xFF00 == 0x100 && argL1 >> 2 < 5\n",
+		"read: in(argL0, 1, 2, 3) && notIn(argH1, 4, 5)\nwrite: (argL0 & 4) != 0 || !(arg1 < 0x100000000)\n",
+		"read: (argL0 | argL1) == (argL2 - argL3) * (argL4 + argL5)\nwrite: argL0 / 3 == argL1 << 2\n",
+		"read: argL0 % 3 == 1\nwrite: argL0 % argL1 == argH1 % 7\n",
+		"read: in(argL0, " + strings.Join(values, ", ") + ")\nwrite: arg1 == 1\n",
+	} {
+		res, ee := PrepareSource(&parser.StringSource{Name: "<test>", Content: source}, set)
+		c.Assert(ee, IsNil, Commentf("policy %q", source))
+		c.Assert(emulator.Verify(res), IsNil, Commentf("policy %q", source))
+	}
+}
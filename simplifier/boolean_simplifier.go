@@ -20,7 +20,18 @@ func (s *booleanSimplifier) AcceptOr(a tree.Or) {
 			}
 		}
 	} else {
-		s.Result = tree.Or{l, r}
+		// Second branch is possible to calculate at compile time
+		if ok2 {
+			if pr {
+				// If the second branch is always true, it doesn't matter what the first branch is
+				s.Result = tree.BooleanLiteral{true}
+			} else {
+				// And if the second branch is false, the or expression is determined by the left arm
+				s.Result = l
+			}
+		} else {
+			s.Result = tree.Or{l, r}
+		}
 	}
 }
 
@@ -65,6 +76,8 @@ func (s *booleanSimplifier) AcceptNegation(v tree.Negation) {
 	val2, ok := potentialExtractBooleanValue(val)
 	if ok {
 		s.Result = tree.BooleanLiteral{!val2}
+	} else {
+		s.Result = tree.Negation{val}
 	}
 }
 
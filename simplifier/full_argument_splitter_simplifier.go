@@ -21,11 +21,7 @@ func (s *fullArgumentSplitterSimplifier) AcceptComparison(a tree.Comparison) {
 				Right: tree.Comparison{Op: a.Op, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.NumericLiteral{prnrHi}},
 			}
 		case tree.BITSET:
-			s.Result = tree.And{
-				Left: tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: tree.NumericLiteral{prnrLow}}, Right: tree.NumericLiteral{prnrLow}},
-
-				Right: tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.NumericLiteral{prnrHi}}, Right: tree.NumericLiteral{prnrHi}},
-			}
+			s.Result = bitsetAgainstLiteral(pral, prnrLow, prnrHi)
 		case tree.NEQL:
 			s.Result = tree.Or{
 				Left:  tree.Comparison{Op: a.Op, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: tree.NumericLiteral{prnrLow}},
@@ -50,10 +46,7 @@ func (s *fullArgumentSplitterSimplifier) AcceptComparison(a tree.Comparison) {
 				Right: tree.Comparison{Op: a.Op, Left: tree.NumericLiteral{prnlHi}, Right: tree.Argument{Type: tree.Hi, Index: prar}},
 			}
 		case tree.BITSET:
-			s.Result = tree.And{
-				Left:  tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.NumericLiteral{prnlLow}, Right: tree.Argument{Type: tree.Low, Index: prar}}, Right: tree.Argument{Type: tree.Low, Index: prar}},
-				Right: tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.NumericLiteral{prnlHi}, Right: tree.Argument{Type: tree.Hi, Index: prar}}, Right: tree.Argument{Type: tree.Hi, Index: prar}},
-			}
+			s.Result = bitsetAgainstLiteral(prar, prnlLow, prnlHi)
 		case tree.NEQL:
 			s.Result = tree.Or{
 				Left:  tree.Comparison{Op: a.Op, Left: tree.NumericLiteral{prnlLow}, Right: tree.Argument{Type: tree.Low, Index: prar}},
@@ -78,9 +71,9 @@ func (s *fullArgumentSplitterSimplifier) AcceptComparison(a tree.Comparison) {
 				Right: tree.Comparison{Op: a.Op, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.Argument{Type: tree.Hi, Index: prar}},
 			}
 		case tree.BITSET:
-			s.Result = tree.And{
-				Left:  tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: tree.Argument{Type: tree.Low, Index: prar}}, Right: tree.Argument{Type: tree.Low, Index: prar}},
-				Right: tree.Comparison{Op: tree.EQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.Argument{Type: tree.Hi, Index: prar}}, Right: tree.Argument{Type: tree.Hi, Index: prar}},
+			s.Result = tree.Or{
+				Left:  tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: tree.Argument{Type: tree.Low, Index: prar}},
+				Right: tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.Argument{Type: tree.Hi, Index: prar}},
 			}
 
 		case tree.NEQL:
@@ -100,15 +93,31 @@ func (s *fullArgumentSplitterSimplifier) AcceptComparison(a tree.Comparison) {
 			panic("shouldn't happen")
 		}
 	} else if okal && a.Op == tree.BITSET {
-		s.Result = tree.And{
-			Left:  tree.Comparison{Op: tree.EQL, Left: tree.Argument{Type: tree.Hi, Index: pral}, Right: tree.NumericLiteral{0}},
-			Right: tree.Comparison{Op: tree.NEQL, Left: tree.Arithmetic{Op: tree.BINAND, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: a.Right}, Right: tree.NumericLiteral{0}},
-		}
+		// The other side is a 32 bit value, so none of the bits in the upper half can be set
+		s.Result = tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Low, Index: pral}, Right: r}
+	} else if okar && a.Op == tree.BITSET {
+		s.Result = tree.Comparison{Op: tree.BITSET, Left: l, Right: tree.Argument{Type: tree.Low, Index: prar}}
 	} else {
 		s.Result = tree.Comparison{Op: a.Op, Left: l, Right: r}
 	}
 }
 
+// bitsetAgainstLiteral checks if any of the bits in the literal are set in the argument, leaving out
+// the halves where the literal has no bits set, since they can never match
+func bitsetAgainstLiteral(index int, low, high uint64) tree.Expression {
+	lowCheck := tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Low, Index: index}, Right: tree.NumericLiteral{low}}
+	highCheck := tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Hi, Index: index}, Right: tree.NumericLiteral{high}}
+	switch {
+	case low != 0 && high != 0:
+		return tree.Or{Left: lowCheck, Right: highCheck}
+	case low != 0:
+		return lowCheck
+	case high != 0:
+		return highCheck
+	}
+	return tree.BooleanLiteral{false}
+}
+
 // fullArgumentSplitterSimplifier simplifies full argument references in such a way that
 // after this has run, there will be no references to full arguments
 // this simplifier is expected to run after the inclusion simplifiers and the LT and LTE simplifiers
@@ -40,7 +40,7 @@ func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitandWithArgAgains
 		},
 	)
 
-	c.Assert(tree.ExpressionString(sx), Equals, "(and (eq (binand argL2 2596069104) 2596069104) (eq (binand argH2 305419896) 305419896))")
+	c.Assert(tree.ExpressionString(sx), Equals, "(or (bitset argL2 2596069104) (bitset argH2 305419896))")
 
 	sx = createFullArgumentSplitterSimplifier().Transform(
 		tree.Comparison{
@@ -50,7 +50,7 @@ func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitandWithArgAgains
 		},
 	)
 
-	c.Assert(tree.ExpressionString(sx), Equals, "(and (eq (binand 2596069104 argL2) argL2) (eq (binand 305419896 argH2) argH2))")
+	c.Assert(tree.ExpressionString(sx), Equals, "(or (bitset argL2 2596069104) (bitset argH2 305419896))")
 }
 
 func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesEqualityWithArgAgainstArg(c *C) {
@@ -74,7 +74,7 @@ func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitsetWithArgAgains
 		},
 	)
 
-	c.Assert(tree.ExpressionString(sx), Equals, "(and (eq (binand argL2 argL4) argL4) (eq (binand argH2 argH4) argH4))")
+	c.Assert(tree.ExpressionString(sx), Equals, "(or (bitset argL2 argL4) (bitset argH2 argH4))")
 }
 
 func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitsetWithArgAgainstExpression(c *C) {
@@ -86,7 +86,24 @@ func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitsetWithArgAgains
 		},
 	)
 
-	c.Assert(tree.ExpressionString(sx), Equals, "(and (eq argH2 0) (neq (binand argL2 (binor 1 2)) 0))")
+	c.Assert(tree.ExpressionString(sx), Equals, "(bitset argL2 (binor 1 2))")
+}
+
+func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesBitsetOnlyOnTheHalvesWithBitsInTheLiteral(c *C) {
+	sx := createFullArgumentSplitterSimplifier().Transform(
+		tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Full, Index: 0}, Right: tree.NumericLiteral{0x100}},
+	)
+	c.Assert(tree.ExpressionString(sx), Equals, "(bitset argL0 256)")
+
+	sx = createFullArgumentSplitterSimplifier().Transform(
+		tree.Comparison{Op: tree.BITSET, Left: tree.NumericLiteral{0x100000000}, Right: tree.Argument{Type: tree.Full, Index: 0}},
+	)
+	c.Assert(tree.ExpressionString(sx), Equals, "(bitset argH0 1)")
+
+	sx = createFullArgumentSplitterSimplifier().Transform(
+		tree.Comparison{Op: tree.BITSET, Left: tree.Argument{Type: tree.Full, Index: 0}, Right: tree.NumericLiteral{0}},
+	)
+	c.Assert(tree.ExpressionString(sx), Equals, "false")
 }
 
 func (s *FullArgumentSplitterSimplifierSuite) Test_simplifiesNonequalityWithArgAgainstNumber(c *C) {
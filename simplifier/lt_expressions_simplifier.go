@@ -11,10 +11,10 @@ func (s *ltExpressionsSimplifier) AcceptComparison(a tree.Comparison) {
 
 	switch a.Op {
 	case tree.LT:
-		newOp = tree.GTE
+		newOp = tree.GT
 		l, r = r, l
 	case tree.LTE:
-		newOp = tree.GT
+		newOp = tree.GTE
 		l, r = r, l
 	}
 
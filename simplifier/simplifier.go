@@ -34,8 +34,8 @@ func Simplify(inp tree.Expression) tree.Expression {
 		// X notIn [P, Q, R]  ==>  X != P && X != Q && X != R
 		createInclusionRemoverSimplifier(),
 
-		// X < Y    ==>  Y >= X
-		// X <= Y   ==>  Y > X
+		// X < Y    ==>  Y > X
+		// X <= Y   ==>  Y >= X
 		createLtExpressionsSimplifier(),
 
 		// Where X and Y can be determined statically:
@@ -75,10 +75,14 @@ func Simplify(inp tree.Expression) tree.Expression {
 		// false || true   ==>  true
 		// false || false  ==>  false
 		// true  || Y      ==>  true
+		// Y     || true   ==>  true
+		// Y     || false  ==>  Y
 		// true  && true   ==>  true
 		// true  && false  ==>  false
 		// true  && Y      ==>  Y
+		// Y     && true   ==>  Y
 		// false && [any]  ==>  false
+		// Y     && false  ==>  false
 		createBooleanSimplifier(),
 
 		// ~X  ==> X ^ 0xFFFFFFFFFFFFFFFF
@@ -93,6 +97,9 @@ func Simplify(inp tree.Expression) tree.Expression {
 		// arg0 != arg1  ==>  argL0 != argL1 || argH0 != argH1
 		// arg0 > arg1   ==>  argH0 > argH1  || (argH0 == argH1 && argL0 > argL1)
 		// arg0 >= arg1  ==>  argH0 > argH1  || (argH0 == argH1 && argL0 >= argL1)
+		// arg0 &? X     ==>  argL0 &? X.low || argH0 &? X.high  (leaving out halves where X has no bits set)
+		// arg0 &? arg1  ==>  argL0 &? argL1 || argH0 &? argH1
+		// arg0 &? Y     ==>  argL0 &? Y  where Y is a 32bit expression
 		createFullArgumentSplitterSimplifier(),
 
 		// We repeat some of the simplifiers in the hope that the above operations have opened up new avenues of simplification
@@ -119,6 +119,14 @@ func (s *SimplifierSuite) Test_simplifyComparison(c *C) {
 	c.Assert(tree.ExpressionString(sx), Equals, "false")
 }
 
+func (s *SimplifierSuite) Test_simplifyLessThanOnEqualValues(c *C) {
+	sx := Simplify(tree.Comparison{Left: tree.NumericLiteral{42}, Op: tree.LT, Right: tree.NumericLiteral{42}})
+	c.Assert(tree.ExpressionString(sx), Equals, "false")
+
+	sx = Simplify(tree.Comparison{Left: tree.NumericLiteral{42}, Op: tree.LTE, Right: tree.NumericLiteral{42}})
+	c.Assert(tree.ExpressionString(sx), Equals, "true")
+}
+
 func (s *SimplifierSuite) Test_simplifyOr(c *C) {
 	sx := Simplify(tree.Or{
 		tree.Comparison{
@@ -263,6 +271,21 @@ func (s *SimplifierSuite) Test_simplifyNegation(c *C) {
 	c.Assert(tree.ExpressionString(sx), Equals, "false")
 }
 
+func (s *SimplifierSuite) Test_simplifyKeepsNegationOfRuntimeValues(c *C) {
+	sx := Simplify(tree.Negation{tree.Comparison{Op: tree.EQL, Left: tree.Argument{Type: tree.Low, Index: 0}, Right: tree.NumericLiteral{1}}})
+	c.Assert(tree.ExpressionString(sx), Equals, "(not (eq argL0 1))")
+}
+
+func (s *SimplifierSuite) Test_simplifyOrWithConstantRightArm(c *C) {
+	runtime := tree.Comparison{Op: tree.EQL, Left: tree.Argument{Type: tree.Low, Index: 0}, Right: tree.NumericLiteral{1}}
+
+	sx := Simplify(tree.Or{runtime, tree.BooleanLiteral{true}})
+	c.Assert(tree.ExpressionString(sx), Equals, "true")
+
+	sx = Simplify(tree.Or{runtime, tree.BooleanLiteral{false}})
+	c.Assert(tree.ExpressionString(sx), Equals, "(eq argL0 1)")
+}
+
 func (s *SimplifierSuite) Test_simplifyNumericLiteral(c *C) {
 	sx := Simplify(tree.NumericLiteral{42})
 	c.Assert(tree.ExpressionString(sx), Equals, "42")
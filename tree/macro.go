@@ -5,4 +5,5 @@ type Macro struct {
 	Name          string
 	ArgumentNames []string
 	Body          Expression
+	Position      Position
 }
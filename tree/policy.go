@@ -14,4 +14,7 @@ type Policy struct {
 	ActionOnAuditFailure  string
 	Macros                map[string]Macro
 	Rules                 []*Rule
+	// DefaultPositions contains the places where DEFAULT_POSITIVE, DEFAULT_NEGATIVE and DEFAULT_POLICY were
+	// defined, if they were defined in a source
+	DefaultPositions map[string]Position
 }
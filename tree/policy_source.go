@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicySource returns the policy formatted in the syntax of the policy language, so that it can be parsed back.
+// The default actions come first, then the macros in alphabetical order, and then the rules in their order.
+// ActionOnX32 and ActionOnAuditFailure can't be expressed in the language, so they are written as comments
+func PolicySource(p Policy) string {
+	lines := []string{}
+	if p.ActionOnAuditFailure != "" {
+		lines = append(lines, "# on other architectures: "+p.ActionOnAuditFailure)
+	}
+	if p.ActionOnX32 != "" {
+		lines = append(lines, "# on x32 system calls: "+p.ActionOnX32)
+	}
+	for _, d := range []struct{ name, action string }{
+		{"DEFAULT_POSITIVE", p.DefaultPositiveAction},
+		{"DEFAULT_NEGATIVE", p.DefaultNegativeAction},
+		{"DEFAULT_POLICY", p.DefaultPolicyAction},
+	} {
+		if d.action != "" {
+			lines = append(lines, fmt.Sprintf("%s = %s", d.name, d.action))
+		}
+	}
+
+	names := []string{}
+	for name := range p.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := p.Macros[name]
+		head := m.Name
+		if len(m.ArgumentNames) > 0 {
+			head += "(" + strings.Join(m.ArgumentNames, ", ") + ")"
+		}
+		lines = append(lines, fmt.Sprintf("%s = %s", head, SourceString(m.Body)))
+	}
+
+	for _, r := range p.Rules {
+		lines = append(lines, RuleSource(r))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// RuleSource returns the rule formatted in the syntax of the policy language
+func RuleSource(r *Rule) string {
+	actions := []string{}
+	if r.PositiveAction != "" {
+		actions = append(actions, "+"+r.PositiveAction)
+	}
+	if r.NegativeAction != "" {
+		actions = append(actions, "-"+r.NegativeAction)
+	}
+	head := r.Name
+	if len(actions) > 0 {
+		head += "[" + strings.Join(actions, ", ") + "]"
+	}
+	return fmt.Sprintf("%s: %s", head, SourceString(r.Body))
+}
@@ -0,0 +1,34 @@
+package tree
+
+import . "gopkg.in/check.v1"
+
+type PolicySourceSuite struct{}
+
+var _ = Suite(&PolicySourceSuite{})
+
+func (s *PolicySourceSuite) Test_writesAPolicy(c *C) {
+	p := Policy{
+		DefaultPositiveAction: "allow",
+		DefaultNegativeAction: "kill",
+		ActionOnX32:           "trap",
+		Macros: map[string]Macro{
+			"small": Macro{Name: "small", ArgumentNames: []string{"x"}, Body: Comparison{Op: LT, Left: Variable{"x"}, Right: NumericLiteral{10}}},
+			"big":   Macro{Name: "big", Body: NumericLiteral{0x10000}},
+		},
+		Rules: []*Rule{
+			&Rule{Name: "read", Body: Comparison{Op: EQL, Left: Argument{Index: 0}, Right: NumericLiteral{0}}},
+			&Rule{Name: "write", PositiveAction: "trace", NegativeAction: "EPERM", Body: BooleanLiteral{true}},
+			&Rule{Name: "close", NegativeAction: "1", Body: Call{Name: "small", Args: []Any{Argument{Index: 0, Type: Low}}}},
+		},
+	}
+
+	c.Assert(PolicySource(p), Equals, ""+
+		"# on x32 system calls: trap\n"+
+		"DEFAULT_POSITIVE = allow\n"+
+		"DEFAULT_NEGATIVE = kill\n"+
+		"big = 0x10000\n"+
+		"small(x) = x < 10\n"+
+		"read: arg0 == 0\n"+
+		"write[+trace, -EPERM]: true\n"+
+		"close[-1]: small(argL0)\n")
+}
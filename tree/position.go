@@ -0,0 +1,20 @@
+package tree
+
+import "fmt"
+
+// Position represents the place in a source where a rule or a macro was defined
+type Position struct {
+	// File is the name of the file or string source
+	File string
+	// Line is the index of the line in the source, counted the same way parse errors report it
+	Line int
+}
+
+// IsKnown returns true if the position points to an actual source
+func (p Position) IsKnown() bool {
+	return p.File != ""
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
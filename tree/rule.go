@@ -1,9 +1,29 @@
 package tree
 
+import "fmt"
+
 // Rule contains all the information for one specific rule
 type Rule struct {
 	Name           string
 	PositiveAction string
 	NegativeAction string
 	Body           Expression
+	Position       Position
+	// Suppressions contains the space separated codes of the checker warnings that should not be reported for this rule.
+	// It is kept as a string so that rules stay comparable
+	Suppressions string
+}
+
+// RuleError represents a problem found in a specific rule
+type RuleError struct {
+	SyscallName string
+	Position    Position
+	Err         error
+}
+
+func (e *RuleError) Error() string {
+	if e.Position.IsKnown() {
+		return fmt.Sprintf("%s: [%s] %s", e.Position, e.SyscallName, e.Err)
+	}
+	return fmt.Sprintf("[%s] %s", e.SyscallName, e.Err)
 }
@@ -0,0 +1,207 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceString returns the given expression formatted in the syntax of the policy language
+func SourceString(e Expression) string {
+	sv := &SourceVisitor{}
+	e.Accept(sv)
+	return sv.String()
+}
+
+// SourceVisitor will generate a representation of an expression that can be parsed back
+// by the parser. Parenthesis are only added where the precedence rules require them
+type SourceVisitor struct {
+	result string
+}
+
+// String returns the current string built up
+func (sv *SourceVisitor) String() string {
+	return sv.result
+}
+
+// These follow the precedence schedule from the language documentation
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceEquality
+	precedenceRelational
+	precedenceBinOr
+	precedenceBinXor
+	precedenceBinAnd
+	precedenceShift
+	precedenceAdditive
+	precedenceMultiplicative
+	precedenceUnary
+	precedencePrimary
+)
+
+var arithmeticPrecedence = map[ArithmeticType]int{
+	PLUS:   precedenceAdditive,
+	MINUS:  precedenceAdditive,
+	MULT:   precedenceMultiplicative,
+	DIV:    precedenceMultiplicative,
+	MOD:    precedenceMultiplicative,
+	BINAND: precedenceBinAnd,
+	BINOR:  precedenceBinOr,
+	BINXOR: precedenceBinXor,
+	LSH:    precedenceShift,
+	RSH:    precedenceShift,
+}
+
+var associativeArithmetic = map[ArithmeticType]bool{
+	PLUS:   true,
+	MULT:   true,
+	BINAND: true,
+	BINOR:  true,
+	BINXOR: true,
+}
+
+func precedenceOf(e Expression) int {
+	switch v := e.(type) {
+	case Or:
+		return precedenceOr
+	case And:
+		return precedenceAnd
+	case Comparison:
+		switch v.Op {
+		case EQL, NEQL, BITSET:
+			return precedenceEquality
+		}
+		return precedenceRelational
+	case Arithmetic:
+		return arithmeticPrecedence[v.Op]
+	case Negation, BinaryNegation:
+		return precedenceUnary
+	}
+	return precedencePrimary
+}
+
+func sameAssociativeOperation(parent, child Expression) bool {
+	switch p := parent.(type) {
+	case Or:
+		_, ok := child.(Or)
+		return ok
+	case And:
+		_, ok := child.(And)
+		return ok
+	case Arithmetic:
+		c, ok := child.(Arithmetic)
+		return ok && c.Op == p.Op && associativeArithmetic[p.Op]
+	}
+	return false
+}
+
+func (sv *SourceVisitor) operand(parent, child Expression) {
+	pp, cp := precedenceOf(parent), precedenceOf(child)
+	if cp > pp || (cp == pp && sameAssociativeOperation(parent, child)) {
+		child.Accept(sv)
+		return
+	}
+	sv.result += "("
+	child.Accept(sv)
+	sv.result += ")"
+}
+
+func (sv *SourceVisitor) binary(parent, left, right Expression, op string) {
+	sv.operand(parent, left)
+	sv.result += " " + op + " "
+	sv.operand(parent, right)
+}
+
+// AcceptAnd implements Visitor
+func (sv *SourceVisitor) AcceptAnd(v And) {
+	sv.binary(v, v.Left, v.Right, "&&")
+}
+
+// AcceptArgument implements Visitor
+func (sv *SourceVisitor) AcceptArgument(v Argument) {
+	addition := ""
+	if v.Type == Hi {
+		addition = "H"
+	} else if v.Type == Low {
+		addition = "L"
+	}
+	sv.result += fmt.Sprintf("arg%s%d", addition, v.Index)
+}
+
+// AcceptArithmetic implements Visitor
+func (sv *SourceVisitor) AcceptArithmetic(v Arithmetic) {
+	sv.binary(v, v.Left, v.Right, ArithmeticNames[v.Op])
+}
+
+// AcceptBinaryNegation implements Visitor
+func (sv *SourceVisitor) AcceptBinaryNegation(v BinaryNegation) {
+	sv.result += "~"
+	sv.operand(v, v.Operand)
+}
+
+// AcceptBooleanLiteral implements Visitor
+func (sv *SourceVisitor) AcceptBooleanLiteral(v BooleanLiteral) {
+	if v.Value {
+		sv.result += "true"
+	} else {
+		sv.result += "false"
+	}
+}
+
+func (sv *SourceVisitor) list(parts []string) {
+	sv.result += "(" + strings.Join(parts, ", ") + ")"
+}
+
+// AcceptCall implements Visitor
+func (sv *SourceVisitor) AcceptCall(v Call) {
+	parts := []string{}
+	for _, a := range v.Args {
+		parts = append(parts, SourceString(a))
+	}
+	sv.result += v.Name
+	sv.list(parts)
+}
+
+// AcceptComparison implements Visitor
+func (sv *SourceVisitor) AcceptComparison(v Comparison) {
+	sv.binary(v, v.Left, v.Right, ComparisonNames[v.Op])
+}
+
+// AcceptInclusion implements Visitor
+func (sv *SourceVisitor) AcceptInclusion(v Inclusion) {
+	if v.Positive {
+		sv.result += "in"
+	} else {
+		sv.result += "notIn"
+	}
+	parts := []string{SourceString(v.Left)}
+	for _, r := range v.Rights {
+		parts = append(parts, SourceString(r))
+	}
+	sv.list(parts)
+}
+
+// AcceptNegation implements Visitor
+func (sv *SourceVisitor) AcceptNegation(v Negation) {
+	sv.result += "!"
+	sv.operand(v, v.Operand)
+}
+
+// AcceptNumericLiteral implements Visitor
+func (sv *SourceVisitor) AcceptNumericLiteral(v NumericLiteral) {
+	if v.Value < 0x1000 {
+		sv.result += fmt.Sprintf("%d", v.Value)
+	} else {
+		sv.result += fmt.Sprintf("0x%X", v.Value)
+	}
+}
+
+// AcceptOr implements Visitor
+func (sv *SourceVisitor) AcceptOr(v Or) {
+	sv.binary(v, v.Left, v.Right, "||")
+}
+
+// AcceptVariable implements Visitor
+func (sv *SourceVisitor) AcceptVariable(v Variable) {
+	sv.result += v.Name
+}
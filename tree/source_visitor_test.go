@@ -0,0 +1,42 @@
+package tree
+
+import . "gopkg.in/check.v1"
+
+type SourceVisitorSuite struct{}
+
+var _ = Suite(&SourceVisitorSuite{})
+
+func (s *SourceVisitorSuite) Test_simpleValues(c *C) {
+	c.Assert(SourceString(Variable{"foo1"}), Equals, "foo1")
+	c.Assert(SourceString(Argument{Index: 3}), Equals, "arg3")
+	c.Assert(SourceString(Argument{Index: 3, Type: Hi}), Equals, "argH3")
+	c.Assert(SourceString(Argument{Index: 3, Type: Low}), Equals, "argL3")
+	c.Assert(SourceString(NumericLiteral{42}), Equals, "42")
+	c.Assert(SourceString(NumericLiteral{0xC000003E}), Equals, "0xC000003E")
+	c.Assert(SourceString(BooleanLiteral{false}), Equals, "false")
+}
+
+func (s *SourceVisitorSuite) Test_onlyAddsNeededParenthesis(c *C) {
+	x := Or{
+		Left: And{
+			Left:  Comparison{Op: EQL, Left: Argument{Index: 0}, Right: Arithmetic{Op: PLUS, Left: NumericLiteral{1}, Right: Arithmetic{Op: MULT, Left: NumericLiteral{2}, Right: NumericLiteral{3}}}},
+			Right: Comparison{Op: GT, Left: Arithmetic{Op: MULT, Left: Arithmetic{Op: PLUS, Left: NumericLiteral{1}, Right: NumericLiteral{2}}, Right: NumericLiteral{3}}, Right: NumericLiteral{4}},
+		},
+		Right: Negation{Or{Left: BooleanLiteral{true}, Right: BooleanLiteral{false}}},
+	}
+	c.Assert(SourceString(x), Equals, "arg0 == 1 + 2 * 3 && (1 + 2) * 3 > 4 || !(true || false)")
+}
+
+func (s *SourceVisitorSuite) Test_keepsNonAssociativeGrouping(c *C) {
+	x := Arithmetic{Op: MINUS, Left: Arithmetic{Op: MINUS, Left: Variable{"a"}, Right: Variable{"b"}}, Right: Variable{"c"}}
+	c.Assert(SourceString(x), Equals, "(a - b) - c")
+
+	y := Arithmetic{Op: BINOR, Left: Arithmetic{Op: BINOR, Left: Variable{"a"}, Right: Variable{"b"}}, Right: BinaryNegation{Variable{"c"}}}
+	c.Assert(SourceString(y), Equals, "a | b | ~c")
+}
+
+func (s *SourceVisitorSuite) Test_callsAndInclusions(c *C) {
+	c.Assert(SourceString(Call{Name: "foo", Args: []Any{Argument{Index: 1}, NumericLiteral{2}}}), Equals, "foo(arg1, 2)")
+	c.Assert(SourceString(Inclusion{Positive: false, Left: Argument{Index: 1}, Rights: []Numeric{NumericLiteral{2}, Variable{"X"}}}), Equals, "notIn(arg1, 2, X)")
+	c.Assert(SourceString(Comparison{Op: BITSET, Left: Argument{Index: 1}, Right: NumericLiteral{2}}), Equals, "arg1 &? 2")
+}
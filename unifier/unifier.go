@@ -39,32 +39,56 @@ func combineMacroMaps(ms []map[string]tree.Macro) map[string]tree.Macro {
 // and DEFAULT_NEGATIVE variables anywhere in the files. The default actions can only be defined once in a file, and will be in effect
 // for all rules in that file, unless a specific rule overrides the default actions.
 func Unify(r tree.RawPolicy, additionalMacros []map[string]tree.Macro, defaultPositive, defaultNegative, defaultPolicy string) (tree.Policy, error) {
+	p, errs := unify(r, additionalMacros, defaultPositive, defaultNegative, defaultPolicy, true)
+	if len(errs) > 0 {
+		return tree.Policy{}, errs[0].Err
+	}
+	return p, nil
+}
+
+// UnifyLenient will unify the rule set in the same way as Unify, but rules with names that can't be resolved are
+// left out instead of stopping the unification. It returns the policy with the rest of the rules and the errors
+// for the rules left out, so that tools can point to every rule with problems at once
+func UnifyLenient(r tree.RawPolicy, additionalMacros []map[string]tree.Macro, defaultPositive, defaultNegative, defaultPolicy string) (tree.Policy, []*tree.RuleError) {
+	return unify(r, additionalMacros, defaultPositive, defaultNegative, defaultPolicy, false)
+}
+
+func unify(r tree.RawPolicy, additionalMacros []map[string]tree.Macro, defaultPositive, defaultNegative, defaultPolicy string, stopAtError bool) (tree.Policy, []*tree.RuleError) {
 	var rules []*tree.Rule
+	var errs []*tree.RuleError
 	macros := combineMacroMaps(additionalMacros)
 	collectedMacros := make(map[string]tree.Macro)
+	defaultPositions := make(map[string]tree.Position)
 	for _, e := range r.RuleOrMacros {
 		switch v := e.(type) {
 		case tree.Rule:
 			r, err := replaceFreeNames(v, macros)
 			if err != nil {
-				return tree.Policy{}, err
+				errs = append(errs, &tree.RuleError{SyscallName: v.Name, Position: v.Position, Err: err})
+				if stopAtError {
+					return tree.Policy{}, errs
+				}
+				continue
 			}
 			rules = append(rules, &r)
 		case tree.Macro:
 			switch v.Name {
 			case "DEFAULT_POSITIVE":
 				defaultPositive = getDefaultAction(v)
+				defaultPositions[v.Name] = v.Position
 			case "DEFAULT_NEGATIVE":
 				defaultNegative = getDefaultAction(v)
+				defaultPositions[v.Name] = v.Position
 			case "DEFAULT_POLICY":
 				defaultPolicy = getDefaultAction(v)
+				defaultPositions[v.Name] = v.Position
 			default:
 				macros[v.Name] = v
 				collectedMacros[v.Name] = v
 			}
 		}
 	}
-	return tree.Policy{DefaultPositiveAction: defaultPositive, DefaultNegativeAction: defaultNegative, DefaultPolicyAction: defaultPolicy, Macros: collectedMacros, Rules: rules}, nil
+	return tree.Policy{DefaultPositiveAction: defaultPositive, DefaultNegativeAction: defaultNegative, DefaultPolicyAction: defaultPolicy, Macros: collectedMacros, Rules: rules, DefaultPositions: defaultPositions}, errs
 }
 
 func replaceFreeNames(r tree.Rule, macros map[string]tree.Macro) (tree.Rule, error) {
@@ -74,12 +98,24 @@ func replaceFreeNames(r tree.Rule, macros map[string]tree.Macro) (tree.Rule, err
 		PositiveAction: r.PositiveAction,
 		NegativeAction: r.NegativeAction,
 		Body:           body,
+		Position:       r.Position,
+		Suppressions:   r.Suppressions,
 	}
 	return rule, err
 }
 
+// Expand will replace all variables and calls in the given expression with the definitions from the given macros
+// or the known constants. It returns an error if any name can't be resolved
+func Expand(x tree.Expression, macros map[string]tree.Macro) (tree.Expression, error) {
+	return replace(x, macros)
+}
+
 func replace(x tree.Expression, macros map[string]tree.Macro) (tree.Expression, error) {
-	r := &replacer{expression: x, macros: macros, err: nil}
+	return replaceIn(x, &environment{macros: macros})
+}
+
+func replaceIn(x tree.Expression, env *environment) (tree.Expression, error) {
+	r := &replacer{expression: x, env: env, err: nil}
 	x.Accept(r)
 	if r.err != nil {
 		return nil, r.err
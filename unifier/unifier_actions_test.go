@@ -35,3 +35,16 @@ func (s *UnifierActionsSuite) Test_Unify_setsDefaultActions2(c *C) {
 	c.Assert(output.DefaultNegativeAction, Equals, "allow")
 	c.Assert(output.DefaultPolicyAction, Equals, "trace")
 }
+
+func (s *UnifierActionsSuite) Test_Unify_keepsTrackOfWhereDefaultActionsAreDefined(c *C) {
+	input := tree.RawPolicy{
+		RuleOrMacros: []interface{}{
+			tree.Macro{Name: "DEFAULT_NEGATIVE", Body: tree.Variable{"EPRM"}, Position: tree.Position{File: "a.policy", Line: 3}},
+		},
+	}
+
+	output, _ := Unify(input, nil, "kill", "allow", "trace")
+
+	c.Assert(output.DefaultNegativeAction, Equals, "EPRM")
+	c.Assert(output.DefaultPositions, DeepEquals, map[string]tree.Position{"DEFAULT_NEGATIVE": tree.Position{File: "a.policy", Line: 3}})
+}
@@ -0,0 +1,85 @@
+package unifier
+
+import (
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+type UnifierMacrosSuite struct{}
+
+var _ = Suite(&UnifierMacrosSuite{})
+
+func unifyRule(body tree.Expression, macros ...tree.Macro) (tree.Policy, error) {
+	rms := []interface{}{}
+	for _, m := range macros {
+		rms = append(rms, m)
+	}
+	rms = append(rms, tree.Rule{Name: "write", Body: body})
+	return Unify(tree.RawPolicy{RuleOrMacros: rms}, nil, "", "", "")
+}
+
+var twoParameters = tree.Macro{
+	Name:          "f",
+	ArgumentNames: []string{"a", "b"},
+	Body:          tree.Comparison{Op: tree.EQL, Left: tree.Variable{"a"}, Right: tree.Variable{"b"}},
+}
+
+func (s *UnifierMacrosSuite) Test_reportsWrongNumberOfArguments(c *C) {
+	_, err := unifyRule(tree.Call{Name: "f", Args: []tree.Any{tree.NumericLiteral{1}, tree.NumericLiteral{2}, tree.NumericLiteral{3}}}, twoParameters)
+	c.Assert(err, ErrorMatches, "Macro 'f' takes 2 arguments, but was called with 3")
+
+	_, err = unifyRule(tree.Call{Name: "f", Args: []tree.Any{tree.NumericLiteral{1}}}, twoParameters)
+	c.Assert(err, ErrorMatches, "Macro 'f' takes 2 arguments, but was called with 1")
+}
+
+func (s *UnifierMacrosSuite) Test_reportsNamesUsedAsTheWrongKind(c *C) {
+	_, err := unifyRule(tree.Variable{"f"}, twoParameters)
+	c.Assert(err, ErrorMatches, "Macro 'f' takes 2 arguments and can't be used as a variable")
+
+	_, err = unifyRule(tree.Call{Name: "VAL", Args: []tree.Any{tree.NumericLiteral{1}}}, tree.Macro{Name: "VAL", Body: tree.NumericLiteral{1}})
+	c.Assert(err, ErrorMatches, "'VAL' is a variable and can't be called with arguments")
+
+	_, err = unifyRule(tree.Call{Name: "O_RDONLY", Args: []tree.Any{tree.NumericLiteral{1}}})
+	c.Assert(err, ErrorMatches, "'O_RDONLY' is a constant and can't be called")
+
+	g := tree.Macro{Name: "g", ArgumentNames: []string{"x"}, Body: tree.Call{Name: "x", Args: []tree.Any{}}}
+	_, err = unifyRule(tree.Call{Name: "g", Args: []tree.Any{tree.NumericLiteral{1}}}, g)
+	c.Assert(err, ErrorMatches, "'x' is a parameter of macro 'g' and can't be called")
+}
+
+func (s *UnifierMacrosSuite) Test_parametersShadowGlobalMacros(c *C) {
+	a := tree.Macro{Name: "a", Body: tree.NumericLiteral{42}}
+	output, err := unifyRule(tree.Call{Name: "f", Args: []tree.Any{tree.Argument{Index: 0}, tree.NumericLiteral{1}}}, a, twoParameters)
+	c.Assert(err, IsNil)
+	c.Assert(tree.ExpressionString(output.Rules[0].Body), Equals, "(eq arg0 1)")
+}
+
+func (s *UnifierMacrosSuite) Test_macroBodiesCantSeeTheParametersOfTheirCaller(c *C) {
+	usesX := tree.Macro{Name: "usesX", ArgumentNames: []string{"y"}, Body: tree.Comparison{Op: tree.EQL, Left: tree.Variable{"y"}, Right: tree.Variable{"x"}}}
+	outer := tree.Macro{Name: "outer", ArgumentNames: []string{"x"}, Body: tree.Call{Name: "usesX", Args: []tree.Any{tree.Variable{"x"}}}}
+	x := tree.Macro{Name: "x", Body: tree.NumericLiteral{7}}
+
+	output, err := unifyRule(tree.Call{Name: "outer", Args: []tree.Any{tree.Argument{Index: 1}}}, x, usesX, outer)
+	c.Assert(err, IsNil)
+	c.Assert(tree.ExpressionString(output.Rules[0].Body), Equals, "(eq arg1 7)")
+
+	_, err = unifyRule(tree.Call{Name: "outer", Args: []tree.Any{tree.Argument{Index: 1}}}, usesX, outer)
+	c.Assert(err, ErrorMatches, "Variable 'x' is not defined")
+}
+
+func (s *UnifierMacrosSuite) Test_reportsRecursiveDefinitionsAcrossFiles(c *C) {
+	first := tree.Macro{Name: "FIRST", Body: tree.Variable{"SECOND"}, Position: tree.Position{File: "a.policy", Line: 1}}
+	second := tree.Macro{Name: "SECOND", Body: tree.Arithmetic{Op: tree.PLUS, Left: tree.Variable{"FIRST"}, Right: tree.NumericLiteral{1}}, Position: tree.Position{File: "b.policy", Line: 4}}
+
+	input := tree.RawPolicy{RuleOrMacros: []interface{}{
+		second,
+		tree.Rule{Name: "write", Body: tree.Comparison{Op: tree.EQL, Left: tree.Argument{Index: 0}, Right: tree.Variable{"SECOND"}}},
+	}}
+	_, err := Unify(input, []map[string]tree.Macro{map[string]tree.Macro{"FIRST": first}}, "", "", "")
+	c.Assert(err, ErrorMatches, "Recursive definition of 'SECOND': SECOND \\(b.policy:4\\) -> FIRST \\(a.policy:1\\) -> SECOND \\(b.policy:4\\)")
+
+	self := tree.Macro{Name: "f", ArgumentNames: []string{"a"}, Body: tree.Call{Name: "f", Args: []tree.Any{tree.Variable{"a"}}}}
+	_, err = unifyRule(tree.Call{Name: "f", Args: []tree.Any{tree.NumericLiteral{1}}}, self)
+	c.Assert(err, ErrorMatches, "Recursive definition of 'f': f -> f")
+}
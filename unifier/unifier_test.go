@@ -717,3 +717,27 @@ func (s *UnifierSuite) Test_Unify_generatesErrorWithAMissingDependentVariable(c
 	c.Assert(e, Not(IsNil))
 	c.Assert(e, ErrorMatches, "Variable 'var2' is not defined")
 }
+
+func (s *UnifierSuite) Test_UnifyLenient_leavesOutTheRulesThatCantBeUnified(c *C) {
+	input := tree.RawPolicy{
+		RuleOrMacros: []interface{}{
+			tree.Rule{
+				Name:     "write",
+				Body:     tree.Comparison{Left: tree.Argument{Index: 0}, Op: tree.EQL, Right: tree.Variable{"var1"}},
+				Position: tree.Position{File: "a.policy", Line: 0},
+			},
+			tree.Macro{Name: "var1", Body: tree.NumericLiteral{42}},
+			tree.Rule{
+				Name: "read",
+				Body: tree.Comparison{Left: tree.Argument{Index: 0}, Op: tree.EQL, Right: tree.Variable{"var1"}},
+			},
+		},
+	}
+
+	output, errs := UnifyLenient(input, nil, "", "", "")
+	c.Assert(output.Rules, HasLen, 1)
+	c.Assert(tree.ExpressionString(output.Rules[0].Body), Equals, "(eq arg0 42)")
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0].Position, Equals, tree.Position{File: "a.policy", Line: 0})
+	c.Assert(errs[0], ErrorMatches, "a.policy:0: \\[write\\] Variable 'var1' is not defined")
+}
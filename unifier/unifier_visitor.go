@@ -2,23 +2,69 @@ package unifier
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/twtiger/gosecco/constants"
 	"github.com/twtiger/gosecco/tree"
 )
 
+// environment contains everything names can refer to at one point of the expansion.
+// The parameters of the macro currently being expanded are kept separate from the global
+// macros, so that parameters always shadow globals, and so that the body of a macro can
+// never see the parameters of the macro that called it.
+type environment struct {
+	macros map[string]tree.Macro
+	// params maps the parameter names of the current macro to the already expanded arguments
+	params map[string]tree.Expression
+	// expanding contains all macros currently being expanded, outermost first
+	expanding []tree.Macro
+}
+
+func describeMacro(m tree.Macro) string {
+	if m.Position.IsKnown() {
+		return fmt.Sprintf("%s (%s)", m.Name, m.Position)
+	}
+	return m.Name
+}
+
+// enter returns the environment to use when expanding the body of the given macro
+func (e *environment) enter(m tree.Macro, params map[string]tree.Expression) (*environment, error) {
+	for ix, outer := range e.expanding {
+		if outer.Name == m.Name {
+			chain := []string{}
+			for _, x := range e.expanding[ix:] {
+				chain = append(chain, describeMacro(x))
+			}
+			chain = append(chain, describeMacro(m))
+			return nil, fmt.Errorf("Recursive definition of '%s': %s", m.Name, strings.Join(chain, " -> "))
+		}
+	}
+
+	expanding := make([]tree.Macro, len(e.expanding), len(e.expanding)+1)
+	copy(expanding, e.expanding)
+	return &environment{macros: e.macros, params: params, expanding: append(expanding, m)}, nil
+}
+
+func (e *environment) currentMacro() string {
+	return e.expanding[len(e.expanding)-1].Name
+}
+
 type replacer struct {
 	expression tree.Expression
-	macros     map[string]tree.Macro
+	env        *environment
 	err        error
 }
 
+func (r *replacer) replace(x tree.Expression) (tree.Expression, error) {
+	return replaceIn(x, r.env)
+}
+
 func (r *replacer) AcceptAnd(b tree.And) {
 	var left tree.Boolean
 	var right tree.Boolean
-	left, r.err = replace(b.Left, r.macros)
+	left, r.err = r.replace(b.Left)
 	if r.err == nil {
-		right, r.err = replace(b.Right, r.macros)
+		right, r.err = r.replace(b.Right)
 		r.expression = tree.And{Left: left, Right: right}
 	}
 }
@@ -28,47 +74,61 @@ func (r *replacer) AcceptArgument(tree.Argument) {}
 func (r *replacer) AcceptArithmetic(b tree.Arithmetic) {
 	var left tree.Numeric
 	var right tree.Numeric
-	left, r.err = replace(b.Left, r.macros)
+	left, r.err = r.replace(b.Left)
 	if r.err == nil {
-		right, r.err = replace(b.Right, r.macros)
+		right, r.err = r.replace(b.Right)
 		r.expression = tree.Arithmetic{Left: left, Op: b.Op, Right: right}
 	}
 }
 
 func (r *replacer) AcceptBinaryNegation(b tree.BinaryNegation) {
 	var op tree.Numeric
-	op, r.err = replace(b.Operand, r.macros)
+	op, r.err = r.replace(b.Operand)
 	r.expression = tree.BinaryNegation{op}
 }
 
 func (r *replacer) AcceptBooleanLiteral(tree.BooleanLiteral) {}
 
 func (r *replacer) AcceptCall(b tree.Call) {
-	v, ok := r.macros[b.Name] // we get the name of the macro
+	if _, ok := r.env.params[b.Name]; ok {
+		r.err = fmt.Errorf("'%s' is a parameter of macro '%s' and can't be called", b.Name, r.env.currentMacro())
+		return
+	}
+
+	v, ok := r.env.macros[b.Name] // we get the name of the macro
 
 	if !ok {
-		r.err = fmt.Errorf("Macro '%s' is not defined", b.Name)
+		if _, isConstant := constants.GetConstant(b.Name); isConstant {
+			r.err = fmt.Errorf("'%s' is a constant and can't be called", b.Name)
+		} else {
+			r.err = fmt.Errorf("Macro '%s' is not defined", b.Name)
+		}
 		return
 	}
 
-	nm := make(map[string]tree.Macro)
+	if len(v.ArgumentNames) == 0 && len(b.Args) > 0 {
+		r.err = fmt.Errorf("'%s' is a variable and can't be called with arguments", b.Name)
+		return
+	}
+
+	if len(v.ArgumentNames) != len(b.Args) {
+		r.err = fmt.Errorf("Macro '%s' takes %d arguments, but was called with %d", b.Name, len(v.ArgumentNames), len(b.Args))
+		return
+	}
+
+	params := make(map[string]tree.Expression)
 	for i, k := range b.Args {
 		var e tree.Expression
-		e, r.err = replace(k, r.macros)
-		if r.err == nil {
-			m := tree.Macro{Name: v.ArgumentNames[i], Body: e}
-			nm[v.ArgumentNames[i]] = m
-		} else {
+		e, r.err = r.replace(k)
+		if r.err != nil {
 			return
 		}
+		params[v.ArgumentNames[i]] = e
 	}
 
-	for k, v := range r.macros {
-		nm[k] = v
-	}
-
-	if r.err == nil {
-		r.expression, r.err = replace(v.Body, nm)
+	var env *environment
+	if env, r.err = r.env.enter(v, params); r.err == nil {
+		r.expression, r.err = replaceIn(v.Body, env)
 	}
 }
 
@@ -76,10 +136,10 @@ func (r *replacer) AcceptComparison(b tree.Comparison) {
 	var left tree.Numeric
 	var right tree.Numeric
 
-	left, r.err = replace(b.Left, r.macros)
+	left, r.err = r.replace(b.Left)
 
 	if r.err == nil {
-		right, r.err = replace(b.Right, r.macros)
+		right, r.err = r.replace(b.Right)
 		r.expression = tree.Comparison{
 			Left:  left,
 			Op:    b.Op,
@@ -92,13 +152,13 @@ func (r *replacer) AcceptComparison(b tree.Comparison) {
 func (r *replacer) AcceptInclusion(b tree.Inclusion) {
 	var rights []tree.Numeric
 	for _, e := range b.Rights {
-		right, err := replace(e, r.macros)
+		right, err := r.replace(e)
 		if err != nil {
 			r.err = err
 		}
 		rights = append(rights, right)
 	}
-	left, err := replace(b.Left, r.macros)
+	left, err := r.replace(b.Left)
 	if err != nil {
 		r.err = err
 	}
@@ -108,7 +168,7 @@ func (r *replacer) AcceptInclusion(b tree.Inclusion) {
 
 func (r *replacer) AcceptNegation(b tree.Negation) {
 	var op tree.Numeric
-	op, r.err = replace(b.Operand, r.macros)
+	op, r.err = r.replace(b.Operand)
 
 	r.expression = tree.Negation{Operand: op}
 }
@@ -119,23 +179,34 @@ func (r *replacer) AcceptOr(b tree.Or) {
 	var left tree.Boolean
 	var right tree.Boolean
 
-	left, r.err = replace(b.Left, r.macros)
+	left, r.err = r.replace(b.Left)
 
 	if r.err == nil {
-		right, r.err = replace(b.Right, r.macros)
-		r.expression = tree.And{Left: left, Right: right}
+		right, r.err = r.replace(b.Right)
 		r.expression = tree.Or{Left: left, Right: right}
 	}
 }
 
 func (r *replacer) AcceptVariable(b tree.Variable) {
-	expr, ok := r.macros[b.Name]
+	if x, ok := r.env.params[b.Name]; ok {
+		// Arguments are expanded in the environment of the caller before they are bound
+		r.expression = x
+		return
+	}
+
+	expr, ok := r.env.macros[b.Name]
 	if ok {
-		x, ee := replace(expr.Body, r.macros)
+		if len(expr.ArgumentNames) > 0 {
+			r.err = fmt.Errorf("Macro '%s' takes %d arguments and can't be used as a variable", b.Name, len(expr.ArgumentNames))
+			return
+		}
+		env, ee := r.env.enter(expr, nil)
+		if ee == nil {
+			r.expression, ee = replaceIn(expr.Body, env)
+		}
 		if ee != nil {
 			r.err = ee
 		}
-		r.expression = x
 	} else {
 		value, ok2 := constants.GetConstant(b.Name)
 		if ok2 {
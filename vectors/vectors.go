@@ -0,0 +1,395 @@
+// Package vectors generates test inputs for a policy at the boundaries of its comparisons, together with the
+// results the reference interpreter expects for them. For a comparison such as argL0 == 5 the argument is set to
+// 4, 5 and 6, for full arguments the two 32 bit halves are also moved on their own, and for an inclusion every
+// member is tried, together with a value that isn't a member. The vectors can be used as regression fixtures,
+// or to check that the emulator and the kernel agree with each other.
+package vectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/constants"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/interpreter"
+	"github.com/twtiger/gosecco/native"
+	"github.com/twtiger/gosecco/tree"
+)
+
+// Vector is one generated input with the value the policy should return for it
+type Vector struct {
+	Memory   data.SeccompWorkingMemory
+	Expected uint32
+	// Reason describes the comparison and the boundary the vector was generated for
+	Reason string
+}
+
+// Expectation returns the vector as an expect line for the policytest package
+func (v Vector) Expectation() string {
+	prefix := ""
+	nr := uint32(v.Memory.NR)
+	if v.Memory.Arch != native.AuditArch {
+		prefix = fmt.Sprintf("arch=0x%X ", v.Memory.Arch)
+	}
+	if nr&native.X32SyscallBit != 0 {
+		prefix += "x32 "
+		nr &^= native.X32SyscallBit
+	}
+	name := fmt.Sprintf("%d", nr)
+	if n, ok := constants.SyscallNumbers[int(nr)]; ok {
+		name = n
+	}
+
+	args := make([]string, len(v.Memory.Args))
+	for i, a := range v.Memory.Args {
+		args[i] = number(a)
+	}
+
+	action := actions.Describe(v.Expected)
+	if strings.HasPrefix(action, "errno ") {
+		action = strings.TrimPrefix(action, "errno ")
+	}
+	return fmt.Sprintf("expect %s%s(%s) => %s # %s", prefix, name, strings.Join(args, ", "), action, v.Reason)
+}
+
+func number(v uint64) string {
+	if v < 10 {
+		return fmt.Sprintf("%d", v)
+	}
+	return fmt.Sprintf("0x%X", v)
+}
+
+// generator collects the inputs for a policy in the order they were found, without duplicates
+type generator struct {
+	inputs  []data.SeccompWorkingMemory
+	reasons map[data.SeccompWorkingMemory]string
+}
+
+func (g *generator) add(d data.SeccompWorkingMemory, reason string) {
+	if _, ok := g.reasons[d]; ok {
+		return
+	}
+	g.inputs = append(g.inputs, d)
+	g.reasons[d] = reason
+}
+
+// Generate returns the vectors for the policy, which should be unified and can be simplified. Besides the
+// boundaries of the comparisons, every rule gets a vector with all arguments zero, and there are vectors for a
+// system call that no rule mentions and for the wrong architecture
+func Generate(p tree.Policy) ([]Vector, error) {
+	g := &generator{reasons: make(map[data.SeccompWorkingMemory]string)}
+
+	used := map[uint32]bool{}
+	for _, r := range p.Rules {
+		nr, ok := constants.GetSyscall(r.Name)
+		if !ok {
+			return nil, fmt.Errorf("unknown system call '%s'", r.Name)
+		}
+		used[nr] = true
+
+		base := data.SeccompWorkingMemory{NR: int32(nr), Arch: native.AuditArch}
+		g.add(base, fmt.Sprintf("%s: all arguments zero", r.Name))
+		r.Body.Accept(&boundaryVisitor{g: g, rule: r.Name, base: base, halves: halvesIn(r.Body)})
+	}
+
+	other := uint32(0)
+	for used[other] {
+		other++
+	}
+	g.add(data.SeccompWorkingMemory{NR: int32(other), Arch: native.AuditArch}, "system call without a rule")
+	g.add(data.SeccompWorkingMemory{Arch: native.AuditArch ^ 1}, "wrong architecture")
+	if p.ActionOnX32 != "" {
+		g.add(data.SeccompWorkingMemory{NR: int32(native.X32SyscallBit), Arch: native.AuditArch}, "x32 system call")
+	}
+
+	result := []Vector{}
+	for _, d := range g.inputs {
+		expected, err := interpreter.Interpret(p, d)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Vector{Memory: d, Expected: expected, Reason: g.reasons[d]})
+	}
+	return result, nil
+}
+
+// boundaryVisitor finds the comparisons and inclusions in a rule body, and adds the vectors for them
+type boundaryVisitor struct {
+	g    *generator
+	rule string
+	base data.SeccompWorkingMemory
+	// halves contains the literals the rule compares each half argument with
+	halves map[tree.Argument][]uint64
+}
+
+func (v *boundaryVisitor) AcceptAnd(x tree.And) {
+	x.Left.Accept(v)
+	x.Right.Accept(v)
+}
+
+func (v *boundaryVisitor) AcceptOr(x tree.Or) {
+	x.Left.Accept(v)
+	x.Right.Accept(v)
+}
+
+func (v *boundaryVisitor) AcceptNegation(x tree.Negation) {
+	x.Operand.Accept(v)
+}
+
+// AcceptComparison tries the boundaries of every literal in the comparison for every argument in it. For
+// a comparison of an argument with a literal those are the interesting values, and for comparisons of
+// arithmetic, such as masks, they are a good guess
+func (v *boundaryVisitor) AcceptComparison(x tree.Comparison) {
+	desc := fmt.Sprintf("%s: %s", v.rule, tree.SourceString(x))
+	literals := literalsIn(x)
+	for _, a := range argumentsIn(x) {
+		for _, l := range literals {
+			for _, b := range boundaries(a, l) {
+				for _, d := range v.with(a, b.value) {
+					v.g.add(d, fmt.Sprintf("%s (%s)", desc, b.name))
+				}
+			}
+		}
+	}
+}
+
+// AcceptInclusion tries every member of the inclusion, and a value that isn't a member
+func (v *boundaryVisitor) AcceptInclusion(x tree.Inclusion) {
+	desc := fmt.Sprintf("%s: %s", v.rule, tree.SourceString(x))
+	members := []uint64{}
+	for _, r := range x.Rights {
+		members = append(members, literalsIn(r)...)
+	}
+	for _, a := range argumentsIn(x.Left) {
+		for _, m := range members {
+			for _, d := range v.with(a, truncate(a, m)) {
+				v.g.add(d, fmt.Sprintf("%s (member %s)", desc, number(m)))
+			}
+		}
+		if n, ok := nonMember(a, members); ok {
+			for _, d := range v.with(a, n) {
+				v.g.add(d, fmt.Sprintf("%s (non-member %s)", desc, number(n)))
+			}
+		}
+	}
+}
+
+func (v *boundaryVisitor) AcceptArgument(tree.Argument)             {}
+func (v *boundaryVisitor) AcceptArithmetic(tree.Arithmetic)         {}
+func (v *boundaryVisitor) AcceptBinaryNegation(tree.BinaryNegation) {}
+func (v *boundaryVisitor) AcceptBooleanLiteral(tree.BooleanLiteral) {}
+func (v *boundaryVisitor) AcceptCall(tree.Call)                     {}
+func (v *boundaryVisitor) AcceptNumericLiteral(tree.NumericLiteral) {}
+func (v *boundaryVisitor) AcceptVariable(tree.Variable)             {}
+
+// with returns the base memory with the argument set to the value. When only a half of the argument is set, the
+// other half is zero, or one of the values the rule compares it with. This is needed to reach the boundaries of
+// comparisons of full arguments, since the simplifier splits those into comparisons of the halves
+func (v *boundaryVisitor) with(a tree.Argument, value uint64) []data.SeccompWorkingMemory {
+	if a.Type == tree.Full {
+		d := v.base
+		d.Args[a.Index] = value
+		return []data.SeccompWorkingMemory{d}
+	}
+
+	other := tree.Argument{Type: tree.Hi, Index: a.Index}
+	if a.Type == tree.Hi {
+		other.Type = tree.Low
+	}
+	result := []data.SeccompWorkingMemory{}
+	for _, o := range append([]uint64{0}, v.halves[other]...) {
+		low, high := value, o
+		if a.Type == tree.Hi {
+			low, high = o, value
+		}
+		d := v.base
+		d.Args[a.Index] = (high&0xFFFFFFFF)<<32 | low&0xFFFFFFFF
+		result = append(result, d)
+	}
+	return result
+}
+
+// halvesIn returns the literals the half arguments are directly compared with in the expression
+func halvesIn(x tree.Expression) map[tree.Argument][]uint64 {
+	result := map[tree.Argument][]uint64{}
+	x.Accept(&halvesVisitor{result})
+	return result
+}
+
+type halvesVisitor struct {
+	halves map[tree.Argument][]uint64
+}
+
+func (v *halvesVisitor) AcceptAnd(x tree.And) {
+	x.Left.Accept(v)
+	x.Right.Accept(v)
+}
+
+func (v *halvesVisitor) AcceptOr(x tree.Or) {
+	x.Left.Accept(v)
+	x.Right.Accept(v)
+}
+
+func (v *halvesVisitor) AcceptNegation(x tree.Negation) {
+	x.Operand.Accept(v)
+}
+
+func (v *halvesVisitor) AcceptComparison(x tree.Comparison) {
+	a, isArgument := x.Left.(tree.Argument)
+	l, isLiteral := x.Right.(tree.NumericLiteral)
+	if !isArgument {
+		a, isArgument = x.Right.(tree.Argument)
+		l, isLiteral = x.Left.(tree.NumericLiteral)
+	}
+	if isArgument && isLiteral && a.Type != tree.Full {
+		v.halves[a] = append(v.halves[a], l.Value)
+	}
+}
+
+func (v *halvesVisitor) AcceptArgument(tree.Argument)             {}
+func (v *halvesVisitor) AcceptArithmetic(tree.Arithmetic)         {}
+func (v *halvesVisitor) AcceptBinaryNegation(tree.BinaryNegation) {}
+func (v *halvesVisitor) AcceptBooleanLiteral(tree.BooleanLiteral) {}
+func (v *halvesVisitor) AcceptCall(tree.Call)                     {}
+func (v *halvesVisitor) AcceptInclusion(tree.Inclusion)           {}
+func (v *halvesVisitor) AcceptNumericLiteral(tree.NumericLiteral) {}
+func (v *halvesVisitor) AcceptVariable(tree.Variable)             {}
+
+type boundary struct {
+	name  string
+	value uint64
+}
+
+// boundaries returns the values around a literal for the argument. Values that would wrap around are left out
+func boundaries(a tree.Argument, l uint64) []boundary {
+	max := uint64(0xFFFFFFFF)
+	if a.Type == tree.Full {
+		max = ^uint64(0)
+	}
+	l = truncate(a, l)
+
+	result := []boundary{{"equal", l}}
+	if l > 0 {
+		result = append(result, boundary{"one less", l - 1})
+	}
+	if l < max {
+		result = append(result, boundary{"one more", l + 1})
+	}
+	if a.Type == tree.Full {
+		low, high := l&0xFFFFFFFF, l>>32
+		if high > 0 {
+			result = append(result, boundary{"high half one less", (high-1)<<32 | low})
+		}
+		if high < 0xFFFFFFFF {
+			result = append(result, boundary{"high half one more", (high+1)<<32 | low})
+		}
+		if low > 0 {
+			result = append(result, boundary{"low half one less", high<<32 | (low - 1)})
+		}
+		if low < 0xFFFFFFFF {
+			result = append(result, boundary{"low half one more", high<<32 | (low + 1)})
+		}
+	}
+	return result
+}
+
+// truncate returns the part of the value a half argument can be compared with
+func truncate(a tree.Argument, l uint64) uint64 {
+	if a.Type == tree.Full {
+		return l
+	}
+	return l & 0xFFFFFFFF
+}
+
+// nonMember returns the smallest value after the largest member that isn't a member, or the largest value
+// below that if there is none
+func nonMember(a tree.Argument, members []uint64) (uint64, bool) {
+	if len(members) == 0 {
+		return 0, false
+	}
+	set := map[uint64]bool{}
+	largest := uint64(0)
+	for _, m := range members {
+		m = truncate(a, m)
+		set[m] = true
+		if m > largest {
+			largest = m
+		}
+	}
+
+	if largest < truncate(a, ^uint64(0)) {
+		return largest + 1, true
+	}
+	for n := largest; n > 0; n-- {
+		if !set[n-1] {
+			return n - 1, true
+		}
+	}
+	return 0, false
+}
+
+// collector finds all arguments and numeric literals in an expression
+type collector struct {
+	arguments []tree.Argument
+	literals  []uint64
+}
+
+func argumentsIn(x tree.Expression) []tree.Argument {
+	c := &collector{}
+	x.Accept(c)
+	return c.arguments
+}
+
+func literalsIn(x tree.Expression) []uint64 {
+	c := &collector{}
+	x.Accept(c)
+	return c.literals
+}
+
+func (c *collector) AcceptAnd(x tree.And) {
+	x.Left.Accept(c)
+	x.Right.Accept(c)
+}
+
+func (c *collector) AcceptOr(x tree.Or) {
+	x.Left.Accept(c)
+	x.Right.Accept(c)
+}
+
+func (c *collector) AcceptNegation(x tree.Negation) {
+	x.Operand.Accept(c)
+}
+
+func (c *collector) AcceptArgument(x tree.Argument) {
+	c.arguments = append(c.arguments, x)
+}
+
+func (c *collector) AcceptArithmetic(x tree.Arithmetic) {
+	x.Left.Accept(c)
+	x.Right.Accept(c)
+}
+
+func (c *collector) AcceptBinaryNegation(x tree.BinaryNegation) {
+	x.Operand.Accept(c)
+}
+
+func (c *collector) AcceptComparison(x tree.Comparison) {
+	x.Left.Accept(c)
+	x.Right.Accept(c)
+}
+
+func (c *collector) AcceptInclusion(x tree.Inclusion) {
+	x.Left.Accept(c)
+	for _, r := range x.Rights {
+		r.Accept(c)
+	}
+}
+
+func (c *collector) AcceptNumericLiteral(x tree.NumericLiteral) {
+	c.literals = append(c.literals, x.Value)
+}
+
+func (c *collector) AcceptBooleanLiteral(tree.BooleanLiteral) {}
+func (c *collector) AcceptCall(tree.Call)                     {}
+func (c *collector) AcceptVariable(tree.Variable)             {}
@@ -0,0 +1,116 @@
+package vectors
+
+import (
+	"testing"
+
+	"github.com/twtiger/gosecco"
+	"github.com/twtiger/gosecco/actions"
+	"github.com/twtiger/gosecco/data"
+	"github.com/twtiger/gosecco/emulator"
+	"github.com/twtiger/gosecco/parser"
+	"github.com/twtiger/gosecco/tree"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type VectorsSuite struct{}
+
+var _ = Suite(&VectorsSuite{})
+
+var settings = gosecco.SeccompSettings{DefaultPositiveAction: "allow", DefaultNegativeAction: "trap", DefaultPolicyAction: "kill"}
+
+func source(s string) parser.Source {
+	return &parser.StringSource{Name: "<test>", Content: s}
+}
+
+func reasons(vs []Vector) []string {
+	result := []string{}
+	for _, v := range vs {
+		result = append(result, v.Reason)
+	}
+	return result
+}
+
+func (s *VectorsSuite) Test_generatesBoundariesOfHalfArgumentComparisons(c *C) {
+	p := tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "trap", DefaultPolicyAction: "kill",
+		Rules: []*tree.Rule{{Name: "read", Body: tree.Comparison{Op: tree.GT, Left: tree.Argument{Type: tree.Low, Index: 1}, Right: tree.NumericLiteral{5}}}}}
+
+	vs, err := Generate(p)
+	c.Assert(err, IsNil)
+	c.Assert(vs, DeepEquals, []Vector{
+		{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003E}, actions.RetTrap, "read: all arguments zero"},
+		{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003E, Args: [6]uint64{0, 5}}, actions.RetTrap, "read: argL1 > 5 (equal)"},
+		{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003E, Args: [6]uint64{0, 4}}, actions.RetTrap, "read: argL1 > 5 (one less)"},
+		{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003E, Args: [6]uint64{0, 6}}, actions.RetAllow, "read: argL1 > 5 (one more)"},
+		{data.SeccompWorkingMemory{NR: 1, Arch: 0xC000003E}, actions.RetKill, "system call without a rule"},
+		{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003F}, actions.RetKill, "wrong architecture"},
+	})
+}
+
+func (s *VectorsSuite) Test_generatesBothHalvesOfFullArguments(c *C) {
+	p, err := gosecco.PreparePolicy(source("write: arg0 == 0x100000000\n"), settings)
+	c.Assert(err, IsNil)
+
+	vs, err := Generate(p)
+	c.Assert(err, IsNil)
+	args := map[uint64]uint32{}
+	for _, v := range vs {
+		if v.Memory.NR == 1 {
+			args[v.Memory.Args[0]] = v.Expected
+		}
+	}
+	c.Assert(args, DeepEquals, map[uint64]uint32{
+		0:           actions.RetTrap,
+		1:           actions.RetTrap,
+		0x100000000: actions.RetAllow,
+		0x200000000: actions.RetTrap,
+		0x100000001: actions.RetTrap,
+	})
+}
+
+func (s *VectorsSuite) Test_generatesMembersAndANonMemberOfInclusions(c *C) {
+	p := tree.Policy{DefaultPositiveAction: "allow", DefaultNegativeAction: "trap", DefaultPolicyAction: "kill",
+		Rules: []*tree.Rule{{Name: "read", Body: tree.Inclusion{Positive: true, Left: tree.Argument{Type: tree.Full, Index: 0},
+			Rights: []tree.Numeric{tree.NumericLiteral{3}, tree.NumericLiteral{7}}}}}}
+
+	vs, err := Generate(p)
+	c.Assert(err, IsNil)
+	c.Assert(reasons(vs), DeepEquals, []string{
+		"read: all arguments zero",
+		"read: in(arg0, 3, 7) (member 3)",
+		"read: in(arg0, 3, 7) (member 7)",
+		"read: in(arg0, 3, 7) (non-member 8)",
+		"system call without a rule",
+		"wrong architecture",
+	})
+	c.Assert(vs[2].Expected, Equals, actions.RetAllow)
+	c.Assert(vs[3].Expected, Equals, actions.RetTrap)
+}
+
+func (s *VectorsSuite) Test_writesVectorsAsExpectations(c *C) {
+	v := Vector{data.SeccompWorkingMemory{NR: 0, Arch: 0xC000003E, Args: [6]uint64{0, 0x10}}, actions.RetErrno | 13, "read: argL1 == 16 (equal)"}
+	c.Assert(v.Expectation(), Equals, "expect read(0, 0x10, 0, 0, 0, 0) => EACCES # read: argL1 == 16 (equal)")
+
+	v = Vector{data.SeccompWorkingMemory{NR: 0x40000001, Arch: 0x40000003}, actions.RetErrno | 4000, "x"}
+	c.Assert(v.Expectation(), Equals, "expect arch=0x40000003 x32 write(0, 0, 0, 0, 0, 0) => 4000 # x")
+}
+
+func (s *VectorsSuite) Test_compiledFiltersAgreeWithTheVectors(c *C) {
+	src := "" +
+		"read: arg0 == 0 || in(argL1, 1, 2, 0xFFFFFFFF)\n" +
+		"write: argL0 & 0x40 == 0 && arg2 > 0xFFFFFFFF\n" +
+		"openat: arg1 != 5; return 13\n"
+	p, err := gosecco.PreparePolicy(source(src), settings)
+	c.Assert(err, IsNil)
+	filters, err := gosecco.PrepareSource(source(src), settings)
+	c.Assert(err, IsNil)
+
+	vs, err := Generate(p)
+	c.Assert(err, IsNil)
+	c.Assert(len(vs) > 20, Equals, true)
+	for _, v := range vs {
+		c.Check(emulator.Emulate(v.Memory, filters), Equals, v.Expected, Commentf("%s", v.Expectation()))
+	}
+}